@@ -8,6 +8,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/BRO3886/gtasks/internal/config"
@@ -27,7 +28,7 @@ const (
 var approvedPorts = []int{8080, 8081, 8082, 9090, 9091}
 
 // Login performs OAuth2 authentication using PKCE + localhost flow
-func Login() error {
+func Login(ctx context.Context) error {
 	// Get OAuth2 configuration
 	oauthConfig, err := config.GetOAuth2Config()
 	if err != nil {
@@ -42,7 +43,7 @@ func Login() error {
 	// Check if already logged in with a valid token
 	existingToken, err := loadToken()
 	if err == nil {
-		if isTokenValid(oauthConfig, existingToken) {
+		if isTokenValid(ctx, oauthConfig, existingToken) {
 			return fmt.Errorf("already logged in (token is valid)")
 		}
 		// Token exists but is invalid/expired — remove it and proceed
@@ -53,7 +54,7 @@ func Login() error {
 	}
 
 	// Perform PKCE + localhost authentication
-	token, port, err := authenticateWithPKCE(oauthConfig)
+	token, port, err := authenticateWithPKCE(ctx, oauthConfig)
 	if err != nil {
 		return fmt.Errorf("authentication failed: %v", err)
 	}
@@ -70,15 +71,15 @@ func Login() error {
 }
 
 // isTokenValid checks if a token is still valid by making a test API call
-func isTokenValid(oauthConfig *oauth2.Config, token *oauth2.Token) bool {
-	client := oauthConfig.Client(context.Background(), token)
+func isTokenValid(ctx context.Context, oauthConfig *oauth2.Config, token *oauth2.Token) bool {
+	client := oauthConfig.Client(ctx, token)
 
-	srv, err := tasks.NewService(context.Background(), option.WithHTTPClient(client))
+	srv, err := tasks.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
 		return false
 	}
 
-	_, err = srv.Tasklists.List().MaxResults(1).Do()
+	_, err = srv.Tasklists.List().MaxResults(1).Context(ctx).Do()
 	return err == nil
 }
 
@@ -92,18 +93,32 @@ func Logout() error {
 }
 
 // GetService creates a Google Tasks service client
-func GetService() (*tasks.Service, error) {
+func GetService(ctx context.Context) (*tasks.Service, error) {
+	// An endpoint override talks to a local, unauthenticated stand-in of the API
+	if endpoint := config.GetAPIEndpoint(); endpoint != "" {
+		if !strings.HasSuffix(endpoint, "/") {
+			endpoint += "/"
+		}
+		srv, err := tasks.NewService(ctx,
+			option.WithEndpoint(endpoint),
+			option.WithHTTPClient(withRetries(&http.Client{})))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Tasks service: %v", err)
+		}
+		return srv, nil
+	}
+
 	oauthConfig, err := config.GetOAuth2Config()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get OAuth2 config: %v", err)
 	}
 
-	client, err := getClient(oauthConfig)
+	client, err := getClient(ctx, oauthConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get HTTP client: %v", err)
 	}
 
-	srv, err := tasks.NewService(context.Background(), option.WithHTTPClient(client))
+	srv, err := tasks.NewService(ctx, option.WithHTTPClient(withRetries(client)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Tasks service: %v", err)
 	}
@@ -111,8 +126,16 @@ func GetService() (*tasks.Service, error) {
 	return srv, nil
 }
 
+// withRetries makes client retry failed requests and limits how long each
+// call may take, as set by api.max_retries and api.timeout.
+func withRetries(client *http.Client) *http.Client {
+	client.Transport = newRetryTransport(client.Transport, config.GetAPIMaxRetries())
+	client.Timeout = config.GetAPITimeout()
+	return client
+}
+
 // authenticateWithPKCE performs OAuth2 authentication with PKCE
-func authenticateWithPKCE(config *oauth2.Config) (*oauth2.Token, int, error) {
+func authenticateWithPKCE(ctx context.Context, config *oauth2.Config) (*oauth2.Token, int, error) {
 	verifier := oauth2.GenerateVerifier()
 
 	port, listener, err := findAvailablePort()
@@ -138,7 +161,7 @@ func authenticateWithPKCE(config *oauth2.Config) (*oauth2.Token, int, error) {
 		utils.Warn("Please manually visit the URL above\n")
 	}
 
-	return startCallbackServer(listener, &configCopy, verifier, state, port)
+	return startCallbackServer(ctx, listener, &configCopy, verifier, state, port)
 }
 
 // findAvailablePort tries to bind to one of the pre-approved ports
@@ -154,7 +177,7 @@ func findAvailablePort() (int, net.Listener, error) {
 }
 
 // startCallbackServer handles the OAuth2 callback
-func startCallbackServer(listener net.Listener, config *oauth2.Config, verifier, state string, port int) (*oauth2.Token, int, error) {
+func startCallbackServer(ctx context.Context, listener net.Listener, config *oauth2.Config, verifier, state string, port int) (*oauth2.Token, int, error) {
 	tokenChan := make(chan *oauth2.Token, 1)
 	errorChan := make(chan error, 1)
 
@@ -171,7 +194,7 @@ func startCallbackServer(listener net.Listener, config *oauth2.Config, verifier,
 			return
 		}
 
-		token, err := config.Exchange(context.Background(), code,
+		token, err := config.Exchange(ctx, code,
 			oauth2.VerifierOption(verifier))
 		if err != nil {
 			errorChan <- fmt.Errorf("failed to exchange code for token: %v", err)
@@ -226,17 +249,19 @@ func startCallbackServer(listener net.Listener, config *oauth2.Config, verifier,
 		return nil, port, err
 	case <-time.After(5 * time.Minute):
 		return nil, port, fmt.Errorf("authentication timeout (5 minutes)")
+	case <-ctx.Done():
+		return nil, port, ctx.Err()
 	}
 }
 
 // getClient retrieves HTTP client with valid token
-func getClient(oauthConfig *oauth2.Config) (*http.Client, error) {
+func getClient(ctx context.Context, oauthConfig *oauth2.Config) (*http.Client, error) {
 	token, err := loadToken()
 	if err != nil {
 		return nil, fmt.Errorf("not authenticated. Run 'gtasks login' first")
 	}
 
-	return oauthConfig.Client(context.Background(), token), nil
+	return oauthConfig.Client(ctx, token), nil
 }
 
 // saveToken serializes a token and stores it in the system keyring.
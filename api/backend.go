@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+// Backend is the subset of the Google Tasks API used by gtasks.
+//
+// The Google-backed implementation is returned by NewBackend. MemoryBackend
+// implements the same interface in memory so commands can be exercised
+// without network access.
+type Backend interface {
+	// ListTaskLists returns one page of task lists.
+	ListTaskLists(ctx context.Context, pageToken string) (*tasks.TaskLists, error)
+	GetTaskList(ctx context.Context, tasklistID string) (*tasks.TaskList, error)
+	InsertTaskList(ctx context.Context, tl *tasks.TaskList) (*tasks.TaskList, error)
+	PatchTaskList(ctx context.Context, tl *tasks.TaskList) (*tasks.TaskList, error)
+	DeleteTaskList(ctx context.Context, tasklistID string) error
+
+	// ListTasks returns one page of tasks in a task list.
+	ListTasks(ctx context.Context, tasklistID string, opts ListOptions) (*tasks.Tasks, error)
+	GetTask(ctx context.Context, tasklistID, taskID string) (*tasks.Task, error)
+	InsertTask(ctx context.Context, tasklistID string, t *tasks.Task, opts MoveOptions) (*tasks.Task, error)
+	PatchTask(ctx context.Context, tasklistID string, t *tasks.Task) (*tasks.Task, error)
+	// PatchTaskIfMatch patches a task only if its ETag is still etag, failing
+	// with a 412 Precondition Failed error otherwise.
+	PatchTaskIfMatch(ctx context.Context, tasklistID string, t *tasks.Task, etag string) (*tasks.Task, error)
+	DeleteTask(ctx context.Context, tasklistID, taskID string) error
+	ClearTasks(ctx context.Context, tasklistID string) error
+	MoveTask(ctx context.Context, tasklistID, taskID string, opts MoveOptions) (*tasks.Task, error)
+}
+
+// ListOptions mirrors the optional parameters of tasks.list.
+type ListOptions struct {
+	PageToken   string
+	MaxResults  int64
+	ShowHidden  bool
+	ShowDeleted bool
+	// UpdatedMin is an RFC 3339 timestamp; only tasks modified at or after it are returned.
+	UpdatedMin string
+}
+
+// MoveOptions positions a task when inserting or moving it.
+// An empty Parent means top level, an empty Previous means first among its siblings.
+type MoveOptions struct {
+	Parent   string
+	Previous string
+	// DestinationTasklist moves the task into another list. Only used by MoveTask.
+	DestinationTasklist string
+}
+
+// googleBackend implements Backend on top of the generated Google Tasks client.
+type googleBackend struct {
+	srv *tasks.Service
+}
+
+// NewBackend returns a Backend talking to Google Tasks with the stored credentials.
+func NewBackend(ctx context.Context) (Backend, error) {
+	srv, err := GetService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewGoogleBackend(srv), nil
+}
+
+// NewGoogleBackend wraps an existing Tasks service.
+func NewGoogleBackend(srv *tasks.Service) Backend {
+	return &googleBackend{srv: srv}
+}
+
+func (g *googleBackend) ListTaskLists(ctx context.Context, pageToken string) (*tasks.TaskLists, error) {
+	call := g.srv.Tasklists.List().MaxResults(100)
+	if pageToken != "" {
+		call = call.PageToken(pageToken)
+	}
+	return call.Context(ctx).Do()
+}
+
+func (g *googleBackend) GetTaskList(ctx context.Context, tasklistID string) (*tasks.TaskList, error) {
+	return g.srv.Tasklists.Get(tasklistID).Context(ctx).Do()
+}
+
+func (g *googleBackend) InsertTaskList(ctx context.Context, tl *tasks.TaskList) (*tasks.TaskList, error) {
+	return g.srv.Tasklists.Insert(tl).Context(ctx).Do()
+}
+
+func (g *googleBackend) PatchTaskList(ctx context.Context, tl *tasks.TaskList) (*tasks.TaskList, error) {
+	return g.srv.Tasklists.Patch(tl.Id, tl).Context(ctx).Do()
+}
+
+func (g *googleBackend) DeleteTaskList(ctx context.Context, tasklistID string) error {
+	return g.srv.Tasklists.Delete(tasklistID).Context(ctx).Do()
+}
+
+func (g *googleBackend) ListTasks(ctx context.Context, tasklistID string, opts ListOptions) (*tasks.Tasks, error) {
+	call := g.srv.Tasks.List(tasklistID).ShowHidden(opts.ShowHidden).ShowDeleted(opts.ShowDeleted)
+	if opts.MaxResults > 0 {
+		call = call.MaxResults(opts.MaxResults)
+	}
+	if opts.PageToken != "" {
+		call = call.PageToken(opts.PageToken)
+	}
+	if opts.UpdatedMin != "" {
+		call = call.UpdatedMin(opts.UpdatedMin)
+	}
+	return call.Context(ctx).Do()
+}
+
+func (g *googleBackend) GetTask(ctx context.Context, tasklistID, taskID string) (*tasks.Task, error) {
+	return g.srv.Tasks.Get(tasklistID, taskID).Context(ctx).Do()
+}
+
+func (g *googleBackend) InsertTask(ctx context.Context, tasklistID string, t *tasks.Task, opts MoveOptions) (*tasks.Task, error) {
+	call := g.srv.Tasks.Insert(tasklistID, t)
+	if opts.Parent != "" {
+		call = call.Parent(opts.Parent)
+	}
+	if opts.Previous != "" {
+		call = call.Previous(opts.Previous)
+	}
+	return call.Context(ctx).Do()
+}
+
+func (g *googleBackend) PatchTask(ctx context.Context, tasklistID string, t *tasks.Task) (*tasks.Task, error) {
+	if t.Id == "" {
+		return nil, fmt.Errorf("task has no ID")
+	}
+	return g.srv.Tasks.Patch(tasklistID, t.Id, t).Context(ctx).Do()
+}
+
+func (g *googleBackend) PatchTaskIfMatch(ctx context.Context, tasklistID string, t *tasks.Task, etag string) (*tasks.Task, error) {
+	if t.Id == "" {
+		return nil, fmt.Errorf("task has no ID")
+	}
+	call := g.srv.Tasks.Patch(tasklistID, t.Id, t)
+	call.Header().Set("If-Match", etag)
+	return call.Context(ctx).Do()
+}
+
+func (g *googleBackend) DeleteTask(ctx context.Context, tasklistID, taskID string) error {
+	return g.srv.Tasks.Delete(tasklistID, taskID).Context(ctx).Do()
+}
+
+func (g *googleBackend) ClearTasks(ctx context.Context, tasklistID string) error {
+	return g.srv.Tasks.Clear(tasklistID).Context(ctx).Do()
+}
+
+func (g *googleBackend) MoveTask(ctx context.Context, tasklistID, taskID string, opts MoveOptions) (*tasks.Task, error) {
+	call := g.srv.Tasks.Move(tasklistID, taskID)
+	if opts.DestinationTasklist != "" {
+		call = call.DestinationTasklist(opts.DestinationTasklist)
+	}
+	if opts.Parent != "" {
+		call = call.Parent(opts.Parent)
+	}
+	if opts.Previous != "" {
+		call = call.Previous(opts.Previous)
+	}
+	return call.Context(ctx).Do()
+}
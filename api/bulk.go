@@ -0,0 +1,215 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+// BulkOptions controls RunBulk.
+type BulkOptions struct {
+	// Concurrency is the number of items processed at once; values below 1
+	// mean one at a time.
+	Concurrency int
+	// OnResult, if set, is called as each item finishes. Calls are never
+	// concurrent, so it can print or update progress directly.
+	OnResult func(r BulkResult)
+}
+
+// BulkResult is the outcome of one item of a bulk operation.
+type BulkResult struct {
+	// Index is the item's position in the input.
+	Index int
+	// Task is the task returned by the API, if any.
+	Task *tasks.Task
+	// Err is nil if the item succeeded. Items skipped because ctx was
+	// done carry the context's error.
+	Err error
+}
+
+// RunBulk calls fn for items 0..n-1 using up to opts.Concurrency workers and
+// returns one result per item, in input order. Once ctx is done no new items
+// start, and fn is expected to abandon the ones running.
+func RunBulk(ctx context.Context, n int, opts BulkOptions, fn func(ctx context.Context, i int) (*tasks.Task, error)) []BulkResult {
+	workers := min(max(opts.Concurrency, 1), n)
+	results := make([]BulkResult, n)
+	next := make(chan int)
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	report := func(r BulkResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		results[r.Index] = r
+		if opts.OnResult != nil {
+			opts.OnResult(r)
+		}
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if err := ctx.Err(); err != nil {
+					report(BulkResult{Index: i, Err: err})
+					continue
+				}
+				t, err := fn(ctx, i)
+				report(BulkResult{Index: i, Task: t, Err: err})
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
+
+// BulkDelete deletes tasks of a list by ID.
+func BulkDelete(ctx context.Context, b Backend, tasklistID string, taskIDs []string, opts BulkOptions) []BulkResult {
+	return RunBulk(ctx, len(taskIDs), opts, func(ctx context.Context, i int) (*tasks.Task, error) {
+		return nil, b.DeleteTask(ctx, tasklistID, taskIDs[i])
+	})
+}
+
+// NewTask is a task for BulkInsert to create under Parent, right after
+// Previous (see MoveOptions).
+type NewTask struct {
+	Task     *tasks.Task
+	Parent   string
+	Previous string
+}
+
+// BulkInsert creates tasks in a list on the worker pool, then moves them so
+// that tasks with the same Parent and Previous follow Previous in input
+// order, as if they had been created one after the other. Results are in
+// input order. Once ctx is done no new tasks are started and none are
+// moved. The error reports that tasks were created but not put in order.
+func BulkInsert(ctx context.Context, b Backend, tasklistID string, items []NewTask, opts BulkOptions) ([]BulkResult, error) {
+	// Each task lands right after Previous, ahead of the ones created before
+	// it, so starting with the last task leaves most of them in order.
+	n := len(items)
+	if onResult := opts.OnResult; onResult != nil {
+		opts.OnResult = func(r BulkResult) {
+			r.Index = n - 1 - r.Index
+			onResult(r)
+		}
+	}
+	results := make([]BulkResult, n)
+	for _, r := range RunBulk(ctx, n, opts, func(ctx context.Context, i int) (*tasks.Task, error) {
+		it := items[n-1-i]
+		return b.InsertTask(ctx, tasklistID, it.Task, MoveOptions{Parent: it.Parent, Previous: it.Previous})
+	}) {
+		r.Index = n - 1 - r.Index
+		results[r.Index] = r
+	}
+	if err := ctx.Err(); err != nil {
+		return results, fmt.Errorf("unable to put the new tasks in order: %w", err)
+	}
+
+	type place struct{ parent, previous string }
+	var places []place
+	created := make(map[place][]string)
+	for i, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		at := place{items[i].Parent, items[i].Previous}
+		if _, ok := created[at]; !ok {
+			places = append(places, at)
+		}
+		created[at] = append(created[at], r.Task.Id)
+	}
+	for _, at := range places {
+		if err := putInOrder(ctx, b, tasklistID, at.parent, at.previous, created[at]); err != nil {
+			return results, fmt.Errorf("unable to put the new tasks in order: %w", err)
+		}
+	}
+	return results, nil
+}
+
+// putInOrder moves the tasks ids, all under parent, so that they follow
+// previous in the given order. Only the tasks outside the longest run that
+// is already in order are moved. Tasks without a position, such as those
+// created offline, are all moved.
+func putInOrder(ctx context.Context, b Backend, tasklistID, parent, previous string, ids []string) error {
+	if len(ids) < 2 {
+		return nil
+	}
+	want := make(map[string]int, len(ids))
+	for i, id := range ids {
+		want[id] = i
+	}
+	items, err := GetTasks(ctx, b, tasklistID, true, 0)
+	if err != nil {
+		return err
+	}
+	var siblings []*tasks.Task
+	positioned := true
+	for _, t := range items {
+		if _, ok := want[t.Id]; ok && t.Parent == parent {
+			siblings = append(siblings, t)
+			positioned = positioned && t.Position != ""
+		}
+	}
+	sort.SliceStable(siblings, func(i, j int) bool { return siblings[i].Position < siblings[j].Position })
+
+	inPlace := make([]bool, len(ids))
+	if positioned {
+		current := make([]int, len(siblings))
+		for i, t := range siblings {
+			current[i] = want[t.Id]
+		}
+		for _, i := range longestIncreasing(current) {
+			inPlace[i] = true
+		}
+	}
+	for i, id := range ids {
+		if inPlace[i] {
+			continue
+		}
+		after := previous
+		if i > 0 {
+			after = ids[i-1]
+		}
+		if _, err := b.MoveTask(ctx, tasklistID, id, MoveOptions{Parent: parent, Previous: after}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// longestIncreasing returns the values of a longest increasing subsequence
+// of s, last first.
+func longestIncreasing(s []int) []int {
+	// tails[k] is the index in s of the smallest value ending an increasing
+	// run of length k+1; prev links each index to the one before it.
+	var tails []int
+	prev := make([]int, len(s))
+	for i, v := range s {
+		k := sort.Search(len(tails), func(k int) bool { return s[tails[k]] >= v })
+		prev[i] = -1
+		if k > 0 {
+			prev[i] = tails[k-1]
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+	var out []int
+	if len(tails) > 0 {
+		for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+			out = append(out, s[i])
+		}
+	}
+	return out
+}
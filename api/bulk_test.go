@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+// jitteryBackend delays each insert by a random amount, so that tasks
+// created in parallel land in no particular order.
+type jitteryBackend struct {
+	*MemoryBackend
+}
+
+func (j jitteryBackend) InsertTask(ctx context.Context, tasklistID string, task *tasks.Task, opts MoveOptions) (*tasks.Task, error) {
+	time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+	return j.MemoryBackend.InsertTask(ctx, tasklistID, task, opts)
+}
+
+func TestBulkInsert(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryBackend()
+	list := newMemoryList(t, m)
+	a, err := m.InsertTask(ctx, list, &tasks.Task{Title: "a"}, MoveOptions{})
+	if err != nil {
+		t.Fatalf("InsertTask: %v", err)
+	}
+	if _, err := m.InsertTask(ctx, list, &tasks.Task{Title: "b"}, MoveOptions{Previous: a.Id}); err != nil {
+		t.Fatalf("InsertTask: %v", err)
+	}
+	a1, err := m.InsertTask(ctx, list, &tasks.Task{Title: "a1"}, MoveOptions{Parent: a.Id})
+	if err != nil {
+		t.Fatalf("InsertTask: %v", err)
+	}
+
+	var items []NewTask
+	for i := 0; i < 8; i++ {
+		items = append(items,
+			NewTask{Task: &tasks.Task{Title: fmt.Sprint("t", i)}},
+			NewTask{Task: &tasks.Task{Title: fmt.Sprint("s", i)}, Parent: a.Id, Previous: a1.Id})
+	}
+	var reported []int
+	results, err := BulkInsert(ctx, jitteryBackend{m}, list, items, BulkOptions{
+		Concurrency: 4,
+		OnResult:    func(r BulkResult) { reported = append(reported, r.Index) },
+	})
+	if err != nil {
+		t.Fatalf("BulkInsert: %v", err)
+	}
+	for i, r := range results {
+		if r.Index != i || r.Err != nil || r.Task.Title != items[i].Task.Title {
+			t.Errorf("result %d: %+v", i, r)
+		}
+	}
+	slices.Sort(reported)
+	if want := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}; !slices.Equal(reported, want) {
+		t.Errorf("reported %v, want %v", reported, want)
+	}
+
+	want := "t0@0 t1@1 t2@2 t3@3 t4@4 t5@5 t6@6 t7@7 a@8 >a1@0 >s0@1 >s1@2 >s2@3 >s3@4 >s4@5 >s5@6 >s6@7 >s7@8 b@9"
+	if got := memoryTree(t, m, list); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestLongestIncreasing(t *testing.T) {
+	tests := []struct {
+		in   []int
+		want int
+	}{
+		{nil, 0},
+		{[]int{0}, 1},
+		{[]int{0, 1, 2, 3}, 4},
+		{[]int{3, 2, 1, 0}, 1},
+		{[]int{1, 0, 2, 4, 3, 5}, 4},
+		{[]int{4, 0, 1, 2, 3}, 4},
+	}
+	for _, tt := range tests {
+		got := longestIncreasing(tt.in)
+		if len(got) != tt.want {
+			t.Errorf("longestIncreasing(%v) = %v, want %d values", tt.in, got, tt.want)
+			continue
+		}
+		// The values come last first and must appear in the input in
+		// increasing order.
+		at := len(tt.in)
+		for _, v := range got {
+			i := slices.Index(tt.in, v)
+			if i >= at {
+				t.Errorf("longestIncreasing(%v) = %v, not increasing", tt.in, got)
+				break
+			}
+			at = i
+		}
+	}
+}
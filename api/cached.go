@@ -0,0 +1,445 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/BRO3886/gtasks/internal/cache"
+	"google.golang.org/api/tasks/v1"
+)
+
+// ErrOffline is returned by CachedBackend for changes attempted offline.
+var ErrOffline = errors.New("cannot change tasks while offline")
+
+// ErrNotCached is returned by CachedBackend when data is needed offline but
+// was never fetched.
+var ErrNotCached = errors.New("not available offline; run the command once while online to cache it")
+
+// CachedBackend serves task lists and tasks from a local cache.Store.
+//
+// While online, listing first brings the store up to date with SyncTasks,
+// and changes are sent to the remote Backend and then recorded in the store.
+// If the network is unavailable, or the backend was created without a remote,
+// reads are answered from the store and the data is reported as stale.
+// Changes to tasks are then recorded in the journal and applied to the store,
+// to be sent later by Push.
+type CachedBackend struct {
+	remote  Backend
+	store   *cache.Store
+	journal *cache.Journal
+
+	mu    sync.Mutex
+	stale time.Time
+	// netErr is the network error that caused a fallback to cached data.
+	netErr error
+	// queued counts the changes recorded in the journal by this backend.
+	queued int
+}
+
+// NewCachedBackend returns a CachedBackend reading through remote into store.
+// A nil remote works offline. Changes made offline are recorded in journal;
+// with a nil journal they fail with ErrOffline.
+func NewCachedBackend(remote Backend, store *cache.Store, journal *cache.Journal) *CachedBackend {
+	return &CachedBackend{remote: remote, store: store, journal: journal}
+}
+
+// Session returns a backend over the same remote, store and journal that
+// keeps its own record of stale data and network errors, so that a command
+// answering many requests, such as 'gtasks serve', does not stay on the
+// cache after one network error. Unless online is set, the session answers
+// from the store alone without contacting the remote.
+func (c *CachedBackend) Session(online bool) *CachedBackend {
+	s := &CachedBackend{store: c.store, journal: c.journal}
+	if online {
+		s.remote = c.remote
+	}
+	return s
+}
+
+// Offline reports whether the backend was created without a remote.
+func (c *CachedBackend) Offline() bool {
+	return c.remote == nil
+}
+
+// Stale reports whether any data was served from the cache without being
+// refreshed, and if so when the oldest such data was fetched. err is the
+// network error that forced the fallback, or nil when working offline.
+func (c *CachedBackend) Stale() (stale bool, fetchedAt time.Time, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.stale.IsZero(), c.stale, c.netErr
+}
+
+// Save persists the store.
+func (c *CachedBackend) Save() error {
+	return c.store.Save()
+}
+
+// reachable reports whether reads should try the remote: there is one, and
+// no request has failed with a network error yet. Once one has, the rest of
+// the command is answered from the store instead of waiting on each call.
+func (c *CachedBackend) reachable() bool {
+	if c.remote == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.netErr == nil
+}
+
+// useCache decides whether a failed remote call should fall back to the store.
+func (c *CachedBackend) useCache(err error) bool {
+	if !IsNetworkError(err) {
+		return false
+	}
+	c.mu.Lock()
+	if c.netErr == nil {
+		c.netErr = err
+	}
+	c.mu.Unlock()
+	return true
+}
+
+// notCached returns the error for data missing from the store: the network
+// error that prevented fetching it, or ErrNotCached when offline.
+func (c *CachedBackend) notCached() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.netErr != nil {
+		return c.netErr
+	}
+	return ErrNotCached
+}
+
+func (c *CachedBackend) markStale(fetchedAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stale.IsZero() || fetchedAt.Before(c.stale) {
+		c.stale = fetchedAt
+	}
+}
+
+// IsNetworkError reports whether err means the API could not be reached, as
+// opposed to the API rejecting the request.
+func IsNetworkError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	if errors.As(err, &opErr) || errors.As(err, &dnsErr) {
+		return true
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && urlErr.Timeout()
+}
+
+func (c *CachedBackend) ListTaskLists(ctx context.Context, pageToken string) (*tasks.TaskLists, error) {
+	fresh := false
+	if pageToken == "" && c.reachable() {
+		err := c.refreshTaskLists(ctx)
+		if err != nil && !c.useCache(err) {
+			return nil, err
+		}
+		fresh = err == nil
+	}
+
+	lists, fetchedAt := c.store.TaskLists()
+	if fetchedAt.IsZero() {
+		return nil, c.notCached()
+	}
+	if !fresh && pageToken == "" {
+		c.markStale(fetchedAt)
+	}
+
+	start, end, next, err := paginate(len(lists), pageToken, maxPageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &tasks.TaskLists{Kind: "tasks#taskLists", Items: lists[start:end], NextPageToken: next}, nil
+}
+
+func (c *CachedBackend) refreshTaskLists(ctx context.Context) error {
+	var all []*tasks.TaskList
+	pageToken := ""
+	for {
+		r, err := c.remote.ListTaskLists(ctx, pageToken)
+		if err != nil {
+			return err
+		}
+		all = append(all, r.Items...)
+		if r.NextPageToken == "" {
+			break
+		}
+		pageToken = r.NextPageToken
+	}
+	c.store.SetTaskLists(all, time.Now())
+	return nil
+}
+
+func (c *CachedBackend) GetTaskList(ctx context.Context, tasklistID string) (*tasks.TaskList, error) {
+	if c.reachable() {
+		tl, err := c.remote.GetTaskList(ctx, tasklistID)
+		if err == nil {
+			c.store.PutTaskList(tl)
+			return tl, nil
+		}
+		if !c.useCache(err) {
+			return nil, err
+		}
+	}
+
+	lists, fetchedAt := c.store.TaskLists()
+	for _, tl := range lists {
+		if tl.Id == tasklistID {
+			c.markStale(fetchedAt)
+			return tl, nil
+		}
+	}
+	return nil, c.notCached()
+}
+
+func (c *CachedBackend) InsertTaskList(ctx context.Context, tl *tasks.TaskList) (*tasks.TaskList, error) {
+	if c.remote == nil {
+		return nil, ErrOffline
+	}
+	r, err := c.remote.InsertTaskList(ctx, tl)
+	if err != nil {
+		return nil, err
+	}
+	c.store.PutTaskList(r)
+	return r, nil
+}
+
+func (c *CachedBackend) PatchTaskList(ctx context.Context, tl *tasks.TaskList) (*tasks.TaskList, error) {
+	if c.remote == nil {
+		return nil, ErrOffline
+	}
+	r, err := c.remote.PatchTaskList(ctx, tl)
+	if err != nil {
+		return nil, err
+	}
+	c.store.PutTaskList(r)
+	return r, nil
+}
+
+func (c *CachedBackend) DeleteTaskList(ctx context.Context, tasklistID string) error {
+	if c.remote == nil {
+		return ErrOffline
+	}
+	if err := c.remote.DeleteTaskList(ctx, tasklistID); err != nil {
+		return err
+	}
+	c.store.RemoveTaskList(tasklistID)
+	return nil
+}
+
+// ListTasks syncs the list from the remote on the first page, then pages
+// through the cached copy applying opts. Deleted tasks are listed straight
+// from the remote while it can be reached.
+func (c *CachedBackend) ListTasks(ctx context.Context, tasklistID string, opts ListOptions) (*tasks.Tasks, error) {
+	// The store keeps no deleted tasks, so those can only come from the remote.
+	if opts.ShowDeleted && c.reachable() {
+		r, err := c.remote.ListTasks(ctx, tasklistID, opts)
+		if err == nil || !c.useCache(err) {
+			return r, err
+		}
+	}
+
+	fresh := false
+	if opts.PageToken == "" && c.reachable() {
+		_, err := c.Sync(ctx, tasklistID)
+		if err != nil && !c.useCache(err) {
+			return nil, err
+		}
+		fresh = err == nil
+	}
+
+	items, fetchedAt, ok := c.store.Tasks(tasklistID)
+	if !ok {
+		return nil, c.notCached()
+	}
+	if !fresh && opts.PageToken == "" {
+		c.markStale(fetchedAt)
+	}
+
+	var updatedMin time.Time
+	if opts.UpdatedMin != "" {
+		t, err := time.Parse(time.RFC3339, opts.UpdatedMin)
+		if err != nil {
+			return nil, badRequest("invalid updatedMin")
+		}
+		updatedMin = t
+	}
+
+	var visible []*tasks.Task
+	for _, t := range items {
+		if t.Deleted && !opts.ShowDeleted {
+			continue
+		}
+		if t.Hidden && !opts.ShowHidden {
+			continue
+		}
+		if !updatedMin.IsZero() {
+			updated, err := time.Parse(time.RFC3339, t.Updated)
+			if err == nil && updated.Before(updatedMin) {
+				continue
+			}
+		}
+		visible = append(visible, t)
+	}
+
+	start, end, next, err := paginate(len(visible), opts.PageToken, opts.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	return &tasks.Tasks{Kind: "tasks#tasks", Items: visible[start:end], NextPageToken: next}, nil
+}
+
+// Sync brings the cached copy of a list up to date and returns the tasks that
+// changed, as SyncTasks does. Changes not yet pushed are applied on top.
+func (c *CachedBackend) Sync(ctx context.Context, tasklistID string) ([]*tasks.Task, error) {
+	if c.remote == nil {
+		return nil, ErrOffline
+	}
+	changed, err := SyncTasks(ctx, c.remote, c.store, tasklistID)
+	if err != nil {
+		return nil, err
+	}
+	c.reapplyPending(tasklistID)
+	return changed, nil
+}
+
+func (c *CachedBackend) GetTask(ctx context.Context, tasklistID, taskID string) (*tasks.Task, error) {
+	if c.reachable() {
+		t, err := c.remote.GetTask(ctx, tasklistID, taskID)
+		if err == nil {
+			c.store.PutTask(tasklistID, t)
+			return t, nil
+		}
+		if !c.useCache(err) {
+			return nil, err
+		}
+	}
+
+	t, ok := c.store.Task(tasklistID, taskID)
+	if !ok {
+		return nil, c.notCached()
+	}
+	if _, fetchedAt, ok := c.store.Tasks(tasklistID); ok {
+		c.markStale(fetchedAt)
+	}
+	return t, nil
+}
+
+func (c *CachedBackend) InsertTask(ctx context.Context, tasklistID string, t *tasks.Task, opts MoveOptions) (*tasks.Task, error) {
+	var err error
+	if c.remote != nil {
+		var r *tasks.Task
+		r, err = c.remote.InsertTask(ctx, tasklistID, t, opts)
+		if err == nil {
+			c.store.PutTask(tasklistID, r)
+			return r, nil
+		}
+	}
+	if !c.queueable(err) {
+		return nil, offlineErr(err)
+	}
+	return c.queueInsert(tasklistID, t, opts)
+}
+
+func (c *CachedBackend) PatchTask(ctx context.Context, tasklistID string, t *tasks.Task) (*tasks.Task, error) {
+	var err error
+	if c.remote != nil {
+		var r *tasks.Task
+		r, err = c.remote.PatchTask(ctx, tasklistID, t)
+		if err == nil {
+			c.store.PutTask(tasklistID, r)
+			return r, nil
+		}
+	}
+	if !c.queueable(err) {
+		return nil, offlineErr(err)
+	}
+	return c.queuePatch(tasklistID, t)
+}
+
+// PatchTaskIfMatch sends the conditional patch to the remote. Offline, the
+// change is queued and the ETag is checked when it is pushed.
+func (c *CachedBackend) PatchTaskIfMatch(ctx context.Context, tasklistID string, t *tasks.Task, etag string) (*tasks.Task, error) {
+	var err error
+	if c.remote != nil {
+		var r *tasks.Task
+		r, err = c.remote.PatchTaskIfMatch(ctx, tasklistID, t, etag)
+		if err == nil {
+			c.store.PutTask(tasklistID, r)
+			return r, nil
+		}
+	}
+	if !c.queueable(err) {
+		return nil, offlineErr(err)
+	}
+	return c.queuePatch(tasklistID, t)
+}
+
+func (c *CachedBackend) DeleteTask(ctx context.Context, tasklistID, taskID string) error {
+	var err error
+	if c.remote != nil {
+		if err = c.remote.DeleteTask(ctx, tasklistID, taskID); err == nil {
+			c.store.RemoveTask(tasklistID, taskID)
+			return nil
+		}
+	}
+	if !c.queueable(err) {
+		return offlineErr(err)
+	}
+	return c.queueDelete(tasklistID, taskID)
+}
+
+func (c *CachedBackend) ClearTasks(ctx context.Context, tasklistID string) error {
+	if c.remote == nil {
+		return ErrOffline
+	}
+	if err := c.remote.ClearTasks(ctx, tasklistID); err != nil {
+		return err
+	}
+	c.store.HideCompleted(tasklistID)
+	return nil
+}
+
+func (c *CachedBackend) MoveTask(ctx context.Context, tasklistID, taskID string, opts MoveOptions) (*tasks.Task, error) {
+	var err error
+	if c.remote != nil {
+		var r *tasks.Task
+		r, err = c.remote.MoveTask(ctx, tasklistID, taskID, opts)
+		if err == nil {
+			if opts.DestinationTasklist != "" && opts.DestinationTasklist != tasklistID {
+				c.store.RemoveTask(tasklistID, taskID)
+				c.store.PutTask(opts.DestinationTasklist, r)
+			} else {
+				c.store.PutTask(tasklistID, r)
+			}
+			return r, nil
+		}
+	}
+	if !c.queueable(err) {
+		return nil, offlineErr(err)
+	}
+	return c.queueMove(tasklistID, taskID, opts)
+}
+
+// offlineErr returns the error of a change that could not be queued: the
+// remote error, or ErrOffline when there was no remote.
+func offlineErr(err error) error {
+	if err == nil {
+		return ErrOffline
+	}
+	return err
+}
@@ -0,0 +1,467 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/BRO3886/gtasks/internal/cache"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/tasks/v1"
+)
+
+// ErrConflict is reported by Push for a queued change to a task that was
+// also changed on the server after the change was made.
+var ErrConflict = errors.New("changed on the server since it was edited offline")
+
+// PushResult is the outcome of replaying one queued change.
+type PushResult struct {
+	Op *cache.Op
+	// Err is nil if the change was applied. Changes that fail stay queued.
+	Err error
+}
+
+// queue records a change in the journal and applies it to the store, so that
+// later reads see it before it reaches the server.
+func (c *CachedBackend) queue(op *cache.Op) (*tasks.Task, error) {
+	if err := c.journal.Append(op); err != nil {
+		return nil, fmt.Errorf("unable to record offline change: %v", err)
+	}
+	c.mu.Lock()
+	c.queued++
+	c.mu.Unlock()
+	return applyOp(c.store, op), nil
+}
+
+// queueable reports whether a write should be queued rather than failed: the
+// backend is offline, or the request could not be sent at all.
+func (c *CachedBackend) queueable(err error) bool {
+	if c.journal == nil {
+		return false
+	}
+	return err == nil || couldNotConnect(err)
+}
+
+// couldNotConnect reports whether err means a request never reached the
+// server, so it is safe to queue it without risking applying it twice.
+func couldNotConnect(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false // the command was interrupted, not the network
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func (c *CachedBackend) queueInsert(tasklistID string, t *tasks.Task, opts MoveOptions) (*tasks.Task, error) {
+	return c.queue(&cache.Op{
+		Kind:     cache.OpCreate,
+		ListID:   tasklistID,
+		TaskID:   cache.NewLocalID(),
+		Title:    t.Title,
+		Task:     copyTask(t),
+		Parent:   opts.Parent,
+		Previous: opts.Previous,
+	})
+}
+
+func (c *CachedBackend) queuePatch(tasklistID string, t *tasks.Task) (*tasks.Task, error) {
+	stored, ok := c.store.Task(tasklistID, t.Id)
+	if !ok {
+		return nil, c.notCached()
+	}
+	// Make sure the patch applies before recording it.
+	if _, err := mergeTask(stored, t); err != nil {
+		return nil, err
+	}
+	return c.queue(&cache.Op{
+		Kind:            cache.OpPatch,
+		ListID:          tasklistID,
+		TaskID:          t.Id,
+		Title:           stored.Title,
+		Task:            copyTask(t),
+		NullFields:      t.NullFields,
+		ForceSendFields: t.ForceSendFields,
+		ETag:            stored.Etag,
+		Base:            stored,
+	})
+}
+
+func (c *CachedBackend) queueDelete(tasklistID, taskID string) error {
+	if cache.IsLocalID(taskID) {
+		// Never sent to the server: forget it instead of queueing a delete.
+		if err := c.journal.DropLocalTask(taskID); err != nil {
+			return fmt.Errorf("unable to record offline change: %v", err)
+		}
+		c.store.RemoveTask(tasklistID, taskID)
+		return nil
+	}
+	op := &cache.Op{Kind: cache.OpDelete, ListID: tasklistID, TaskID: taskID}
+	if stored, ok := c.store.Task(tasklistID, taskID); ok {
+		op.Title = stored.Title
+		op.ETag = stored.Etag
+		op.Base = stored
+	}
+	_, err := c.queue(op)
+	return err
+}
+
+func (c *CachedBackend) queueMove(tasklistID, taskID string, opts MoveOptions) (*tasks.Task, error) {
+	stored, ok := c.store.Task(tasklistID, taskID)
+	if !ok {
+		return nil, c.notCached()
+	}
+	moved, err := c.queue(&cache.Op{
+		Kind:            cache.OpMove,
+		ListID:          tasklistID,
+		TaskID:          taskID,
+		Title:           stored.Title,
+		Parent:          opts.Parent,
+		Previous:        opts.Previous,
+		DestinationList: opts.DestinationTasklist,
+		ETag:            stored.Etag,
+		Base:            copyTask(stored),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if moved == nil {
+		// The destination list is not cached; report the task as moved.
+		stored.Parent = opts.Parent
+		moved = stored
+	}
+	return moved, nil
+}
+
+// applyOp applies a queued change to the cached tasks and returns the
+// affected task as it is now cached, or nil if it is not (or no longer) cached.
+// Applying the same change twice has no further effect.
+func applyOp(store *cache.Store, op *cache.Op) *tasks.Task {
+	now := op.QueuedAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+	stamp := now.UTC().Format(timestampLayout)
+
+	switch op.Kind {
+	case cache.OpCreate:
+		t := copyTask(op.Task)
+		t.Kind = "tasks#task"
+		t.Id = op.TaskID
+		t.Parent = op.Parent
+		t.Updated = stamp
+		if t.Status == "" {
+			t.Status = "needsAction"
+		}
+		setCompleted(t, stamp)
+		store.PutTask(op.ListID, t)
+		return t
+
+	case cache.OpPatch:
+		stored, ok := store.Task(op.ListID, op.TaskID)
+		if !ok {
+			return nil
+		}
+		patch := copyTask(op.Task)
+		patch.NullFields = op.NullFields
+		patch.ForceSendFields = op.ForceSendFields
+		merged, err := mergeTask(stored, patch)
+		if err != nil {
+			return nil
+		}
+		merged.Updated = stamp
+		setCompleted(merged, stamp)
+		store.PutTask(op.ListID, merged)
+		return merged
+
+	case cache.OpDelete:
+		store.RemoveTask(op.ListID, op.TaskID)
+		return nil
+
+	case cache.OpMove:
+		stored, ok := store.Task(op.ListID, op.TaskID)
+		if !ok {
+			// Already applied to a task moved to another list.
+			if op.DestinationList != "" {
+				if t, ok := store.Task(op.DestinationList, op.TaskID); ok {
+					return t
+				}
+			}
+			return nil
+		}
+		stored.Parent = op.Parent
+		stored.Updated = stamp
+		if op.DestinationList != "" && op.DestinationList != op.ListID {
+			store.RemoveTask(op.ListID, op.TaskID)
+			store.PutTask(op.DestinationList, stored)
+			if t, ok := store.Task(op.DestinationList, op.TaskID); ok {
+				return t
+			}
+			return nil
+		}
+		store.PutTask(op.ListID, stored)
+		return stored
+	}
+	return nil
+}
+
+// setCompleted keeps Completed consistent with Status, as the API does.
+func setCompleted(t *tasks.Task, stamp string) {
+	switch {
+	case t.Status == "completed" && t.Completed == nil:
+		t.Completed = &stamp
+	case t.Status == "needsAction":
+		t.Completed = nil
+	}
+}
+
+// reapplyPending applies queued changes affecting a list on top of freshly
+// synced data, so that changes not yet pushed stay visible.
+func (c *CachedBackend) reapplyPending(tasklistID string) {
+	if c.journal == nil {
+		return
+	}
+	for _, op := range c.journal.Ops() {
+		if op.ListID == tasklistID || op.DestinationList == tasklistID {
+			applyOp(c.store, op)
+		}
+	}
+}
+
+// Pending returns the changes waiting to be pushed.
+func (c *CachedBackend) Pending() []*cache.Op {
+	if c.journal == nil {
+		return nil
+	}
+	return c.journal.Ops()
+}
+
+// Queued returns how many changes were queued by this backend instead of
+// being sent to the server.
+func (c *CachedBackend) Queued() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.queued
+}
+
+// Discard drops queued changes without sending them. With no IDs every
+// queued change is dropped. Lists they touched are synced again in full on
+// next use.
+func (c *CachedBackend) Discard(ids ...int) error {
+	if c.journal == nil {
+		return nil
+	}
+	drop := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
+	var remove []int
+	for _, op := range c.journal.Ops() {
+		if len(ids) > 0 && !drop[op.ID] {
+			continue
+		}
+		remove = append(remove, op.ID)
+		if op.Kind == cache.OpCreate {
+			c.store.RemoveTask(op.ListID, op.TaskID)
+		}
+		c.resetList(op)
+	}
+	return c.journal.Remove(remove...)
+}
+
+// resetList makes the next sync of the lists touched by op fetch every task,
+// replacing whatever the change left in the store.
+func (c *CachedBackend) resetList(op *cache.Op) {
+	c.store.ResetSync(op.ListID)
+	if op.DestinationList != "" {
+		c.store.ResetSync(op.DestinationList)
+	}
+}
+
+// Push replays the queued changes against the server in the order they were
+// made. Unless force is set, a change to a task that was modified on the
+// server since is not applied and reported with ErrConflict. Changes that are
+// applied leave the journal; the others stay queued and are reported.
+//
+// Push stops early, returning the results so far and the error, if the
+// server cannot be reached or ctx is done.
+func (c *CachedBackend) Push(ctx context.Context, force bool) ([]PushResult, error) {
+	if c.remote == nil {
+		return nil, ErrOffline
+	}
+	if c.journal == nil {
+		return nil, nil
+	}
+
+	// ids maps local IDs to the IDs the server gave the created tasks.
+	ids := make(map[string]string)
+	// etags holds the ETag each task got from changes replayed so far.
+	etags := make(map[string]string)
+	resolve := func(id string) string {
+		if real, ok := ids[id]; ok {
+			return real
+		}
+		return id
+	}
+	unresolved := func(id string) bool {
+		return cache.IsLocalID(id) && ids[id] == ""
+	}
+
+	var results []PushResult
+	touched := make(map[string]bool)
+	defer func() {
+		for listID := range touched {
+			c.store.ResetSync(listID)
+			if _, err := SyncTasks(ctx, c.remote, c.store, listID); err == nil {
+				c.reapplyPending(listID)
+			}
+		}
+	}()
+
+	for _, op := range c.journal.Ops() {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		res := PushResult{Op: op}
+
+		if op.Kind != cache.OpCreate && unresolved(op.TaskID) ||
+			unresolved(op.Parent) || unresolved(op.Previous) {
+			res.Err = errors.New("depends on a task that could not be created")
+			results = append(results, res)
+			continue
+		}
+
+		taskID := resolve(op.TaskID)
+		// current is the ETag checked against, which a patch must still
+		// match when it reaches the server.
+		var current string
+		if op.Kind != cache.OpCreate && !force {
+			expected := op.ETag
+			if e, ok := etags[taskID]; ok {
+				expected = e
+			}
+			if expected != "" {
+				var err error
+				current, err = checkETag(ctx, c.remote, op.ListID, taskID, expected, op.Base)
+				if IsNetworkError(err) {
+					return results, err
+				}
+				if isNotFound(err) && op.Kind == cache.OpDelete {
+					err = nil // already gone
+				} else if isNotFound(err) {
+					res.Err = errors.New("no longer exists on the server")
+					results = append(results, res)
+					continue
+				} else if err != nil {
+					res.Err = err
+					results = append(results, res)
+					continue
+				}
+			}
+		}
+
+		var (
+			r   *tasks.Task
+			err error
+		)
+		switch op.Kind {
+		case cache.OpCreate:
+			r, err = c.remote.InsertTask(ctx, op.ListID, op.Task, MoveOptions{
+				Parent:   resolve(op.Parent),
+				Previous: resolve(op.Previous),
+			})
+			if err == nil {
+				ids[op.TaskID] = r.Id
+			}
+		case cache.OpPatch:
+			p := copyTask(op.Task)
+			p.Id = taskID
+			p.NullFields = op.NullFields
+			p.ForceSendFields = op.ForceSendFields
+			if current != "" {
+				r, err = c.remote.PatchTaskIfMatch(ctx, op.ListID, p, current)
+				if IsConflict(err) {
+					err = ErrConflict // changed again since it was checked
+				}
+			} else {
+				r, err = c.remote.PatchTask(ctx, op.ListID, p)
+			}
+		case cache.OpDelete:
+			err = c.remote.DeleteTask(ctx, op.ListID, taskID)
+			if isNotFound(err) {
+				err = nil
+			}
+		case cache.OpMove:
+			r, err = c.remote.MoveTask(ctx, op.ListID, taskID, MoveOptions{
+				Parent:              resolve(op.Parent),
+				Previous:            resolve(op.Previous),
+				DestinationTasklist: op.DestinationList,
+			})
+		default:
+			err = fmt.Errorf("unknown change %q", op.Kind)
+		}
+		if IsNetworkError(err) {
+			return results, err
+		}
+		if isNotFound(err) {
+			err = errors.New("no longer exists on the server")
+		}
+		if err != nil {
+			res.Err = err
+			results = append(results, res)
+			continue
+		}
+
+		if r != nil {
+			etags[r.Id] = r.Etag
+		}
+		touched[op.ListID] = true
+		if op.DestinationList != "" {
+			touched[op.DestinationList] = true
+		}
+		if err := c.journal.Remove(op.ID); err != nil {
+			return results, fmt.Errorf("unable to update offline journal: %v", err)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// checkETag returns ErrConflict if the task's current ETag differs from
+// expected and its content differs from base. Moving other tasks changes a
+// task's position and so its ETag; that alone is not a conflict. Otherwise
+// it returns the current ETag.
+func checkETag(ctx context.Context, b Backend, tasklistID, taskID, expected string, base *tasks.Task) (string, error) {
+	current, err := b.GetTask(ctx, tasklistID, taskID)
+	if err != nil {
+		return "", err
+	}
+	if current.Etag == expected {
+		return current.Etag, nil
+	}
+	if base == nil || !sameContent(base, current) {
+		return "", ErrConflict
+	}
+	return current.Etag, nil
+}
+
+// sameContent reports whether two versions of a task have the same
+// user-visible content.
+func sameContent(a, b *tasks.Task) bool {
+	return a.Title == b.Title &&
+		a.Notes == b.Notes &&
+		a.Due == b.Due &&
+		a.Status == b.Status &&
+		a.Parent == b.Parent
+}
+
+func isNotFound(err error) bool {
+	var gerr *googleapi.Error
+	return errors.As(err, &gerr) && gerr.Code == http.StatusNotFound
+}
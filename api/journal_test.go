@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/BRO3886/gtasks/internal/cache"
+	"google.golang.org/api/tasks/v1"
+)
+
+// newCachedPair returns an online and an offline CachedBackend sharing a
+// store and journal in a temporary directory, with remote behind the first.
+func newCachedPair(t *testing.T, remote Backend) (online, offline *CachedBackend) {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := cache.Open(filepath.Join(dir, "cache.json"))
+	if err != nil {
+		t.Fatalf("cache.Open: %v", err)
+	}
+	journal, err := cache.OpenJournal(filepath.Join(dir, "journal.json"))
+	if err != nil {
+		t.Fatalf("cache.OpenJournal: %v", err)
+	}
+	return NewCachedBackend(remote, store, journal), NewCachedBackend(nil, store, journal)
+}
+
+func TestPushAfterDeletingOfflineTask(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryBackend()
+	list := newMemoryList(t, m)
+	x, err := m.InsertTask(ctx, list, &tasks.Task{Title: "x"}, MoveOptions{})
+	if err != nil {
+		t.Fatalf("InsertTask: %v", err)
+	}
+	online, offline := newCachedPair(t, m)
+	if _, err := GetTasks(ctx, online, list, false, 0); err != nil {
+		t.Fatalf("GetTasks: %v", err)
+	}
+
+	// A series created offline chains each occurrence after the one before.
+	var ids []string
+	previous := ""
+	for _, title := range []string{"s1", "s2", "s3"} {
+		created, err := CreateTaskAfter(ctx, offline, &tasks.Task{Title: title}, list, "", previous)
+		if err != nil {
+			t.Fatalf("CreateTaskAfter: %v", err)
+		}
+		ids = append(ids, created.Id)
+		previous = created.Id
+	}
+	if _, err := CreateSubtask(ctx, offline, &tasks.Task{Title: "s1a"}, list, ids[0]); err != nil {
+		t.Fatalf("CreateSubtask: %v", err)
+	}
+	if _, err := MoveTask(ctx, offline, list, x.Id, "", ids[0]); err != nil {
+		t.Fatalf("MoveTask: %v", err)
+	}
+	if err := DeleteTask(ctx, offline, ids[0], list); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+
+	results, err := online.Push(ctx, false)
+	if err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			t.Errorf("%s %s: %v", r.Op.Kind, r.Op.Title, r.Err)
+		}
+	}
+	if n := len(online.Pending()); n != 0 {
+		t.Errorf("%d changes still queued", n)
+	}
+	if got, want := memoryTree(t, m, list), "x@0 s2@1 s3@2"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+// editedBackend edits a task on the server right after GetTask reads it, as
+// another device could between Push's conflict check and its patch.
+type editedBackend struct {
+	Backend
+	edit func()
+}
+
+func (b *editedBackend) GetTask(ctx context.Context, tasklistID, taskID string) (*tasks.Task, error) {
+	t, err := b.Backend.GetTask(ctx, tasklistID, taskID)
+	if b.edit != nil {
+		b.edit()
+		b.edit = nil
+	}
+	return t, err
+}
+
+func TestPushPatchEditedMeanwhile(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryBackend()
+	list := newMemoryList(t, m)
+	x, err := m.InsertTask(ctx, list, &tasks.Task{Title: "x"}, MoveOptions{})
+	if err != nil {
+		t.Fatalf("InsertTask: %v", err)
+	}
+	remote := &editedBackend{Backend: m}
+	online, offline := newCachedPair(t, remote)
+	if _, err := GetTasks(ctx, online, list, false, 0); err != nil {
+		t.Fatalf("GetTasks: %v", err)
+	}
+	if _, err := offline.PatchTask(ctx, list, &tasks.Task{Id: x.Id, Title: "offline"}); err != nil {
+		t.Fatalf("PatchTask: %v", err)
+	}
+
+	remote.edit = func() {
+		if _, err := m.PatchTask(ctx, list, &tasks.Task{Id: x.Id, Title: "phone"}); err != nil {
+			t.Errorf("PatchTask: %v", err)
+		}
+	}
+	results, err := online.Push(ctx, false)
+	if err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if len(results) != 1 || !errors.Is(results[0].Err, ErrConflict) {
+		t.Fatalf("got %+v, want a conflict", results)
+	}
+	if n := len(online.Pending()); n != 1 {
+		t.Errorf("%d changes queued, want the patch kept", n)
+	}
+	if got, _ := m.GetTask(ctx, list, x.Id); got.Title != "phone" {
+		t.Errorf("server has %q, want the edit from the phone kept", got.Title)
+	}
+}
@@ -0,0 +1,542 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/tasks/v1"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// timestampLayout matches the millisecond RFC 3339 timestamps returned by Google.
+const timestampLayout = "2006-01-02T15:04:05.000Z"
+
+// MemoryBackend is an in-memory Backend that mimics the behaviour of the
+// Google Tasks API closely enough for tests: results are paginated, hidden
+// and deleted tasks are filtered, and positions follow parent/previous
+// placement. Like the real API, calls fail once their context is done. It is
+// safe for concurrent use.
+type MemoryBackend struct {
+	mu    sync.Mutex
+	lists []*tasks.TaskList
+	// items holds each list's tasks in tree order (a parent precedes its children).
+	items map[string][]*tasks.Task
+	seq   int
+	last  time.Time
+
+	// Now returns the current time. It defaults to time.Now and can be
+	// overridden to make timestamps deterministic.
+	Now func() time.Time
+}
+
+// NewMemoryBackend returns an empty MemoryBackend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		items: make(map[string][]*tasks.Task),
+		Now:   time.Now,
+	}
+}
+
+func notFound(what string) error {
+	return &googleapi.Error{Code: http.StatusNotFound, Message: what + " not found"}
+}
+
+func badRequest(msg string) error {
+	return &googleapi.Error{Code: http.StatusBadRequest, Message: msg}
+}
+
+// touch returns a strictly increasing modification timestamp and a fresh ETag.
+func (m *MemoryBackend) touch() (string, string) {
+	m.seq++
+	now := m.Now().UTC().Truncate(time.Millisecond)
+	if !now.After(m.last) {
+		now = m.last.Add(time.Millisecond)
+	}
+	m.last = now
+	return now.Format(timestampLayout), fmt.Sprintf("\"%d\"", m.seq)
+}
+
+func (m *MemoryBackend) newID(prefix string) string {
+	m.seq++
+	return fmt.Sprintf("%s%08d", prefix, m.seq)
+}
+
+func (m *MemoryBackend) findList(id string) (int, *tasks.TaskList) {
+	for i, tl := range m.lists {
+		if tl.Id == id {
+			return i, tl
+		}
+	}
+	return -1, nil
+}
+
+func (m *MemoryBackend) findTask(listID, taskID string) (int, *tasks.Task) {
+	for i, t := range m.items[listID] {
+		if t.Id == taskID && !t.Deleted {
+			return i, t
+		}
+	}
+	return -1, nil
+}
+
+func copyTaskList(tl *tasks.TaskList) *tasks.TaskList {
+	c := *tl
+	return &c
+}
+
+func copyTask(t *tasks.Task) *tasks.Task {
+	c := *t
+	if t.Completed != nil {
+		completed := *t.Completed
+		c.Completed = &completed
+	}
+	c.Links = nil
+	for _, l := range t.Links {
+		link := *l
+		c.Links = append(c.Links, &link)
+	}
+	return &c
+}
+
+// paginate slices n items according to a numeric page token.
+func paginate(n int, pageToken string, maxResults int64) (start, end int, next string, err error) {
+	if pageToken != "" {
+		start, err = strconv.Atoi(pageToken)
+		if err != nil || start < 0 || start > n {
+			return 0, 0, "", badRequest("invalid page token")
+		}
+	}
+	size := int(maxResults)
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	if size > maxPageSize {
+		size = maxPageSize
+	}
+	end = start + size
+	if end >= n {
+		return start, n, "", nil
+	}
+	return start, end, strconv.Itoa(end), nil
+}
+
+func (m *MemoryBackend) ListTaskLists(ctx context.Context, pageToken string) (*tasks.TaskLists, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	start, end, next, err := paginate(len(m.lists), pageToken, maxPageSize)
+	if err != nil {
+		return nil, err
+	}
+	out := &tasks.TaskLists{Kind: "tasks#taskLists", NextPageToken: next}
+	for _, tl := range m.lists[start:end] {
+		out.Items = append(out.Items, copyTaskList(tl))
+	}
+	return out, nil
+}
+
+func (m *MemoryBackend) GetTaskList(ctx context.Context, tasklistID string) (*tasks.TaskList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, tl := m.findList(tasklistID)
+	if tl == nil {
+		return nil, notFound("task list")
+	}
+	return copyTaskList(tl), nil
+}
+
+func (m *MemoryBackend) InsertTaskList(ctx context.Context, tl *tasks.TaskList) (*tasks.TaskList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if tl.Title == "" {
+		return nil, badRequest("task list title is required")
+	}
+	stored := &tasks.TaskList{Kind: "tasks#taskList", Id: m.newID("list"), Title: tl.Title}
+	stored.Updated, stored.Etag = m.touch()
+	m.lists = append(m.lists, stored)
+	m.items[stored.Id] = nil
+	return copyTaskList(stored), nil
+}
+
+func (m *MemoryBackend) PatchTaskList(ctx context.Context, tl *tasks.TaskList) (*tasks.TaskList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, stored := m.findList(tl.Id)
+	if stored == nil {
+		return nil, notFound("task list")
+	}
+	if tl.Title != "" {
+		stored.Title = tl.Title
+	}
+	stored.Updated, stored.Etag = m.touch()
+	return copyTaskList(stored), nil
+}
+
+func (m *MemoryBackend) DeleteTaskList(ctx context.Context, tasklistID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i, tl := m.findList(tasklistID)
+	if tl == nil {
+		return notFound("task list")
+	}
+	m.lists = append(m.lists[:i], m.lists[i+1:]...)
+	delete(m.items, tasklistID)
+	return nil
+}
+
+func (m *MemoryBackend) ListTasks(ctx context.Context, tasklistID string, opts ListOptions) (*tasks.Tasks, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, tl := m.findList(tasklistID); tl == nil {
+		return nil, notFound("task list")
+	}
+
+	var updatedMin time.Time
+	if opts.UpdatedMin != "" {
+		t, err := time.Parse(time.RFC3339, opts.UpdatedMin)
+		if err != nil {
+			return nil, badRequest("invalid updatedMin")
+		}
+		updatedMin = t
+	}
+
+	var visible []*tasks.Task
+	for _, t := range m.items[tasklistID] {
+		if t.Deleted && !opts.ShowDeleted {
+			continue
+		}
+		if t.Hidden && !opts.ShowHidden {
+			continue
+		}
+		if !updatedMin.IsZero() {
+			updated, err := time.Parse(time.RFC3339, t.Updated)
+			if err == nil && updated.Before(updatedMin) {
+				continue
+			}
+		}
+		visible = append(visible, t)
+	}
+
+	start, end, next, err := paginate(len(visible), opts.PageToken, opts.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	out := &tasks.Tasks{Kind: "tasks#tasks", NextPageToken: next}
+	for _, t := range visible[start:end] {
+		out.Items = append(out.Items, copyTask(t))
+	}
+	return out, nil
+}
+
+func (m *MemoryBackend) GetTask(ctx context.Context, tasklistID, taskID string) (*tasks.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, tl := m.findList(tasklistID); tl == nil {
+		return nil, notFound("task list")
+	}
+	for _, t := range m.items[tasklistID] {
+		if t.Id == taskID {
+			return copyTask(t), nil
+		}
+	}
+	return nil, notFound("task")
+}
+
+func (m *MemoryBackend) InsertTask(ctx context.Context, tasklistID string, t *tasks.Task, opts MoveOptions) (*tasks.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, tl := m.findList(tasklistID); tl == nil {
+		return nil, notFound("task list")
+	}
+	stored := copyTask(t)
+	stored.Kind = "tasks#task"
+	stored.Id = m.newID("task")
+	stored.Deleted = false
+	stored.Hidden = false
+	if stored.Status == "" {
+		stored.Status = "needsAction"
+	}
+	if stored.Status == "completed" && stored.Completed == nil {
+		now := m.Now().UTC().Format(timestampLayout)
+		stored.Completed = &now
+	}
+	stored.Updated, stored.Etag = m.touch()
+
+	if err := m.place(tasklistID, []*tasks.Task{stored}, opts); err != nil {
+		return nil, err
+	}
+	return copyTask(stored), nil
+}
+
+func (m *MemoryBackend) PatchTask(ctx context.Context, tasklistID string, t *tasks.Task) (*tasks.Task, error) {
+	return m.PatchTaskIfMatch(ctx, tasklistID, t, "")
+}
+
+// PatchTaskIfMatch patches a task if its ETag is etag. An empty etag always
+// matches.
+func (m *MemoryBackend) PatchTaskIfMatch(ctx context.Context, tasklistID string, t *tasks.Task, etag string) (*tasks.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, tl := m.findList(tasklistID); tl == nil {
+		return nil, notFound("task list")
+	}
+	i, stored := m.findTask(tasklistID, t.Id)
+	if stored == nil {
+		return nil, notFound("task")
+	}
+	if etag != "" && etag != stored.Etag {
+		return nil, &googleapi.Error{Code: http.StatusPreconditionFailed, Message: "Precondition Failed"}
+	}
+	patched, err := mergeTask(stored, t)
+	if err != nil {
+		return nil, err
+	}
+	if patched.Status == "completed" && patched.Completed == nil {
+		now := m.Now().UTC().Format(timestampLayout)
+		patched.Completed = &now
+	}
+	if patched.Status == "needsAction" {
+		patched.Completed = nil
+	}
+	patched.Updated, patched.Etag = m.touch()
+	m.items[tasklistID][i] = patched
+	return copyTask(patched), nil
+}
+
+// mergeTask applies the JSON representation of patch onto stored, honouring
+// ForceSendFields and NullFields the same way the REST API does. Read-only
+// fields are never taken from the patch.
+func mergeTask(stored, patch *tasks.Task) (*tasks.Task, error) {
+	base, err := json.Marshal(stored)
+	if err != nil {
+		return nil, err
+	}
+	delta, err := patch.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	var fields, changes map[string]json.RawMessage
+	if err := json.Unmarshal(base, &fields); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(delta, &changes); err != nil {
+		return nil, err
+	}
+	for k, v := range changes {
+		switch k {
+		case "id", "etag", "kind", "selfLink", "updated", "parent", "position", "deleted", "webViewLink":
+			continue
+		}
+		if string(v) == "null" {
+			delete(fields, k)
+			continue
+		}
+		fields[k] = v
+	}
+	merged, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	var out tasks.Task
+	if err := json.Unmarshal(merged, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (m *MemoryBackend) DeleteTask(ctx context.Context, tasklistID, taskID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, tl := m.findList(tasklistID); tl == nil {
+		return notFound("task list")
+	}
+	i, stored := m.findTask(tasklistID, taskID)
+	if stored == nil {
+		return notFound("task")
+	}
+	for _, t := range m.subtree(tasklistID, i) {
+		t.Deleted = true
+		t.Updated, t.Etag = m.touch()
+	}
+	m.renumber(tasklistID)
+	return nil
+}
+
+func (m *MemoryBackend) ClearTasks(ctx context.Context, tasklistID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, tl := m.findList(tasklistID); tl == nil {
+		return notFound("task list")
+	}
+	for _, t := range m.items[tasklistID] {
+		if t.Status == "completed" && !t.Hidden && !t.Deleted {
+			t.Hidden = true
+			t.Updated, t.Etag = m.touch()
+		}
+	}
+	return nil
+}
+
+func (m *MemoryBackend) MoveTask(ctx context.Context, tasklistID, taskID string, opts MoveOptions) (*tasks.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, tl := m.findList(tasklistID); tl == nil {
+		return nil, notFound("task list")
+	}
+	i, stored := m.findTask(tasklistID, taskID)
+	if stored == nil {
+		return nil, notFound("task")
+	}
+	dest := tasklistID
+	if opts.DestinationTasklist != "" {
+		if _, tl := m.findList(opts.DestinationTasklist); tl == nil {
+			return nil, notFound("destination task list")
+		}
+		dest = opts.DestinationTasklist
+	}
+	subtree := m.subtree(tasklistID, i)
+	for _, t := range subtree {
+		if t.Id == opts.Parent || (t.Id == opts.Previous && t != stored) {
+			return nil, badRequest("cannot move a task relative to its own subtask")
+		}
+	}
+	if opts.Previous == taskID && dest == tasklistID {
+		return copyTask(stored), nil
+	}
+
+	items := m.items[tasklistID]
+	m.items[tasklistID] = append(items[:i:i], items[i+len(subtree):]...)
+	if err := m.place(dest, subtree, opts); err != nil {
+		// Put the subtree back where it was.
+		rest := m.items[tasklistID]
+		m.items[tasklistID] = append(append(rest[:i:i], subtree...), rest[i:]...)
+		return nil, err
+	}
+	m.renumber(tasklistID)
+	stored.Updated, stored.Etag = m.touch()
+	return copyTask(stored), nil
+}
+
+// subtree returns the task at index i followed by all of its descendants.
+// Because items are kept in tree order, descendants are contiguous.
+func (m *MemoryBackend) subtree(listID string, i int) []*tasks.Task {
+	items := m.items[listID]
+	inTree := map[string]bool{items[i].Id: true}
+	end := i + 1
+	for end < len(items) && inTree[items[end].Parent] {
+		inTree[items[end].Id] = true
+		end++
+	}
+	return items[i:end]
+}
+
+// place inserts a task together with its descendants (subtree[0] being the
+// root) under opts.Parent, directly after opts.Previous, then renumbers positions.
+func (m *MemoryBackend) place(listID string, subtree []*tasks.Task, opts MoveOptions) error {
+	items := m.items[listID]
+	at := 0
+
+	if opts.Parent != "" {
+		pi, parent := m.findTask(listID, opts.Parent)
+		if parent == nil {
+			return badRequest("invalid parent task")
+		}
+		if parent.Parent != "" {
+			return badRequest("subtasks cannot have subtasks of their own")
+		}
+		at = pi + 1
+	}
+	if opts.Previous != "" {
+		pi, prev := m.findTask(listID, opts.Previous)
+		if prev == nil || prev.Parent != opts.Parent {
+			return badRequest("invalid previous task")
+		}
+		at = pi + len(m.subtree(listID, pi))
+	}
+	if opts.Parent != "" && len(subtree) > 1 {
+		return badRequest("a task with subtasks cannot become a subtask")
+	}
+
+	subtree[0].Parent = opts.Parent
+	out := make([]*tasks.Task, 0, len(items)+len(subtree))
+	out = append(out, items[:at]...)
+	out = append(out, subtree...)
+	out = append(out, items[at:]...)
+	m.items[listID] = out
+	m.renumber(listID)
+	return nil
+}
+
+// renumber assigns zero-padded positions to live tasks, counted per parent.
+// Tasks whose position changes count as updated, so incremental syncs see them.
+func (m *MemoryBackend) renumber(listID string) {
+	counts := make(map[string]int)
+	for _, t := range m.items[listID] {
+		if t.Deleted {
+			continue
+		}
+		position := fmt.Sprintf("%020d", counts[t.Parent])
+		counts[t.Parent]++
+		if t.Position != position {
+			t.Position = position
+			t.Updated, t.Etag = m.touch()
+		}
+	}
+}
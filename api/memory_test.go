@@ -0,0 +1,354 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/tasks/v1"
+)
+
+func newMemoryList(t *testing.T, m *MemoryBackend) string {
+	t.Helper()
+	tl, err := m.InsertTaskList(context.Background(), &tasks.TaskList{Title: "Test"})
+	if err != nil {
+		t.Fatalf("InsertTaskList: %v", err)
+	}
+	return tl.Id
+}
+
+func TestMemoryBackendPaging(t *testing.T) {
+	tests := []struct {
+		name       string
+		tasks      int
+		maxResults int64
+		pages      int
+	}{
+		{"empty list", 0, 0, 1},
+		{"default page size", 45, 0, 3},
+		{"exact pages", 40, 20, 2},
+		{"small pages", 7, 3, 3},
+		{"page size capped", 250, 500, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m := NewMemoryBackend()
+			list := newMemoryList(t, m)
+			// Each task is inserted first, so the list is in reverse order.
+			for i := 0; i < tt.tasks; i++ {
+				if _, err := m.InsertTask(ctx, list, &tasks.Task{Title: fmt.Sprint(i)}, MoveOptions{}); err != nil {
+					t.Fatalf("InsertTask: %v", err)
+				}
+			}
+
+			var titles []string
+			pages := 0
+			opts := ListOptions{MaxResults: tt.maxResults}
+			for {
+				r, err := m.ListTasks(ctx, list, opts)
+				if err != nil {
+					t.Fatalf("ListTasks: %v", err)
+				}
+				pages++
+				for _, task := range r.Items {
+					titles = append(titles, task.Title)
+				}
+				if r.NextPageToken == "" {
+					break
+				}
+				opts.PageToken = r.NextPageToken
+			}
+			if pages != tt.pages {
+				t.Errorf("got %d pages, want %d", pages, tt.pages)
+			}
+			if len(titles) != tt.tasks {
+				t.Fatalf("got %d tasks, want %d", len(titles), tt.tasks)
+			}
+			for i, title := range titles {
+				if want := fmt.Sprint(tt.tasks - 1 - i); title != want {
+					t.Fatalf("task %d is %q, want %q", i, title, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryBackendInvalidPageToken(t *testing.T) {
+	m := NewMemoryBackend()
+	list := newMemoryList(t, m)
+	for _, token := range []string{"x", "-1", "5"} {
+		_, err := m.ListTasks(context.Background(), list, ListOptions{PageToken: token})
+		var gerr *googleapi.Error
+		if !errors.As(err, &gerr) || gerr.Code != http.StatusBadRequest {
+			t.Errorf("page token %q: got %v, want 400", token, err)
+		}
+	}
+}
+
+func TestMemoryBackendHiddenAndDeleted(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryBackend()
+	list := newMemoryList(t, m)
+	for _, title := range []string{"deleted", "done", "open"} {
+		if _, err := m.InsertTask(ctx, list, &tasks.Task{Title: title}, MoveOptions{}); err != nil {
+			t.Fatalf("InsertTask: %v", err)
+		}
+	}
+	all, _ := m.ListTasks(ctx, list, ListOptions{})
+	byTitle := make(map[string]*tasks.Task)
+	for _, task := range all.Items {
+		byTitle[task.Title] = task
+	}
+	if _, err := m.PatchTask(ctx, list, &tasks.Task{Id: byTitle["done"].Id, Status: "completed"}); err != nil {
+		t.Fatalf("PatchTask: %v", err)
+	}
+	if err := m.ClearTasks(ctx, list); err != nil {
+		t.Fatalf("ClearTasks: %v", err)
+	}
+	if err := m.DeleteTask(ctx, list, byTitle["deleted"].Id); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+
+	tests := []struct {
+		opts ListOptions
+		want string
+	}{
+		{ListOptions{}, "open"},
+		{ListOptions{ShowHidden: true}, "open done"},
+		{ListOptions{ShowDeleted: true}, "open deleted"},
+		{ListOptions{ShowHidden: true, ShowDeleted: true}, "open done deleted"},
+	}
+	for _, tt := range tests {
+		r, err := m.ListTasks(ctx, list, tt.opts)
+		if err != nil {
+			t.Fatalf("ListTasks: %v", err)
+		}
+		var titles []string
+		for _, task := range r.Items {
+			titles = append(titles, task.Title)
+		}
+		if got := strings.Join(titles, " "); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryBackendETags(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryBackend()
+	list := newMemoryList(t, m)
+	task, err := m.InsertTask(ctx, list, &tasks.Task{Title: "a"}, MoveOptions{})
+	if err != nil {
+		t.Fatalf("InsertTask: %v", err)
+	}
+	first := task.Etag
+	second, err := m.PatchTask(ctx, list, &tasks.Task{Id: task.Id, Title: "b"})
+	if err != nil {
+		t.Fatalf("PatchTask: %v", err)
+	}
+	if second.Etag == first {
+		t.Fatalf("ETag %s did not change on patch", first)
+	}
+	if second.Updated <= task.Updated {
+		t.Errorf("updated went from %s to %s", task.Updated, second.Updated)
+	}
+
+	tests := []struct {
+		name   string
+		etag   string
+		status int
+	}{
+		{"stale", first, http.StatusPreconditionFailed},
+		{"unknown", `"nope"`, http.StatusPreconditionFailed},
+		{"current", second.Etag, 0},
+		{"empty always matches", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, err := m.GetTask(ctx, list, task.Id)
+			if err != nil {
+				t.Fatalf("GetTask: %v", err)
+			}
+			got, err := m.PatchTaskIfMatch(ctx, list, &tasks.Task{Id: task.Id, Notes: tt.name}, tt.etag)
+			if tt.status != 0 {
+				var gerr *googleapi.Error
+				if !errors.As(err, &gerr) || gerr.Code != tt.status {
+					t.Fatalf("got %v, want %d", err, tt.status)
+				}
+				after, _ := m.GetTask(ctx, list, task.Id)
+				if after.Etag != before.Etag || after.Notes != before.Notes {
+					t.Errorf("failed patch changed the task")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PatchTaskIfMatch: %v", err)
+			}
+			if got.Notes != tt.name || got.Etag == before.Etag {
+				t.Errorf("got notes %q and ETag %s, want %q and a new ETag", got.Notes, got.Etag, tt.name)
+			}
+			if got.Title != "b" {
+				t.Errorf("patch dropped the title: %q", got.Title)
+			}
+		})
+	}
+}
+
+// memoryTree returns the live tasks of a list in order, written as
+// "title@position" with subtasks marked by ">".
+func memoryTree(t *testing.T, m *MemoryBackend, list string) string {
+	t.Helper()
+	r, err := m.ListTasks(context.Background(), list, ListOptions{MaxResults: maxPageSize})
+	if err != nil {
+		t.Fatalf("ListTasks: %v", err)
+	}
+	var out []string
+	for _, task := range r.Items {
+		prefix := ""
+		if task.Parent != "" {
+			prefix = ">"
+		}
+		position, err := strconv.Atoi(task.Position)
+		if err != nil {
+			t.Fatalf("task %s has position %q", task.Title, task.Position)
+		}
+		out = append(out, fmt.Sprintf("%s%s@%d", prefix, task.Title, position))
+	}
+	return strings.Join(out, " ")
+}
+
+func TestMemoryBackendPositions(t *testing.T) {
+	// Each step inserts or moves the task with the given title; parent and
+	// previous name other tasks by title.
+	type step struct {
+		move             bool
+		title            string
+		parent, previous string
+	}
+	tests := []struct {
+		name    string
+		steps   []step
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "inserts go first",
+			steps: []step{{title: "a"}, {title: "b"}, {title: "c"}},
+			want:  "c@0 b@1 a@2",
+		},
+		{
+			name:  "insert after previous",
+			steps: []step{{title: "a"}, {title: "b", previous: "a"}, {title: "c", previous: "a"}},
+			want:  "a@0 c@1 b@2",
+		},
+		{
+			name:  "subtasks are numbered per parent",
+			steps: []step{{title: "a"}, {title: "b", previous: "a"}, {title: "x", parent: "a"}, {title: "y", parent: "a", previous: "x"}},
+			want:  "a@0 >x@0 >y@1 b@1",
+		},
+		{
+			name:  "previous skips its subtasks",
+			steps: []step{{title: "a"}, {title: "x", parent: "a"}, {title: "b", previous: "a"}},
+			want:  "a@0 >x@0 b@1",
+		},
+		{
+			name:  "move carries subtasks",
+			steps: []step{{title: "a"}, {title: "b", previous: "a"}, {title: "x", parent: "a"}, {move: true, title: "a", previous: "b"}},
+			want:  "b@0 a@1 >x@0",
+		},
+		{
+			name:  "move into parent",
+			steps: []step{{title: "a"}, {title: "b", previous: "a"}, {move: true, title: "b", parent: "a"}},
+			want:  "a@0 >b@0",
+		},
+		{
+			name:    "no subtasks of subtasks",
+			steps:   []step{{title: "a"}, {title: "x", parent: "a"}, {title: "y", parent: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "previous must share the parent",
+			steps:   []step{{title: "a"}, {title: "x", parent: "a"}, {title: "b", previous: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "no move under own subtask",
+			steps:   []step{{title: "a"}, {title: "x", parent: "a"}, {move: true, title: "a", previous: "x"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m := NewMemoryBackend()
+			list := newMemoryList(t, m)
+			ids := make(map[string]string)
+			var err error
+			for _, s := range tt.steps {
+				opts := MoveOptions{Parent: ids[s.parent], Previous: ids[s.previous]}
+				var task *tasks.Task
+				if s.move {
+					task, err = m.MoveTask(ctx, list, ids[s.title], opts)
+				} else {
+					task, err = m.InsertTask(ctx, list, &tasks.Task{Title: s.title}, opts)
+				}
+				if err != nil {
+					break
+				}
+				ids[s.title] = task.Id
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %s, want an error", memoryTree(t, m, list))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := memoryTree(t, m, list); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryBackendMoveToList(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryBackend()
+	from := newMemoryList(t, m)
+	to := newMemoryList(t, m)
+	a, _ := m.InsertTask(ctx, from, &tasks.Task{Title: "a"}, MoveOptions{})
+	m.InsertTask(ctx, from, &tasks.Task{Title: "x"}, MoveOptions{Parent: a.Id})
+	m.InsertTask(ctx, from, &tasks.Task{Title: "b"}, MoveOptions{Previous: a.Id})
+	m.InsertTask(ctx, to, &tasks.Task{Title: "c"}, MoveOptions{})
+
+	if _, err := m.MoveTask(ctx, from, a.Id, MoveOptions{DestinationTasklist: to}); err != nil {
+		t.Fatalf("MoveTask: %v", err)
+	}
+	if got, want := memoryTree(t, m, from), "b@0"; got != want {
+		t.Errorf("source: got %s, want %s", got, want)
+	}
+	if got, want := memoryTree(t, m, to), "a@0 >x@0 c@1"; got != want {
+		t.Errorf("destination: got %s, want %s", got, want)
+	}
+}
+
+func TestMemoryBackendContextDone(t *testing.T) {
+	m := NewMemoryBackend()
+	list := newMemoryList(t, m)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.InsertTask(ctx, list, &tasks.Task{Title: "a"}, MoveOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if got := memoryTree(t, m, list); got != "" {
+		t.Errorf("task was inserted: %s", got)
+	}
+}
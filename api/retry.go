@@ -0,0 +1,153 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+	// retryAfterMax caps how long a Retry-After header can make us wait.
+	retryAfterMax = time.Minute
+)
+
+// retryTransport retries requests that failed for reasons that are likely to
+// go away: rate limiting (429), server errors (5xx) and network errors.
+//
+// Only requests that are safe to repeat are retried after reaching the
+// server: GET, HEAD, PUT, DELETE and PATCH (a tasks patch sets fields to
+// fixed values). Inserts, moves and patches sent with If-Match are only
+// retried when the server certainly did not act on them: on 429, or when the
+// connection could not be made at all. A conditional patch that was applied
+// would fail its retry with 412, as if it conflicted with itself.
+type retryTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+}
+
+func newRetryTransport(base http.RoundTripper, maxRetries int) *retryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &retryTransport{base: base, maxRetries: maxRetries}
+}
+
+func (rt *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil {
+			// The previous attempt consumed the body.
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := rt.base.RoundTrip(req)
+		if attempt >= rt.maxRetries || !rt.shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		delay := backoff(attempt)
+		if resp != nil {
+			if d, ok := retryAfter(resp); ok {
+				delay = d
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := sleepContext(req, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// shouldRetry decides whether a request that got resp or err is worth
+// sending again.
+func (rt *retryTransport) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.GetBody == nil {
+		return false // the body cannot be sent again
+	}
+	if err != nil {
+		if req.Context().Err() != nil {
+			return false
+		}
+		if isUnknownHost(err) {
+			return false
+		}
+		return couldNotConnect(err) || idempotent(req)
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case resp.StatusCode >= 500:
+		return idempotent(req)
+	}
+	return false
+}
+
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPatch:
+		return req.Header.Get("If-Match") == ""
+	}
+	return false
+}
+
+// isUnknownHost reports a failed DNS lookup for a name that does not exist,
+// typically because there is no network at all. Retrying only delays the
+// fallback to the local cache.
+func isUnknownHost(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// backoff returns the delay before retry number attempt+1: exponential, capped
+// at retryMaxDelay, with jitter so that parallel requests spread out.
+func backoff(attempt int) time.Duration {
+	d := retryBaseDelay << attempt
+	if d <= 0 || d > retryMaxDelay {
+		d = retryMaxDelay
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryAfter parses the Retry-After header, given either in seconds or as an
+// HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	var d time.Duration
+	if secs, err := strconv.Atoi(v); err == nil {
+		d = time.Duration(secs) * time.Second
+	} else if at, err := http.ParseTime(v); err == nil {
+		d = time.Until(at)
+	} else {
+		return 0, false
+	}
+	return min(max(d, 0), retryAfterMax), true
+}
+
+// sleepContext waits for d, returning early with an error when the request's
+// context ends.
+func sleepContext(req *http.Request, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
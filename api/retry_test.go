@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		method  string
+		ifMatch string
+		status  int
+		tries   int
+	}{
+		{http.MethodGet, "", http.StatusServiceUnavailable, 3},
+		{http.MethodPatch, "", http.StatusInternalServerError, 3},
+		{http.MethodPatch, `"1"`, http.StatusInternalServerError, 1},
+		{http.MethodPatch, `"1"`, http.StatusTooManyRequests, 3},
+		{http.MethodPost, "", http.StatusBadGateway, 1},
+		{http.MethodPost, "", http.StatusTooManyRequests, 3},
+		{http.MethodDelete, "", http.StatusBadRequest, 1},
+	}
+	for _, tt := range tests {
+		tries := 0
+		rt := newRetryTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			tries++
+			return &http.Response{
+				StatusCode: tt.status,
+				Header:     http.Header{"Retry-After": {"0"}},
+				Body:       http.NoBody,
+			}, nil
+		}), 2)
+		req, err := http.NewRequest(tt.method, "http://tasks.test/x", strings.NewReader("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.ifMatch != "" {
+			req.Header.Set("If-Match", tt.ifMatch)
+		}
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.method, err)
+		}
+		if resp.StatusCode != tt.status || tries != tt.tries {
+			t.Errorf("%s If-Match=%q after %d: got %d in %d tries, want %d tries", tt.method, tt.ifMatch, tt.status, resp.StatusCode, tries, tt.tries)
+		}
+	}
+}
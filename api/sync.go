@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/BRO3886/gtasks/internal/cache"
+	"google.golang.org/api/tasks/v1"
+)
+
+// fullSyncInterval is how long incremental syncs of a list are trusted
+// before every task is fetched again. Full syncs catch changes the API does
+// not report through updatedMin, such as tasks moved to another list.
+const fullSyncInterval = 24 * time.Hour
+
+// SyncTasks brings the cached copy of a task list up to date and returns the
+// tasks that changed since the previous sync. Removed tasks are returned with
+// Deleted set.
+//
+// The first sync of a list fetches every task. Later syncs only request tasks
+// updated at or after the latest Updated timestamp seen so far, including
+// deleted and hidden ones, so an unchanged list costs a single small request.
+func SyncTasks(ctx context.Context, b Backend, store *cache.Store, tasklistID string) ([]*tasks.Task, error) {
+	cursor, fullSyncAt := store.SyncState(tasklistID)
+	if cursor == "" || time.Since(fullSyncAt) > fullSyncInterval {
+		return fullSync(ctx, b, store, tasklistID)
+	}
+
+	changed, err := listAllTasks(ctx, b, tasklistID, ListOptions{
+		ShowHidden:  true,
+		ShowDeleted: true,
+		UpdatedMin:  cursor,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// updatedMin is inclusive, so the tasks at the cursor come back every
+	// time. Only report the ones that actually changed.
+	var out []*tasks.Task
+	for _, t := range changed {
+		cached, ok := store.Task(tasklistID, t.Id)
+		if t.Deleted && !ok {
+			continue
+		}
+		if !t.Deleted && ok && cached.Etag == t.Etag {
+			continue
+		}
+		out = append(out, t)
+	}
+	store.MergeTasks(tasklistID, changed, time.Now())
+	return out, nil
+}
+
+// fullSync replaces the cached list with every task in it and reports the
+// difference from what was cached before.
+func fullSync(ctx context.Context, b Backend, store *cache.Store, tasklistID string) ([]*tasks.Task, error) {
+	all, err := listAllTasks(ctx, b, tasklistID, ListOptions{ShowHidden: true})
+	if err != nil {
+		return nil, err
+	}
+
+	previous, _, _ := store.Tasks(tasklistID)
+	before := make(map[string]*tasks.Task, len(previous))
+	for _, t := range previous {
+		before[t.Id] = t
+	}
+
+	var out []*tasks.Task
+	for _, t := range all {
+		if old, ok := before[t.Id]; !ok || old.Etag != t.Etag {
+			out = append(out, t)
+		}
+		delete(before, t.Id)
+	}
+	for _, t := range previous {
+		if _, gone := before[t.Id]; gone {
+			t.Deleted = true
+			out = append(out, t)
+		}
+	}
+
+	store.SetTasks(tasklistID, all, time.Now())
+	return out, nil
+}
+
+// listAllTasks pages through tasks.list with the given options.
+func listAllTasks(ctx context.Context, b Backend, tasklistID string, opts ListOptions) ([]*tasks.Task, error) {
+	var all []*tasks.Task
+	opts.MaxResults = maxPageSize
+	opts.PageToken = ""
+	for {
+		r, err := b.ListTasks(ctx, tasklistID, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to sync tasks: %w", err)
+		}
+		all = append(all, r.Items...)
+		if r.NextPageToken == "" {
+			return all, nil
+		}
+		opts.PageToken = r.NextPageToken
+	}
+}
@@ -1,9 +1,10 @@
 package api
 
 import (
+	"context"
 	"errors"
+	"fmt"
 
-	"github.com/BRO3886/gtasks/internal/utils"
 	"google.golang.org/api/tasks/v1"
 )
 
@@ -21,34 +22,46 @@ func (e TaskList) Swap(i, j int) {
 	e[i], e[j] = e[j], e[i]
 }
 
-func GetTaskLists(srv *tasks.Service) ([]tasks.TaskList, error) {
-	r, err := srv.Tasklists.List().Do()
-	if err != nil {
-		utils.ErrorP("Unable to retrieve task lists. %v\n", err)
-	}
-
+// GetTaskLists returns every task list, following pagination.
+func GetTaskLists(ctx context.Context, b Backend) ([]tasks.TaskList, error) {
 	var list []tasks.TaskList
+	pageToken := ""
 
-	if len(r.Items) == 0 {
-		return nil, errors.New("no Tasklist found")
+	for {
+		r, err := b.ListTaskLists(ctx, pageToken)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve task lists: %w", err)
+		}
+		for _, item := range r.Items {
+			list = append(list, *item)
+		}
+		if r.NextPageToken == "" {
+			break
+		}
+		pageToken = r.NextPageToken
 	}
 
-	for _, item := range r.Items {
-		list = append(list, *item)
+	if len(list) == 0 {
+		return nil, errors.New("no Tasklist found")
 	}
 
 	return list, nil
 }
 
-func UpdateTaskList(srv *tasks.Service, tl *tasks.TaskList) (*tasks.TaskList, error) {
-	r, err := srv.Tasklists.Patch(tl.Id, tl).Do()
+// CreateTaskList creates a new task list with the given title
+func CreateTaskList(ctx context.Context, b Backend, title string) (*tasks.TaskList, error) {
+	return b.InsertTaskList(ctx, &tasks.TaskList{Title: title})
+}
+
+func UpdateTaskList(ctx context.Context, b Backend, tl *tasks.TaskList) (*tasks.TaskList, error) {
+	r, err := b.PatchTaskList(ctx, tl)
 	if err != nil {
 		return nil, err
 	}
 	return r, nil
 }
 
-func DeleteTaskList(srv *tasks.Service, tID string) error {
-	err := srv.Tasklists.Delete(tID).Do()
+func DeleteTaskList(ctx context.Context, b Backend, tID string) error {
+	err := b.DeleteTaskList(ctx, tID)
 	return err
 }
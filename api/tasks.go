@@ -1,25 +1,63 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"sort"
+	"strings"
 
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/tasks/v1"
 )
 
+// ErrNoTasks is returned by GetTasks when a task list has no matching tasks
+var ErrNoTasks = errors.New("no Tasks found")
+
 // CreateTask used to create tasks
-func CreateTask(srv *tasks.Service, task *tasks.Task, tasklistID string) (*tasks.Task, error) {
-	r, err := srv.Tasks.Insert(tasklistID, task).Do()
+func CreateTask(ctx context.Context, b Backend, task *tasks.Task, tasklistID string) (*tasks.Task, error) {
+	r, err := b.InsertTask(ctx, tasklistID, task, MoveOptions{})
 	if err != nil {
 		return nil, err
 	}
 	return r, nil
 }
 
+// CreateSubtask used to create a task under an existing top-level task
+func CreateSubtask(ctx context.Context, b Backend, task *tasks.Task, tasklistID string, parentID string) (*tasks.Task, error) {
+	return b.InsertTask(ctx, tasklistID, task, MoveOptions{Parent: parentID})
+}
+
+// GetAllTasks retrieves every task of a list, including hidden and deleted
+// ones as far as the backend keeps them.
+func GetAllTasks(ctx context.Context, b Backend, id string) ([]*tasks.Task, error) {
+	var all []*tasks.Task
+	opts := ListOptions{MaxResults: 100, ShowHidden: true, ShowDeleted: true}
+	for {
+		r, err := b.ListTasks(ctx, id, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve tasks: %w", err)
+		}
+		all = append(all, r.Items...)
+		if r.NextPageToken == "" {
+			return all, nil
+		}
+		opts.PageToken = r.NextPageToken
+	}
+}
+
+// CreateTaskAfter creates a task right after previous among the subtasks of
+// parent. An empty parent means the top level, and an empty previous the
+// first position.
+func CreateTaskAfter(ctx context.Context, b Backend, task *tasks.Task, tasklistID, parentID, previousID string) (*tasks.Task, error) {
+	return b.InsertTask(ctx, tasklistID, task, MoveOptions{Parent: parentID, Previous: previousID})
+}
+
 // GetTasks used to retreive tasks.
 // If maxResults is 0, fetches all tasks with pagination.
 // If maxResults > 0, limits the number of tasks returned.
-func GetTasks(srv *tasks.Service, id string, includeCompleted bool, maxResults int) ([]*tasks.Task, error) {
+func GetTasks(ctx context.Context, b Backend, id string, includeCompleted bool, maxResults int) ([]*tasks.Task, error) {
 	var allTasks []*tasks.Task
 	pageToken := ""
 
@@ -30,14 +68,13 @@ func GetTasks(srv *tasks.Service, id string, includeCompleted bool, maxResults i
 	}
 
 	for {
-		call := srv.Tasks.List(id).ShowHidden(includeCompleted).MaxResults(pageSize)
-		if pageToken != "" {
-			call = call.PageToken(pageToken)
-		}
-
-		r, err := call.Do()
+		r, err := b.ListTasks(ctx, id, ListOptions{
+			PageToken:  pageToken,
+			MaxResults: pageSize,
+			ShowHidden: includeCompleted,
+		})
 		if err != nil {
-			return nil, fmt.Errorf("unable to retrieve tasks: %v", err)
+			return nil, fmt.Errorf("unable to retrieve tasks: %w", err)
 		}
 
 		allTasks = append(allTasks, r.Items...)
@@ -55,7 +92,7 @@ func GetTasks(srv *tasks.Service, id string, includeCompleted bool, maxResults i
 	}
 
 	if len(allTasks) == 0 {
-		return nil, errors.New("no Tasks found")
+		return nil, ErrNoTasks
 	}
 
 	if includeCompleted {
@@ -80,8 +117,8 @@ func MakeMap(taskList []*tasks.Task) map[string]tasks.Task {
 }
 
 // GetTaskInfo to get more info about a task
-func GetTaskInfo(srv *tasks.Service, id string, taskID string) (*tasks.Task, error) {
-	r, err := srv.Tasks.Get(id, taskID).Do()
+func GetTaskInfo(ctx context.Context, b Backend, id string, taskID string) (*tasks.Task, error) {
+	r, err := b.GetTask(ctx, id, taskID)
 	if err != nil {
 		return nil, err
 	}
@@ -89,22 +126,153 @@ func GetTaskInfo(srv *tasks.Service, id string, taskID string) (*tasks.Task, err
 }
 
 // UpdateTask used to update task data
-func UpdateTask(srv *tasks.Service, t *tasks.Task, tListID string) (*tasks.Task, error) {
-	r, err := srv.Tasks.Patch(tListID, t.Id, t).Do()
+//
+// If t carries an ETag the update only applies if the task is unchanged on the
+// server since it was fetched; otherwise it fails with an error for which
+// IsConflict is true.
+func UpdateTask(ctx context.Context, b Backend, t *tasks.Task, tListID string) (*tasks.Task, error) {
+	var (
+		r   *tasks.Task
+		err error
+	)
+	if t.Etag != "" {
+		r, err = b.PatchTaskIfMatch(ctx, tListID, t, t.Etag)
+	} else {
+		r, err = b.PatchTask(ctx, tListID, t)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return r, nil
 }
 
+// IsConflict reports whether err is the API refusing a conditional update
+// because the task changed since it was fetched.
+func IsConflict(err error) bool {
+	var gerr *googleapi.Error
+	return errors.As(err, &gerr) && gerr.Code == http.StatusPreconditionFailed
+}
+
 // DeleteTask used to delete a task
-func DeleteTask(srv *tasks.Service, id string, tid string) error {
-	err := srv.Tasks.Delete(tid, id).Do()
-	return err
+func DeleteTask(ctx context.Context, b Backend, taskID string, tasklistID string) error {
+	return b.DeleteTask(ctx, tasklistID, taskID)
 }
 
 // ClearTasks clears all completed tasks from a task list.
 // Completed tasks are marked as hidden and no longer returned by default.
-func ClearTasks(srv *tasks.Service, tasklistID string) error {
-	return srv.Tasks.Clear(tasklistID).Do()
+func ClearTasks(ctx context.Context, b Backend, tasklistID string) error {
+	return b.ClearTasks(ctx, tasklistID)
+}
+
+// MoveTask moves a task under parent (empty for top level) directly after
+// previous (empty to make it the first of its siblings).
+func MoveTask(ctx context.Context, b Backend, tasklistID string, taskID string, parent string, previous string) (*tasks.Task, error) {
+	return b.MoveTask(ctx, tasklistID, taskID, MoveOptions{Parent: parent, Previous: previous})
+}
+
+// MoveTaskToList moves a task and its subtasks into another task list.
+// The API refuses some cross-list moves (recurring tasks, for example); in that
+// case the tasks are copied into the destination and deleted from the source.
+func MoveTaskToList(ctx context.Context, b Backend, tasklistID string, taskID string, destinationID string) (*tasks.Task, error) {
+	moved, err := b.MoveTask(ctx, tasklistID, taskID, MoveOptions{DestinationTasklist: destinationID})
+	if err == nil {
+		return moved, nil
+	}
+	if !moveRefused(ctx, b, err, tasklistID, taskID, destinationID) {
+		return nil, err
+	}
+	return copyTaskToList(ctx, b, tasklistID, taskID, destinationID)
+}
+
+// moveRefused reports whether err is the API refusing to move the task into
+// the destination list, rather than rejecting the request: a 400 Bad Request
+// for a task and a destination list that both exist.
+func moveRefused(ctx context.Context, b Backend, err error, tasklistID string, taskID string, destinationID string) bool {
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) || gerr.Code != http.StatusBadRequest || destinationID == tasklistID {
+		return false
+	}
+	if _, err := b.GetTaskList(ctx, destinationID); err != nil {
+		return false
+	}
+	t, err := b.GetTask(ctx, tasklistID, taskID)
+	return err == nil && !t.Deleted
+}
+
+// copyTaskToList recreates a task and its subtasks in another list, then
+// deletes the originals. If copying fails, the copies made so far are deleted
+// again and the originals are left untouched.
+func copyTaskToList(ctx context.Context, b Backend, tasklistID string, taskID string, destinationID string) (*tasks.Task, error) {
+	t, err := b.GetTask(ctx, tasklistID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	var children []*tasks.Task
+	pageToken := ""
+	for {
+		r, err := b.ListTasks(ctx, tasklistID, ListOptions{PageToken: pageToken, MaxResults: 100, ShowHidden: true})
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve subtasks: %v", err)
+		}
+		for _, c := range r.Items {
+			if c.Parent == taskID {
+				children = append(children, c)
+			}
+		}
+		if r.NextPageToken == "" {
+			break
+		}
+		pageToken = r.NextPageToken
+	}
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].Position < children[j].Position
+	})
+
+	created, err := b.InsertTask(ctx, destinationID, CopyableTask(t), MoveOptions{})
+	if err != nil {
+		return nil, err
+	}
+	previous := ""
+	for _, c := range children {
+		cc, err := b.InsertTask(ctx, destinationID, CopyableTask(c), MoveOptions{Parent: created.Id, Previous: previous})
+		if err != nil {
+			// Deleting the copy deletes its subtasks with it. Do so even if
+			// ctx is done, so a retry does not leave duplicates behind.
+			if derr := b.DeleteTask(context.WithoutCancel(ctx), destinationID, created.Id); derr != nil {
+				return nil, fmt.Errorf("failed to copy subtask %q of %q: %v; the partial copy in the destination could not be removed: %v", c.Title, t.Title, err, derr)
+			}
+			return nil, fmt.Errorf("failed to copy subtask %q of %q (original kept): %v", c.Title, t.Title, err)
+		}
+		previous = cc.Id
+	}
+
+	if err := b.DeleteTask(ctx, tasklistID, taskID); err != nil {
+		return nil, fmt.Errorf("copied %q but could not delete the original: %v", t.Title, err)
+	}
+	return created, nil
+}
+
+// CopyableTask returns the user-editable fields of t for inserting a copy.
+// Links are read-only in the API, so they are preserved by appending them to
+// the notes.
+func CopyableTask(t *tasks.Task) *tasks.Task {
+	notes := t.Notes
+	if len(t.Links) > 0 {
+		var lines []string
+		for _, l := range t.Links {
+			lines = append(lines, "- "+l.Link)
+		}
+		if notes != "" {
+			notes += "\n\n"
+		}
+		notes += "Links:\n" + strings.Join(lines, "\n")
+	}
+	return &tasks.Task{
+		Title:     t.Title,
+		Notes:     notes,
+		Due:       t.Due,
+		Status:    t.Status,
+		Completed: t.Completed,
+	}
 }
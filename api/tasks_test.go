@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/tasks/v1"
+)
+
+// refusingBackend refuses cross-list moves with a 400, as the API does for
+// recurring tasks, and fails the insert numbered failInsert (from 1).
+type refusingBackend struct {
+	*MemoryBackend
+	inserts    int
+	failInsert int
+}
+
+func (r *refusingBackend) MoveTask(ctx context.Context, tasklistID, taskID string, opts MoveOptions) (*tasks.Task, error) {
+	if opts.DestinationTasklist != "" {
+		return nil, &googleapi.Error{Code: http.StatusBadRequest, Message: "Bad Request"}
+	}
+	return r.MemoryBackend.MoveTask(ctx, tasklistID, taskID, opts)
+}
+
+func (r *refusingBackend) InsertTask(ctx context.Context, tasklistID string, t *tasks.Task, opts MoveOptions) (*tasks.Task, error) {
+	r.inserts++
+	if r.inserts == r.failInsert {
+		return nil, &googleapi.Error{Code: http.StatusServiceUnavailable, Message: "Service Unavailable"}
+	}
+	return r.MemoryBackend.InsertTask(ctx, tasklistID, t, opts)
+}
+
+func TestMoveTaskToListFallback(t *testing.T) {
+	tests := []struct {
+		name        string
+		failInsert  int
+		destination string // "missing" for a list that does not exist
+		wantErr     bool
+		wantFrom    string
+		wantTo      string
+	}{
+		{name: "copies when refused", wantFrom: "b@0", wantTo: "a@0 >x@0 >y@1"},
+		{name: "parent copy fails", failInsert: 1, wantErr: true, wantFrom: "a@0 >x@0 >y@1 b@1"},
+		{name: "subtask copy fails", failInsert: 3, wantErr: true, wantFrom: "a@0 >x@0 >y@1 b@1"},
+		{name: "missing destination", destination: "missing", wantErr: true, wantFrom: "a@0 >x@0 >y@1 b@1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m := NewMemoryBackend()
+			from := newMemoryList(t, m)
+			to := newMemoryList(t, m)
+			a, _ := m.InsertTask(ctx, from, &tasks.Task{Title: "a"}, MoveOptions{})
+			x, _ := m.InsertTask(ctx, from, &tasks.Task{Title: "x"}, MoveOptions{Parent: a.Id})
+			m.InsertTask(ctx, from, &tasks.Task{Title: "y"}, MoveOptions{Parent: a.Id, Previous: x.Id})
+			m.InsertTask(ctx, from, &tasks.Task{Title: "b"}, MoveOptions{Previous: a.Id})
+
+			destination := to
+			if tt.destination != "" {
+				destination = tt.destination
+			}
+			b := &refusingBackend{MemoryBackend: m, failInsert: tt.failInsert}
+			_, err := MoveTaskToList(ctx, b, from, a.Id, destination)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, tt.wantErr)
+			}
+			if got := memoryTree(t, m, from); got != tt.wantFrom {
+				t.Errorf("source: got %s, want %s", got, tt.wantFrom)
+			}
+			if got := memoryTree(t, m, to); got != tt.wantTo {
+				t.Errorf("destination: got %s, want %s", got, tt.wantTo)
+			}
+		})
+	}
+}
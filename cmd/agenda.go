@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BRO3886/gtasks/api"
+	"github.com/BRO3886/gtasks/internal/utils"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"google.golang.org/api/tasks/v1"
+)
+
+// agendaGroups lists the agenda sections in display order.
+var agendaGroups = []string{"Overdue", "Today", "Tomorrow", "This week", "Later", "No date"}
+
+var agendaCmd = &cobra.Command{
+	Use:   "agenda",
+	Short: "View pending tasks from all tasklists grouped by due date",
+	Long: `
+	Use this command to see pending tasks from every tasklist
+	grouped into Overdue, Today, Tomorrow, This week (the next
+	7 days), Later and No date.
+	You can control output with --format: table (default), json, csv.
+	Use --filter to narrow the agenda with a filter expression.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
+		if err != nil {
+			utils.ErrorP("Failed to get service: %v\n", err)
+			return
+		}
+		expr := parseFilterFlag(agendaFlags.filter)
+		lists, err := api.GetTaskLists(ctx, backend)
+		if err != nil {
+			utils.ErrorP("Error %v\n", err)
+			return
+		}
+
+		items := fetchAllTasks(ctx, backend, lists, false)
+		if expr != nil {
+			var matched []listTask
+			for _, item := range items {
+				if expr.Match(item.Task) {
+					matched = append(matched, item)
+				}
+			}
+			items = matched
+		}
+		groups := groupAgenda(items, time.Now())
+		warnIfStale()
+
+		switch agendaFlags.format {
+		case "json":
+			outputAgendaJSON(groups)
+		case "csv":
+			outputAgendaCSV(groups)
+		default:
+			outputAgendaTable(groups)
+		}
+	},
+}
+
+var agendaFlags struct {
+	format string
+	filter string
+}
+
+func init() {
+	agendaCmd.Flags().StringVar(&agendaFlags.format, "format", "table", "output format: table, json, csv")
+	agendaCmd.Flags().StringVar(&agendaFlags.filter, "filter", "", filterFlagUsage)
+	rootCmd.AddCommand(agendaCmd)
+}
+
+// listTask is a task together with the list it belongs to.
+type listTask struct {
+	Task     *tasks.Task
+	ListID   string
+	ListName string
+}
+
+// fetchAllTasks loads tasks from all lists concurrently, in list order and
+// by position within each list. Lists that fail to load are reported and skipped.
+func fetchAllTasks(ctx context.Context, backend api.Backend, lists []tasks.TaskList, includeCompleted bool) []listTask {
+	results := make([][]listTask, len(lists))
+	errs := make([]error, len(lists))
+
+	var wg sync.WaitGroup
+	for i, tl := range lists {
+		wg.Add(1)
+		go func(i int, tl tasks.TaskList) {
+			defer wg.Done()
+			taskItems, err := api.GetTasks(ctx, backend, tl.Id, includeCompleted, 0)
+			if err != nil {
+				if !errors.Is(err, api.ErrNoTasks) {
+					errs[i] = err
+				}
+				return
+			}
+			utils.Sort(taskItems, "position")
+			for _, t := range taskItems {
+				results[i] = append(results[i], listTask{Task: t, ListID: tl.Id, ListName: tl.Title})
+			}
+		}(i, tl)
+	}
+	wg.Wait()
+
+	var items []listTask
+	for i, r := range results {
+		if errs[i] != nil {
+			utils.Warn("Skipping %s: %v\n", lists[i].Title, errs[i])
+			continue
+		}
+		items = append(items, r...)
+	}
+	return items
+}
+
+// dueDate returns the calendar date a task is due. The API stores due dates
+// as midnight UTC, so the date is read in UTC and placed in loc.
+func dueDate(due string, loc *time.Location) (time.Time, bool) {
+	if due == "" {
+		return time.Time{}, false
+	}
+	parsed, err := time.Parse(time.RFC3339, due)
+	if err != nil {
+		return time.Time{}, false
+	}
+	y, m, d := parsed.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc), true
+}
+
+// agendaGroup returns the agenda section a task belongs in relative to now.
+func agendaGroup(t *tasks.Task, now time.Time) string {
+	due, ok := dueDate(t.Due, now.Location())
+	if !ok {
+		return "No date"
+	}
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+
+	switch {
+	case due.Before(today):
+		return "Overdue"
+	case due.Equal(today):
+		return "Today"
+	case due.Equal(today.AddDate(0, 0, 1)):
+		return "Tomorrow"
+	case due.Before(today.AddDate(0, 0, 7)):
+		return "This week"
+	default:
+		return "Later"
+	}
+}
+
+// groupAgenda buckets items by agendaGroup, sorting each bucket by due date.
+func groupAgenda(items []listTask, now time.Time) map[string][]listTask {
+	groups := make(map[string][]listTask)
+	for _, item := range items {
+		g := agendaGroup(item.Task, now)
+		groups[g] = append(groups[g], item)
+	}
+	for _, g := range groups {
+		sort.SliceStable(g, func(i, j int) bool {
+			return g[i].Task.Due < g[j].Task.Due
+		})
+	}
+	return groups
+}
+
+type AgendaOutput struct {
+	Group       string `json:"group"`
+	List        string `json:"list"`
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Due         string `json:"due,omitempty"`
+}
+
+func outputAgendaTable(groups map[string][]listTask) {
+	empty := true
+	for _, name := range agendaGroups {
+		items := groups[name]
+		if len(items) == 0 {
+			continue
+		}
+		if !empty {
+			utils.Print("\n")
+		}
+		empty = false
+
+		style := utils.InfoStyle
+		if name == "Overdue" {
+			style = utils.ErrorStyle
+		}
+		style.Printf("%s (%d)\n", name, len(items))
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Title", "List", "Description", "Due"})
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		table.SetBorder(false)
+		table.SetCenterSeparator("|")
+		table.SetRowLine(false)
+		table.SetRowSeparator("-")
+		table.SetAutoWrapText(false)
+
+		for _, item := range items {
+			table.Append([]string{
+				truncate(item.Task.Title, 30),
+				truncate(item.ListName, 20),
+				truncate(item.Task.Notes, 30),
+				formatDueHuman(item.Task.Due),
+			})
+		}
+		table.Render()
+	}
+
+	if empty {
+		utils.Info("Nothing pending\n")
+	}
+}
+
+func outputAgendaJSON(groups map[string][]listTask) {
+	output := []AgendaOutput{}
+	for _, name := range agendaGroups {
+		for _, item := range groups[name] {
+			output = append(output, AgendaOutput{
+				Group:       name,
+				List:        item.ListName,
+				ID:          item.Task.Id,
+				Title:       item.Task.Title,
+				Description: item.Task.Notes,
+				Due:         formatDueISO(item.Task.Due),
+			})
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(output)
+}
+
+func outputAgendaCSV(groups map[string][]listTask) {
+	writer := csv.NewWriter(os.Stdout)
+	defer writer.Flush()
+
+	_ = writer.Write([]string{"Group", "List", "Title", "Description", "Due", "ID"})
+	for _, name := range agendaGroups {
+		for _, item := range groups[name] {
+			_ = writer.Write([]string{
+				name,
+				item.ListName,
+				item.Task.Title,
+				item.Task.Notes,
+				formatDueHuman(item.Task.Due),
+				item.Task.Id,
+			})
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/BRO3886/gtasks/api"
+	"github.com/BRO3886/gtasks/internal/backup"
+	"github.com/BRO3886/gtasks/internal/utils"
+	"github.com/spf13/cobra"
+	"google.golang.org/api/tasks/v1"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Save every tasklist and task to a JSON archive",
+	Long: `
+	Use this command to save all tasklists of the signed in account
+	to a JSON archive that 'gtasks restore' can read back. Every
+	field the API returns is kept: IDs, parents, positions, links,
+	completion times, and hidden and deleted tasks.
+
+	The archive is written to gtasks-backup-<date>-<time>.json in
+	the current directory unless -o gives another file, or "-" for
+	standard output.
+	`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
+		if err != nil {
+			utils.ErrorP("Failed to get service: %v\n", err)
+			return
+		}
+		lists, err := api.GetTaskLists(ctx, backend)
+		if err != nil {
+			utils.ErrorP("Error %v\n", err)
+		}
+
+		archive := backup.New()
+		count := 0
+		for _, tl := range lists {
+			items, err := api.GetAllTasks(ctx, backend, tl.Id)
+			if err != nil {
+				utils.ErrorP("Unable to back up %s: %v\n", tl.Title, err)
+			}
+			archive.Lists = append(archive.Lists, backup.List{ID: tl.Id, Title: tl.Title, Updated: tl.Updated, Tasks: items})
+			count += len(items)
+		}
+		warnIfStale()
+
+		out := backupFlags.output
+		if out == "" {
+			out = "gtasks-backup-" + time.Now().Format("20060102-150405") + ".json"
+		}
+		if out == "-" {
+			if err := backup.Write(os.Stdout, archive); err != nil {
+				utils.ErrorP("Unable to write backup: %v\n", err)
+			}
+			return
+		}
+		if err := writeBackupFile(out, archive); err != nil {
+			utils.ErrorP("Unable to write backup: %v\n", err)
+		}
+		utils.Info("Backed up %d tasklists and %s to %s\n", len(lists), pluralTasks(count), out)
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <archive|->",
+	Short: "Recreate tasklists and tasks from a backup archive",
+	Long: `
+	Use this command to recreate the tasklists and tasks saved by
+	'gtasks backup', in this or another account, with subtasks and
+	order preserved. Use "-" to read the archive from standard input.
+
+	Each archived tasklist is restored into the list with the same
+	title, which is created if there is none. Tasks whose title is
+	already in that list at the same level are skipped, so restoring
+	twice does not duplicate them (use --no-dedupe to restore them
+	anyway). Deleted tasks are only restored with --include-deleted,
+	and hidden tasks come back as completed. Task IDs and links are
+	assigned by Google Tasks and cannot be restored.
+
+	Examples:
+	  gtasks restore gtasks-backup-20261018-114500.json --dry-run
+	  gtasks restore backup.json --list Work --list Home
+	`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var in io.Reader = os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				utils.ErrorP("%v\n", err)
+			}
+			defer f.Close()
+			in = f
+		}
+		archive, err := backup.Read(in)
+		if err != nil {
+			utils.ErrorP("Unable to read %s: %v\n", args[0], err)
+		}
+
+		selected := archive.Lists
+		if len(restoreFlags.lists) > 0 {
+			selected = nil
+			for _, title := range restoreFlags.lists {
+				found := false
+				for _, l := range archive.Lists {
+					if l.Title == title {
+						selected = append(selected, l)
+						found = true
+					}
+				}
+				if !found {
+					utils.ErrorP("The backup has no tasklist '%s'\n", title)
+				}
+			}
+		}
+
+		groups := make([]importList, len(selected))
+		for i, l := range selected {
+			groups[i] = importList{title: l.Title, nodes: restoreNodes(l.Tasks, restoreFlags.includeDeleted)}
+		}
+
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
+		if err != nil {
+			utils.ErrorP("Failed to get service: %v\n", err)
+			return
+		}
+		failed := importIntoLists(ctx, backend, groups, restoreFlags.dryRun, !restoreFlags.noDedupe, "restore", "Restored")
+
+		if ctx.Err() != nil {
+			saveCache()
+			utils.ErrorP("Restore stopped (%s)\n", interruptReason(ctx))
+		}
+		if failed > 0 {
+			saveCache()
+			os.Exit(1)
+		}
+	},
+}
+
+var backupFlags struct {
+	output string
+}
+
+var restoreFlags struct {
+	lists          []string
+	dryRun         bool
+	noDedupe       bool
+	includeDeleted bool
+}
+
+func init() {
+	backupCmd.Flags().StringVarP(&backupFlags.output, "output", "o", "", "file to write the archive to, or - for standard output")
+	restoreCmd.Flags().StringArrayVar(&restoreFlags.lists, "list", nil, "only restore this tasklist of the backup (can be repeated)")
+	restoreCmd.Flags().BoolVar(&restoreFlags.dryRun, "dry-run", false, "show what would be restored without changing anything")
+	restoreCmd.Flags().BoolVar(&restoreFlags.noDedupe, "no-dedupe", false, "restore tasks even if the list has one with the same title")
+	restoreCmd.Flags().BoolVar(&restoreFlags.includeDeleted, "include-deleted", false, "also restore tasks that were deleted when the backup was made")
+	rootCmd.AddCommand(backupCmd, restoreCmd)
+}
+
+// writeBackupFile writes the archive to a temporary file next to path and
+// renames it into place, so that an interrupted backup never leaves a
+// truncated archive behind. The archive is only readable by the user.
+func writeBackupFile(path string, archive *backup.Archive) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".gtasks-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := backup.Write(f, archive); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// restoreNodes rebuilds the task tree of an archived list in position order.
+// Tasks whose parent is not restored become top-level tasks. Links cannot be
+// set through the API, so they are appended to the notes.
+func restoreNodes(archived []*tasks.Task, includeDeleted bool) []*importNode {
+	var kept []*tasks.Task
+	for _, t := range archived {
+		if t.Deleted && !includeDeleted {
+			continue
+		}
+		kept = append(kept, t)
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].Position < kept[j].Position
+	})
+
+	nodes := make(map[string]*importNode, len(kept))
+	for _, t := range kept {
+		task := api.CopyableTask(t)
+		if t.Status != "completed" {
+			task.Completed = nil
+		}
+		nodes[t.Id] = &importNode{task: task}
+	}
+	var roots []*importNode
+	for _, t := range kept {
+		if parent, ok := nodes[t.Parent]; ok && t.Parent != "" {
+			parent.children = append(parent.children, nodes[t.Id])
+			continue
+		}
+		roots = append(roots, nodes[t.Id])
+	}
+	return roots
+}
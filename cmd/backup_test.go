@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"testing"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+func TestRestoreNodesKeepsLinks(t *testing.T) {
+	done := "2025-01-05T10:00:00.000Z"
+	archived := []*tasks.Task{
+		{Id: "1", Title: "Reply", Notes: "soon", Position: "1", Status: "needsAction", Completed: &done,
+			Links: []*tasks.TaskLinks{{Type: "email", Link: "https://mail.google.com/x"}}},
+		{Id: "2", Title: "Read", Position: "0", Status: "completed", Completed: &done,
+			Links: []*tasks.TaskLinks{{Link: "https://example.com/a"}, {Link: "https://example.com/b"}}},
+		{Id: "3", Title: "Gone", Position: "2", Deleted: true},
+	}
+	roots := restoreNodes(archived, false)
+	if len(roots) != 2 {
+		t.Fatalf("got %d tasks, want 2", len(roots))
+	}
+	read, reply := roots[0].task, roots[1].task
+	if want := "Links:\n- https://example.com/a\n- https://example.com/b"; read.Notes != want {
+		t.Errorf("Read notes %q, want %q", read.Notes, want)
+	}
+	if read.Completed == nil || *read.Completed != done {
+		t.Errorf("Read lost its completion date")
+	}
+	if want := "soon\n\nLinks:\n- https://mail.google.com/x"; reply.Notes != want {
+		t.Errorf("Reply notes %q, want %q", reply.Notes, want)
+	}
+	if reply.Completed != nil {
+		t.Errorf("Reply is pending but has a completion date")
+	}
+}
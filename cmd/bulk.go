@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BRO3886/gtasks/api"
+	"github.com/BRO3886/gtasks/internal/filter"
+	"github.com/BRO3886/gtasks/internal/utils"
+	"github.com/manifoldco/promptui"
+	"google.golang.org/api/tasks/v1"
+)
+
+// selectTasks returns the tasks chosen on the command line.
+//
+// args may hold any number of task references (see resolveTaskRef) and
+// number ranges such as 5-8. allMatching selects every task whose title or
+// notes contain the given text, and filterExpr every task matching a filter
+// expression; given both, a task must satisfy both. With none of these, the
+// user picks tasks from an interactive multi-select list.
+func selectTasks(args []string, allMatching, filterExpr string, taskList []*tasks.Task, listTitle string) []*tasks.Task {
+	if (allMatching != "" || filterExpr != "") && len(args) > 0 {
+		utils.ErrorP("Use either task references or --all-matching/--filter, not both\n")
+	}
+
+	if allMatching != "" || filterExpr != "" {
+		var expr filter.Expr
+		if allMatching != "" {
+			expr = &filter.Or{
+				Left:  &filter.Compare{Field: "title", Op: "~", Value: allMatching},
+				Right: &filter.Compare{Field: "notes", Op: "~", Value: allMatching},
+			}
+		}
+		if f := parseFilterFlag(filterExpr); f != nil {
+			if expr == nil {
+				expr = f
+			} else {
+				expr = &filter.And{Left: expr, Right: f}
+			}
+		}
+		return filterTasks(taskList, expr)
+	}
+
+	var indices []int
+	if len(args) > 0 {
+		var err error
+		indices, err = parseTaskRefs(args, taskList)
+		if err != nil {
+			utils.ErrorP("%v\n", err)
+		}
+	} else {
+		indices = promptTaskSelection(taskList, listTitle)
+	}
+
+	var selected []*tasks.Task
+	for _, i := range indices {
+		selected = append(selected, taskList[i])
+	}
+	return selected
+}
+
+// parseTaskRefs resolves each argument to task indices, expanding number
+// ranges and dropping duplicates while keeping the order given.
+func parseTaskRefs(args []string, taskList []*tasks.Task) ([]int, error) {
+	seen := make(map[int]bool)
+	var indices []int
+	add := func(i int) {
+		if !seen[i] {
+			seen[i] = true
+			indices = append(indices, i)
+		}
+	}
+
+	for _, arg := range args {
+		if from, to, ok := parseNumberRange(arg); ok {
+			if from > to {
+				return nil, fmt.Errorf("invalid range %q", arg)
+			}
+			if from < 1 || to > len(taskList) {
+				return nil, fmt.Errorf("range %q is outside 1-%d", arg, len(taskList))
+			}
+			for n := from; n <= to; n++ {
+				add(n - 1)
+			}
+			continue
+		}
+
+		i, err := resolveTaskRef(arg, taskList)
+		if err != nil {
+			return nil, err
+		}
+		add(i)
+	}
+	return indices, nil
+}
+
+// parseNumberRange parses "a-b" where both ends are task numbers.
+// Task IDs may contain dashes, so anything else is not treated as a range.
+func parseNumberRange(arg string) (int, int, bool) {
+	lo, hi, found := strings.Cut(arg, "-")
+	if !found {
+		return 0, 0, false
+	}
+	from, err := strconv.Atoi(lo)
+	if err != nil {
+		return 0, 0, false
+	}
+	to, err := strconv.Atoi(hi)
+	if err != nil {
+		return 0, 0, false
+	}
+	return from, to, true
+}
+
+// promptTaskSelection shows a checklist in which Enter toggles a task.
+// Choosing the first entry finishes the selection.
+func promptTaskSelection(taskList []*tasks.Task, listTitle string) []int {
+	utils.Print("Tasks in %s:\n", listTitle)
+
+	subtasks := subtaskSet(taskList)
+	selected := make(map[int]bool)
+	cursor := 0
+
+	for {
+		items := []string{fmt.Sprintf("Done (%d selected)", len(selected))}
+		for i, t := range taskList {
+			mark := "[ ] "
+			if selected[i] {
+				mark = "[x] "
+			}
+			label := t.Title
+			if subtasks[t.Id] {
+				label = subtaskPrefix + label
+			}
+			items = append(items, mark+label)
+		}
+
+		prompt := promptui.Select{
+			Label:        "Select Tasks (Enter to toggle, choose Done to finish)",
+			Items:        items,
+			CursorPos:    cursor,
+			Size:         10,
+			HideSelected: true,
+		}
+		option, _, err := prompt.Run()
+		if err != nil {
+			utils.ErrorP("Error: %s\n", err.Error())
+		}
+		if option == 0 {
+			break
+		}
+
+		if selected[option-1] {
+			delete(selected, option-1)
+		} else {
+			selected[option-1] = true
+		}
+		cursor = option
+	}
+
+	indices := make([]int, 0, len(selected))
+	for i := range selected {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	return indices
+}
+
+// runOnTasks applies op to each task, reporting every outcome under the
+// given success label. With parallel set, several tasks are processed at once
+// (see api.concurrency) and progress is shown on stderr. Once ctx is done the
+// tasks not yet started are skipped. When more than one task is involved a
+// summary is printed. The process exits with status 1 if any operation failed.
+func runOnTasks(ctx context.Context, selected []*tasks.Task, label string, parallel bool, op func(ctx context.Context, t *tasks.Task) error) {
+	if len(selected) == 0 {
+		utils.Warn("No tasks selected\n")
+		return
+	}
+
+	failed := 0
+	p := newProgress("Processed", len(selected))
+	opts := bulkOptions(p, func(r api.BulkResult) {
+		t := selected[r.Index]
+		switch {
+		case interrupted(r.Err):
+			failed++
+			utils.Warn("Skipped: %s (%s)\n", t.Title, interruptReason(ctx))
+		case r.Err != nil:
+			failed++
+			utils.ErrorStyle.Printf("Failed: %s: %v\n", t.Title, r.Err)
+		default:
+			utils.Info("%s: %s\n", label, t.Title)
+		}
+	})
+	if !parallel {
+		opts.Concurrency = 1
+	}
+	api.RunBulk(ctx, len(selected), opts, func(ctx context.Context, i int) (*tasks.Task, error) {
+		return nil, op(ctx, selected[i])
+	})
+	p.clear()
+
+	if len(selected) > 1 {
+		utils.Print("\n%d succeeded, %d failed\n", len(selected)-failed, failed)
+	}
+	if failed > 0 {
+		saveCache()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/BRO3886/gtasks/api"
+	"github.com/BRO3886/gtasks/internal/utils"
+	"google.golang.org/api/tasks/v1"
+)
+
+// How saveTask resolves a task that changed on the server.
+const (
+	conflictAsk       = ""
+	conflictMerge     = "merge"
+	conflictOverwrite = "overwrite"
+	conflictAbort     = "abort"
+)
+
+// conflictField is a task field compared when resolving a conflict.
+type conflictField struct {
+	name string
+	get  func(t *tasks.Task) string
+	set  func(t *tasks.Task, v string)
+	// null is the tasks.Task field name to send as null when cleared.
+	null string
+}
+
+var conflictFields = []conflictField{
+	{"Title", func(t *tasks.Task) string { return t.Title }, func(t *tasks.Task, v string) { t.Title = v }, ""},
+	{"Note", func(t *tasks.Task) string { return t.Notes }, func(t *tasks.Task, v string) { t.Notes = v }, "Notes"},
+	{"Due", func(t *tasks.Task) string { return t.Due }, func(t *tasks.Task, v string) { t.Due = v }, "Due"},
+	{"Status", func(t *tasks.Task) string { return t.Status }, func(t *tasks.Task, v string) { t.Status = v }, ""},
+}
+
+// saveTask saves edited, which was made from base, only if the task has not
+// changed on the server since base was fetched. If it has, the fields that
+// differ are shown and the conflict is resolved as mode says: merge keeps the
+// server's changes to fields that were not edited locally, overwrite saves
+// the local version as is, abort gives up. conflictAsk prompts on reader when
+// stdin is a terminal and aborts otherwise.
+func saveTask(ctx context.Context, backend api.Backend, tID string, base, edited *tasks.Task, mode string, reader *bufio.Reader) error {
+	edited.Etag = base.Etag
+	for {
+		_, err := api.UpdateTask(ctx, backend, edited, tID)
+		if !api.IsConflict(err) {
+			return err
+		}
+
+		remote, err := api.GetTaskInfo(ctx, backend, tID, edited.Id)
+		if err != nil {
+			return fmt.Errorf("task changed on the server and could not be fetched again: %v", err)
+		}
+		utils.Warn("%s was changed on the server since it was loaded:\n", base.Title)
+		printConflict(base, edited, remote)
+
+		choice := mode
+		if choice == conflictAsk {
+			if !isTerminal() {
+				return fmt.Errorf("task changed on the server; use --on-conflict=merge or overwrite to save anyway")
+			}
+			choice = askConflict(reader)
+		}
+
+		switch choice {
+		case conflictMerge:
+			*edited = *mergeConflict(base, edited, remote)
+		case conflictOverwrite:
+			edited.Etag = remote.Etag
+		default:
+			return fmt.Errorf("not saved: task changed on the server")
+		}
+		// Conflicts from here on are against the version just fetched.
+		base = remote
+	}
+}
+
+// printConflict shows each field that differs between the local and the
+// server version, marking which side changed it since base.
+func printConflict(base, local, remote *tasks.Task) {
+	for _, f := range conflictFields {
+		l, r := f.get(local), f.get(remote)
+		if l == r {
+			continue
+		}
+		b := f.get(base)
+		utils.Print("  %s:\n", f.name)
+		utils.Print("    yours:     %s%s\n", conflictValue(f, l), changedMark(l != b))
+		utils.Print("    on server: %s%s\n", conflictValue(f, r), changedMark(r != b))
+	}
+}
+
+func conflictValue(f conflictField, v string) string {
+	if v == "" {
+		return "(empty)"
+	}
+	if f.name == "Due" {
+		return formatDueHuman(v)
+	}
+	return v
+}
+
+func changedMark(changed bool) string {
+	if changed {
+		return utils.WarnStyle.Sprint("  (changed)")
+	}
+	return ""
+}
+
+// askConflict prompts for how to resolve a conflict.
+func askConflict(reader *bufio.Reader) string {
+	for {
+		utils.Print("[m]erge, [o]verwrite or [a]bort? ")
+		switch strings.ToLower(getInput(reader)) {
+		case "m", "merge":
+			return conflictMerge
+		case "o", "overwrite":
+			return conflictOverwrite
+		case "a", "abort", "":
+			return conflictAbort
+		}
+	}
+}
+
+// mergeConflict applies the fields changed locally since base onto remote.
+// Where both sides changed a field, the local value wins.
+func mergeConflict(base, local, remote *tasks.Task) *tasks.Task {
+	merged := *remote
+	merged.NullFields = nil
+	merged.ForceSendFields = nil
+	for _, f := range conflictFields {
+		v := f.get(local)
+		if v == f.get(base) {
+			continue
+		}
+		f.set(&merged, v)
+		if v == "" && f.null != "" {
+			merged.NullFields = append(merged.NullFields, f.null)
+		}
+	}
+	if merged.Status == "needsAction" {
+		merged.Completed = nil
+	}
+	return &merged
+}
+
+// validConflictMode checks an --on-conflict value.
+func validConflictMode(mode string) error {
+	switch mode {
+	case conflictAsk, conflictMerge, conflictOverwrite, conflictAbort:
+		return nil
+	}
+	return fmt.Errorf("invalid --on-conflict value %q: use merge, overwrite or abort", mode)
+}
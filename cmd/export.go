@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/BRO3886/gtasks/api"
+	"github.com/BRO3886/gtasks/internal/ical"
+	"github.com/BRO3886/gtasks/internal/utils"
+	"github.com/spf13/cobra"
+	"google.golang.org/api/tasks/v1"
+)
+
+// exportFormats maps the formats of 'gtasks export' to their writers, which
+// get the tasks of each list in position order.
+var exportFormats = map[string]func(w io.Writer, lists []tasks.TaskList, items [][]*tasks.Task) error{
+	"ics":         exportICS,
+	"taskwarrior": exportTaskwarrior,
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export [format]",
+	Short: "Export tasks to iCalendar or Taskwarrior",
+	Long: `
+	Use this command to write the tasks of every tasklist, or of the
+	one given with -l, in a format other tools can read. Completed
+	and hidden tasks are included. The format is given as argument
+	or with --format:
+
+	  ics          an iCalendar file of VTODOs, one calendar for all
+	               lists; each task has its list as CATEGORIES and
+	               subtasks name their parent in RELATED-TO
+	  taskwarrior  JSON for Taskwarrior's 'task import'; each task
+	               has its list as project, and the lines of its
+	               notes become annotations
+
+	The output goes to standard output unless -o gives a file.
+
+	Examples:
+	  gtasks export ics -o tasks.ics
+	  gtasks export --format ics -l Work
+	  gtasks export --format taskwarrior | task import
+	`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format := exportFlags.format
+		if len(args) == 1 {
+			if format != "" && format != args[0] {
+				utils.ErrorP("Give the format either as argument or with --format, not both\n")
+			}
+			format = args[0]
+		}
+		write, ok := exportFormats[format]
+		switch {
+		case format == "":
+			utils.ErrorP("Give the format to export: %s\n", strings.Join(exportFormatNames(), ", "))
+		case !ok:
+			utils.ErrorP("Unknown format %q (use %s)\n", format, strings.Join(exportFormatNames(), ", "))
+		}
+
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
+		if err != nil {
+			utils.ErrorP("Failed to get service: %v\n", err)
+			return
+		}
+		var lists []tasks.TaskList
+		if taskListFlag != "" {
+			lists = []tasks.TaskList{findTaskList(ctx, backend, taskListFlag)}
+		} else if lists, err = api.GetTaskLists(ctx, backend); err != nil {
+			utils.ErrorP("Error %v\n", err)
+		}
+
+		items := make([][]*tasks.Task, len(lists))
+		count := 0
+		for i, tl := range lists {
+			taskItems, err := api.GetTasks(ctx, backend, tl.Id, true, 0)
+			if err != nil && !errors.Is(err, api.ErrNoTasks) {
+				utils.ErrorP("Unable to export %s: %v\n", tl.Title, err)
+			}
+			utils.Sort(taskItems, "position")
+			items[i] = taskItems
+			count += len(taskItems)
+		}
+		warnIfStale()
+
+		if exportFlags.output == "" || exportFlags.output == "-" {
+			if err := write(os.Stdout, lists, items); err != nil {
+				utils.ErrorP("%v\n", err)
+			}
+			return
+		}
+		f, err := os.Create(exportFlags.output)
+		if err != nil {
+			utils.ErrorP("%v\n", err)
+		}
+		err = write(f, lists, items)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			utils.ErrorP("Unable to write %s: %v\n", exportFlags.output, err)
+		}
+		utils.Info("Exported %s from %d tasklists to %s\n", pluralTasks(count), len(lists), exportFlags.output)
+	},
+}
+
+var exportFlags struct {
+	format string
+	output string
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFlags.format, "format", "", "output format: "+strings.Join(exportFormatNames(), ", "))
+	exportCmd.Flags().StringVarP(&exportFlags.output, "output", "o", "", "file to write to (default: standard output)")
+	exportCmd.Flags().StringVarP(&taskListFlag, "tasklist", "l", "", "only export this tasklist")
+	rootCmd.AddCommand(exportCmd)
+}
+
+func exportFormatNames() []string {
+	names := make([]string, 0, len(exportFormats))
+	for name := range exportFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// exportICS writes the tasks of all lists as one calendar, named after the
+// list if there is only one.
+func exportICS(w io.Writer, lists []tasks.TaskList, items [][]*tasks.Task) error {
+	cal := &ical.Calendar{Name: "Google Tasks"}
+	if len(lists) == 1 {
+		cal.Name = lists[0].Title
+	}
+	for i, tl := range lists {
+		cal.Todos = append(cal.Todos, icsTodos(items[i], tl.Title)...)
+	}
+	return ical.Write(w, cal)
+}
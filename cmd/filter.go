@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/BRO3886/gtasks/internal/filter"
+	"github.com/BRO3886/gtasks/internal/utils"
+	"google.golang.org/api/tasks/v1"
+)
+
+// filterFlagUsage is the help text shared by every --filter flag.
+const filterFlagUsage = `only include tasks matching this expression, e.g. 'due<=+7d and title~"invoice"'`
+
+// parseFilterFlag parses a --filter value, exiting on syntax errors.
+// It returns nil when no filter was given.
+func parseFilterFlag(expr string) filter.Expr {
+	if expr == "" {
+		return nil
+	}
+	f, err := filter.Parse(expr)
+	if err != nil {
+		utils.ErrorP("Invalid filter: %v\n", err)
+	}
+	return f
+}
+
+// filterTasks returns the tasks matched by f, or all of them if f is nil.
+func filterTasks(taskList []*tasks.Task, f filter.Expr) []*tasks.Task {
+	if f == nil {
+		return taskList
+	}
+	var matched []*tasks.Task
+	for _, t := range taskList {
+		if f.Match(t) {
+			matched = append(matched, t)
+		}
+	}
+	return matched
+}
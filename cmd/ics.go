@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/BRO3886/gtasks/internal/ical"
+	"github.com/BRO3886/gtasks/internal/utils"
+	"google.golang.org/api/tasks/v1"
+)
+
+// icsTodos converts the tasks of a list into VTODOs. Subtasks name their
+// parent in RELATED-TO, and the pending occurrence of a rolling series
+// carries the series' rule in RRULE, starting on its due date. Occurrences
+// created ahead of time are tasks of their own and are written without a
+// rule, so that calendar apps do not show them twice.
+func icsTodos(taskList []*tasks.Task, listTitle string) []ical.Todo {
+	todos := make([]ical.Todo, 0, len(taskList))
+	for _, t := range taskList {
+		todo := ical.Todo{
+			UID:         t.Id,
+			Summary:     t.Title,
+			Description: stripSeriesMarker(t.Notes),
+			Status:      ical.NeedsAction,
+			RelatedTo:   t.Parent,
+			Categories:  []string{listTitle},
+		}
+		if due, ok := dueDate(t.Due, time.UTC); ok {
+			todo.Due = due
+		}
+		if t.Status == "completed" {
+			todo.Status = ical.Completed
+			if t.Completed != nil {
+				if completed, err := time.Parse(time.RFC3339, *t.Completed); err == nil {
+					todo.Completed = completed
+				}
+			}
+		}
+		if updated, err := time.Parse(time.RFC3339, t.Updated); err == nil {
+			todo.Modified = updated
+		}
+		if !todo.Due.IsZero() {
+			todo.RRule = icsRule(t)
+			if todo.RRule != "" {
+				todo.Start = todo.Due
+			}
+		}
+		todos = append(todos, todo)
+	}
+	return todos
+}
+
+// icsEvents converts the tasks of a list that have a due date into all-day
+// events on that date, for calendar apps that do not show VTODOs.
+func icsEvents(taskList []*tasks.Task, listTitle string) []ical.Event {
+	var events []ical.Event
+	for _, t := range taskList {
+		due, ok := dueDate(t.Due, time.UTC)
+		if !ok {
+			continue
+		}
+		ev := ical.Event{
+			UID:         t.Id,
+			Summary:     t.Title,
+			Description: stripSeriesMarker(t.Notes),
+			Date:        due,
+			RRule:       icsRule(t),
+			Categories:  []string{listTitle},
+		}
+		if t.Status == "completed" {
+			ev.Summary = "✓ " + ev.Summary
+		}
+		if updated, err := time.Parse(time.RFC3339, t.Updated); err == nil {
+			ev.Modified = updated
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+// icsRule returns the RRULE of a task: the rule of its series if it is the
+// pending occurrence of a rolling series, else "".
+func icsRule(t *tasks.Task) string {
+	if _, rule := seriesOf(t); rule != "" && rollingSeries(t) {
+		return rule
+	}
+	return ""
+}
+
+// outputICS writes tasks as an iCalendar file of VTODOs to standard output.
+func outputICS(taskList []*tasks.Task, listTitle string) {
+	cal := &ical.Calendar{Name: listTitle, Todos: icsTodos(taskList, listTitle)}
+	if err := ical.Write(os.Stdout, cal); err != nil {
+		utils.ErrorP("%v\n", err)
+	}
+}
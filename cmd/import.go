@@ -0,0 +1,496 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BRO3886/gtasks/api"
+	"github.com/BRO3886/gtasks/internal/importer"
+	"github.com/BRO3886/gtasks/internal/rrule"
+	"github.com/BRO3886/gtasks/internal/utils"
+	"github.com/spf13/cobra"
+	"google.golang.org/api/tasks/v1"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import [format] <file|->",
+	Short: "Import tasks from CSV, JSON, iCalendar, Taskwarrior, todo.txt or Markdown",
+	Long: `
+	Use this command to create tasks in a tasklist from a file, or
+	from standard input with "-". The format is taken from the file
+	extension (.csv, .json, .ics, .txt for todo.txt, .md), or given
+	before the file or with --from.
+
+	  csv       a header row naming the columns: title, notes, due,
+	            status and parent (a row's No, ID or title)
+	  json      the output of 'gtasks tasks view --format json'
+	  ics       VTODOs of an iCalendar file; RELATED-TO makes
+	            subtasks, and a task with an RRULE becomes a rolling
+	            series
+	  taskwarrior
+	            the output of 'task export'; each task goes into the
+	            tasklist named by its project, which is created if
+	            needed, and tags and annotations go into the notes
+	  todotxt   one task per line; "x" marks it done, due:DATE sets
+	            the due date
+	  markdown  "- [ ]" and "- [x]" items; indented items become
+	            subtasks
+
+	Google Tasks has one level of subtasks, so deeper items are added
+	under their top-level task. Tasks whose title is already in the
+	list are skipped (use --no-dedupe to create them anyway); the
+	subtasks of such a task are added to the existing one.
+
+	Use --dry-run to see what would be created. Tasks are created one
+	at a time so that they keep the order of the file, at the top of
+	the list.
+
+	Examples:
+	  gtasks import -l Work tasks.csv --dry-run
+	  gtasks import -l Groceries list.md
+	  gtasks import ics -l Work tasks-from-thunderbird.ics
+	  task export | gtasks import taskwarrior -l Inbox -
+	  cat todo.txt | gtasks import --from todotxt -l Inbox -
+	`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := args[len(args)-1]
+		format := importFlags.from
+		if len(args) == 2 {
+			if format != "" && format != args[0] {
+				utils.ErrorP("Give the format either before the file or with --from, not both\n")
+			}
+			format = args[0]
+		}
+		if format == "" {
+			var ok bool
+			if format, ok = importer.FormatOf(path); !ok || path == "-" {
+				utils.ErrorP("Use --from to give the format of %s: %s\n", path, strings.Join(importer.Formats(), ", "))
+			}
+		}
+
+		var in io.Reader = os.Stdin
+		if path != "-" {
+			f, err := os.Open(path)
+			if err != nil {
+				utils.ErrorP("%v\n", err)
+			}
+			defer f.Close()
+			in = f
+		}
+		items, err := importer.Parse(format, in)
+		if err != nil {
+			utils.ErrorP("Unable to read %s: %v\n", path, err)
+		}
+		if len(items) == 0 {
+			utils.Warn("No tasks found in %s\n", path)
+			return
+		}
+
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
+		if err != nil {
+			utils.ErrorP("Failed to get service: %v\n", err)
+			return
+		}
+		// Items that do not name a list go into the one given with -l.
+		var tList *tasks.TaskList
+		var groups []importList
+		listed := make(map[string]int)
+		for _, it := range items {
+			title := it.List
+			var list *tasks.TaskList
+			if title == "" {
+				if tList == nil {
+					tl := getTaskLists(ctx, backend)
+					tList = &tl
+				}
+				title, list = tList.Title, tList
+			}
+			i, ok := listed[title]
+			if !ok {
+				i = len(groups)
+				listed[title] = i
+				groups = append(groups, importList{title: title})
+			}
+			if list != nil {
+				groups[i].list = list
+			}
+			groups[i].nodes = append(groups[i].nodes, importNodes([]*importer.Item{it})...)
+		}
+
+		failed := importIntoLists(ctx, backend, groups, importFlags.dryRun, !importFlags.noDedupe, "import", "Imported")
+		if ctx.Err() != nil {
+			saveCache()
+			utils.ErrorP("Import stopped (%s)\n", interruptReason(ctx))
+		}
+		if failed > 0 {
+			saveCache()
+			os.Exit(1)
+		}
+	},
+}
+
+var importFlags struct {
+	from     string
+	dryRun   bool
+	noDedupe bool
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importFlags.from, "from", "", "input format: "+strings.Join(importer.Formats(), ", ")+" (default: from the file extension)")
+	importCmd.Flags().BoolVar(&importFlags.dryRun, "dry-run", false, "show the tasks that would be created without creating them")
+	importCmd.Flags().BoolVar(&importFlags.noDedupe, "no-dedupe", false, "create tasks even if the list has one with the same title")
+	importCmd.Flags().StringVarP(&taskListFlag, "tasklist", "l", "", "tasklist to import into (for taskwarrior, the tasks without a project)")
+	rootCmd.AddCommand(importCmd)
+}
+
+// importList is what to import into one tasklist.
+type importList struct {
+	title string
+	// list is the tasklist to import into; if nil, it is the first one
+	// titled title, which is created if there is none.
+	list  *tasks.TaskList
+	nodes []*importNode
+}
+
+// importIntoLists imports each group into its tasklist, one after another,
+// or shows what would be imported with dryRun. action and label are passed
+// on to printImportPlan and runImport. It returns the number of tasks that
+// were not created; once ctx is done the remaining lists are skipped.
+func importIntoLists(ctx context.Context, backend api.Backend, groups []importList, dryRun, dedupe bool, action, label string) int {
+	var byTitle map[string]tasks.TaskList
+	failed := 0
+	for i, g := range groups {
+		if ctx.Err() != nil {
+			break
+		}
+		if i > 0 {
+			utils.Print("\n")
+		}
+
+		var tl tasks.TaskList
+		exists := g.list != nil
+		if exists {
+			tl = *g.list
+		} else {
+			if byTitle == nil {
+				current, err := api.GetTaskLists(ctx, backend)
+				if err != nil {
+					utils.ErrorP("Error %v\n", err)
+				}
+				byTitle = make(map[string]tasks.TaskList)
+				for _, tl := range current {
+					if _, ok := byTitle[tl.Title]; !ok {
+						byTitle[tl.Title] = tl
+					}
+				}
+			}
+			tl, exists = byTitle[g.title]
+		}
+
+		var existing []*tasks.Task
+		if exists {
+			var err error
+			existing, err = api.GetTasks(ctx, backend, tl.Id, true, 0)
+			if err != nil && !errors.Is(err, api.ErrNoTasks) {
+				utils.ErrorStyle.Printf("%s: %v\n", g.title, err)
+				failed++
+				continue
+			}
+			utils.Sort(existing, "position")
+		}
+		steps := planImport(g.nodes, existing, dedupe)
+
+		if dryRun {
+			if !exists {
+				utils.Warn("Would create tasklist %s\n", g.title)
+			}
+			printImportPlan(steps, g.title, action)
+			continue
+		}
+		if !exists {
+			created, err := api.CreateTaskList(ctx, backend, g.title)
+			if err != nil {
+				utils.ErrorStyle.Printf("Unable to create tasklist %s: %v\n", g.title, err)
+				failed += len(steps)
+				continue
+			}
+			tl = *created
+			byTitle[g.title] = tl
+			utils.Info("Created tasklist %s\n", g.title)
+		}
+		failed += runImport(ctx, backend, tl, steps, label)
+	}
+	return failed
+}
+
+// importNode is a task to create together with its subtasks.
+type importNode struct {
+	task     *tasks.Task
+	children []*importNode
+}
+
+// importNodes converts parsed items into the tasks to create.
+func importNodes(items []*importer.Item) []*importNode {
+	var nodes []*importNode
+	for _, it := range items {
+		task := &tasks.Task{Title: it.Title, Notes: it.Notes}
+		if !it.Due.IsZero() {
+			task.Due = it.Due.Format(time.RFC3339)
+		}
+		if it.Completed {
+			task.Status = "completed"
+			if !it.CompletedAt.IsZero() {
+				completed := it.CompletedAt.UTC().Format(time.RFC3339)
+				task.Completed = &completed
+			}
+		}
+		if it.Rule != "" {
+			marker, err := importedSeriesMarker(it)
+			if err != nil {
+				utils.WarnStyle.Fprintf(os.Stderr, "Line %d: %s will not repeat: %v\n", it.Line, it.Title, err)
+			} else {
+				task.Notes = withSeriesMarker(task.Notes, marker)
+			}
+		}
+		nodes = append(nodes, &importNode{task: task, children: importNodes(it.Children)})
+	}
+	return nodes
+}
+
+// importedSeriesMarker returns the marker that makes an imported task with a
+// recurrence rule a rolling series, so that its next occurrence is created
+// when it is completed. A COUNT in the rule is turned into the UNTIL date of
+// its last occurrence, as rolling series cannot count. A task that is already
+// completed is tagged without the rolling flag, so that it is not rolled.
+func importedSeriesMarker(it *importer.Item) (string, error) {
+	rule, err := rrule.Parse(it.Rule)
+	if err != nil {
+		return "", err
+	}
+	if it.Due.IsZero() {
+		return "", fmt.Errorf("it has no due date")
+	}
+	if rule.Count > 0 {
+		dates := rule.Expand(it.Due)
+		if len(dates) == 0 {
+			return "", fmt.Errorf("its rule has no occurrences")
+		}
+		rule.Count = 0
+		rule.Until = dates[len(dates)-1]
+	}
+	return rollingMarker(newSeriesID(), rule.String(), !it.Completed), nil
+}
+
+// importStep is one task of an import. Subtasks of subtasks are flattened
+// onto their top-level task.
+type importStep struct {
+	task   *tasks.Task
+	parent *importStep
+	// existing is the task with the same title already in the list; no task
+	// is created for the step then.
+	existing *tasks.Task
+	// created is the task made for the step.
+	created *tasks.Task
+	// after is the ID of the existing subtask to place the first new
+	// subtask of this step after.
+	after string
+}
+
+// id returns the ID of the step's task, if it has one.
+func (s *importStep) id() string {
+	switch {
+	case s.existing != nil:
+		return s.existing.Id
+	case s.created != nil:
+		return s.created.Id
+	}
+	return ""
+}
+
+// planImport lists the steps of creating nodes in a list holding existing,
+// in position order. With dedupe set, a node is matched to an existing task
+// with the same title, case aside, at the same level.
+func planImport(nodes []*importNode, existing []*tasks.Task, dedupe bool) []*importStep {
+	topLevel := make(map[string]*tasks.Task)
+	children := make(map[string][]*tasks.Task)
+	for _, t := range existing {
+		if t.Parent == "" {
+			if _, ok := topLevel[titleKey(t.Title)]; !ok {
+				topLevel[titleKey(t.Title)] = t
+			}
+		} else {
+			children[t.Parent] = append(children[t.Parent], t)
+		}
+	}
+
+	var steps []*importStep
+	var addChildren func(parent *importStep, nodes []*importNode)
+	addChildren = func(parent *importStep, nodes []*importNode) {
+		for _, n := range nodes {
+			s := &importStep{task: n.task, parent: parent}
+			if dedupe && parent.existing != nil {
+				for _, c := range children[parent.existing.Id] {
+					if titleKey(c.Title) == titleKey(n.task.Title) {
+						s.existing = c
+						break
+					}
+				}
+			}
+			steps = append(steps, s)
+			addChildren(parent, n.children)
+		}
+	}
+	for _, n := range nodes {
+		s := &importStep{task: n.task}
+		if dedupe {
+			s.existing = topLevel[titleKey(n.task.Title)]
+		}
+		if s.existing != nil {
+			if c := children[s.existing.Id]; len(c) > 0 {
+				s.after = c[len(c)-1].Id
+			}
+		}
+		steps = append(steps, s)
+		addChildren(s, n.children)
+	}
+	return steps
+}
+
+func pluralTasks(n int) string {
+	if n == 1 {
+		return "1 task"
+	}
+	return fmt.Sprintf("%d tasks", n)
+}
+
+func titleKey(title string) string {
+	return strings.ToLower(strings.TrimSpace(title))
+}
+
+// printImportPlan shows the steps of importing into a list; action is
+// "import" or "restore".
+func printImportPlan(steps []*importStep, listTitle, action string) {
+	utils.Print("Would %s into %s:\n", action, listTitle)
+	create := 0
+	for _, s := range steps {
+		indent := "  "
+		if s.parent != nil {
+			indent = "    "
+		}
+		if s.existing != nil {
+			utils.Print("%s= %s (already in %s)\n", indent, s.task.Title, listTitle)
+			continue
+		}
+		create++
+		var details []string
+		if s.task.Due != "" {
+			details = append(details, "due "+formatDueHuman(s.task.Due))
+		}
+		if s.task.Status == "completed" {
+			details = append(details, "completed")
+		}
+		if _, rule := seriesOf(s.task); rule != "" {
+			details = append(details, "repeats "+rule)
+		}
+		line := indent + "+ " + s.task.Title
+		if len(details) > 0 {
+			line += " (" + strings.Join(details, ", ") + ")"
+		}
+		utils.Print("%s\n", line)
+	}
+	utils.Print("\n")
+	utils.Info("%d to create, %d already in %s\n", create, len(steps)-create, listTitle)
+}
+
+// runImport creates the tasks of steps so that the list shows them as in
+// the input: new top-level tasks first in the list, and new subtasks after
+// their parent's existing ones. The tasks are created several at a time
+// (see api.BulkInsert), top-level tasks before subtasks, which need their
+// parent's ID. A subtask whose parent could not be created is skipped. Once
+// ctx is done no further tasks are created. label, e.g. "Imported", starts
+// the summary. It returns the number of tasks that were not created.
+func runImport(ctx context.Context, backend api.Backend, tList tasks.TaskList, steps []*importStep, label string) int {
+	var top, sub []*importStep
+	for _, s := range steps {
+		switch {
+		case s.existing != nil:
+		case s.parent == nil:
+			top = append(top, s)
+		default:
+			sub = append(sub, s)
+		}
+	}
+	toCreate := len(top) + len(sub)
+	if toCreate == 0 {
+		utils.Info("%s nothing into %s; all %s are already there\n", label, tList.Title, pluralTasks(len(steps)))
+		return 0
+	}
+
+	p := newProgress(label, toCreate)
+	created, failed := 0, 0
+	create := func(level []*importStep) {
+		items := make([]api.NewTask, len(level))
+		for i, s := range level {
+			items[i] = api.NewTask{Task: s.task}
+			if s.parent != nil {
+				items[i].Parent, items[i].Previous = s.parent.id(), s.parent.after
+			}
+		}
+		_, err := api.BulkInsert(ctx, backend, tList.Id, items, bulkOptions(p, func(r api.BulkResult) {
+			s := level[r.Index]
+			switch {
+			case r.Err == nil:
+				created++
+				s.created = r.Task
+			case interrupted(r.Err):
+				failed++
+			default:
+				failed++
+				utils.ErrorStyle.Printf("Failed: %s: %v\n", s.task.Title, r.Err)
+			}
+		}))
+		if err != nil && ctx.Err() == nil {
+			p.clear()
+			utils.Warn("%s: %v\n", tList.Title, err)
+		}
+	}
+
+	create(top)
+	var ready []*importStep
+	for _, s := range sub {
+		if s.parent.id() == "" {
+			failed++
+			p.clear()
+			if ctx.Err() == nil {
+				utils.ErrorStyle.Printf("Skipped: %s (its parent was not created)\n", s.task.Title)
+			}
+			p.step()
+			continue
+		}
+		ready = append(ready, s)
+	}
+	create(ready)
+	p.clear()
+
+	skipped := len(steps) - toCreate
+	msg := fmt.Sprintf("%s %s into %s", label, pluralTasks(created), tList.Title)
+	if skipped > 0 {
+		msg += fmt.Sprintf(" (%d already there)", skipped)
+	}
+	switch {
+	case failed == 0:
+		utils.Info("%s\n", msg)
+	case ctx.Err() != nil:
+		utils.Warn("%s; stopped (%s), %d not created\n", msg, interruptReason(ctx), failed)
+	default:
+		utils.Warn("%s; %d failed\n", msg, failed)
+	}
+	return failed
+}
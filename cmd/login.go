@@ -19,7 +19,7 @@ This command will:
 
 If the browser doesn't open automatically, you'll be provided with a URL to visit manually.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		err := api.Login()
+		err := api.Login(cmd.Context())
 		if err != nil {
 			utils.ErrorP("Login failed: %v\n", err)
 			return
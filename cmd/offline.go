@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/BRO3886/gtasks/api"
+	"github.com/BRO3886/gtasks/internal/cache"
+	"github.com/BRO3886/gtasks/internal/utils"
+)
+
+// offlineFlag is set by the global --offline flag.
+var offlineFlag bool
+
+// cachedBackend is the backend opened by openBackend, saved after the command runs.
+var cachedBackend *api.CachedBackend
+
+// openBackend returns a backend that keeps the local cache up to date and
+// falls back to it when Google Tasks cannot be reached. With --offline it
+// only reads the cache and never needs credentials.
+func openBackend(ctx context.Context) (api.Backend, error) {
+	if cachedBackend != nil {
+		return cachedBackend, nil
+	}
+
+	store, err := cache.Open(cache.Path())
+	if err != nil {
+		utils.WarnStyle.Fprintf(os.Stderr, "Ignoring local cache: %v\n", err)
+	}
+	journal, err := cache.OpenJournal(cache.JournalPath())
+	if err != nil {
+		// Never overwrite a journal that could not be read: it holds changes
+		// that exist nowhere else.
+		utils.WarnStyle.Fprintf(os.Stderr, "Changes cannot be made offline: %v\n", err)
+		journal = nil
+	}
+
+	var remote api.Backend
+	if !offlineFlag {
+		remote, err = api.NewBackend(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	cachedBackend = api.NewCachedBackend(remote, store, journal)
+	return cachedBackend, nil
+}
+
+// saveCache writes the local cache back to disk if a command used it, and
+// tells the user about changes waiting to be pushed.
+func saveCache() {
+	if cachedBackend == nil {
+		return
+	}
+	if err := cachedBackend.Save(); err != nil {
+		utils.WarnStyle.Fprintf(os.Stderr, "Unable to save local cache: %v\n", err)
+	}
+	if n := cachedBackend.Queued(); n > 0 {
+		utils.WarnStyle.Fprintf(os.Stderr, "Saved %s offline; run 'gtasks sync push' when back online\n", pluralChanges(n))
+	} else if n := len(cachedBackend.Pending()); n > 0 && !cachedBackend.Offline() && !pushing {
+		utils.WarnStyle.Fprintf(os.Stderr, "%s made offline waiting; run 'gtasks sync push' to send them\n", pluralChanges(n))
+	}
+}
+
+func pluralChanges(n int) string {
+	if n == 1 {
+		return "1 change"
+	}
+	return fmt.Sprintf("%d changes", n)
+}
+
+// warnIfStale tells the user on stderr when results come from the local
+// cache rather than from Google Tasks.
+func warnIfStale() {
+	if cachedBackend == nil {
+		return
+	}
+	stale, fetchedAt, err := cachedBackend.Stale()
+	if !stale {
+		return
+	}
+	when := fetchedAt.Local().Format("02 Jan 2006 15:04")
+	if err != nil {
+		utils.WarnStyle.Fprintf(os.Stderr, "Network unavailable (%v)\n", err)
+	}
+	utils.WarnStyle.Fprintf(os.Stderr, "Showing cached data from %s; it may be out of date\n", when)
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/BRO3886/gtasks/api"
+	"github.com/BRO3886/gtasks/internal/config"
+)
+
+// progress shows how far a bulk operation has got on a single stderr line.
+// It is only drawn when stderr is a terminal and there is more than one item.
+type progress struct {
+	label   string
+	total   int
+	done    int
+	enabled bool
+}
+
+func newProgress(label string, total int) *progress {
+	p := &progress{label: label, total: total}
+	if fi, err := os.Stderr.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		p.enabled = total > 1
+	}
+	p.draw()
+	return p
+}
+
+func (p *progress) draw() {
+	if p.enabled {
+		fmt.Fprintf(os.Stderr, "\r%s %d/%d", p.label, p.done, p.total)
+	}
+}
+
+// clear removes the progress line so other output can be printed.
+func (p *progress) clear() {
+	if p.enabled {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+}
+
+// step counts one more item as done.
+func (p *progress) step() {
+	p.done++
+	p.draw()
+}
+
+// bulkOptions returns the settings for a bulk operation reporting to p.
+func bulkOptions(p *progress, onResult func(r api.BulkResult)) api.BulkOptions {
+	return api.BulkOptions{
+		Concurrency: config.GetAPIConcurrency(),
+		OnResult: func(r api.BulkResult) {
+			p.clear()
+			if onResult != nil {
+				onResult(r)
+			}
+			p.step()
+		},
+	}
+}
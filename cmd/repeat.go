@@ -2,82 +2,248 @@ package cmd
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/BRO3886/gtasks/internal/rrule"
 )
 
-type repeatUnit int
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+var ordinalNames = map[string]int{
+	"first": 1, "1st": 1,
+	"second": 2, "2nd": 2,
+	"third": 3, "3rd": 3,
+	"fourth": 4, "4th": 4,
+	"fifth": 5, "5th": 5,
+	"last":        -1,
+	"second last": -2, "second to last": -2,
+}
+
+var unitFreqs = map[string]rrule.Freq{
+	"day": rrule.Daily, "days": rrule.Daily,
+	"week": rrule.Weekly, "weeks": rrule.Weekly,
+	"month": rrule.Monthly, "months": rrule.Monthly,
+	"year": rrule.Yearly, "years": rrule.Yearly,
+}
 
-const (
-	repeatNone repeatUnit = iota
-	repeatDaily
-	repeatWeekly
-	repeatMonthly
-	repeatYearly
+var (
+	// every 2 weeks on mon,thu / every other month on the 15th / every day
+	everyPattern = regexp.MustCompile(`^every (?:(other|\d+) )?(days?|weeks?|months?|years?)(?: on (.+))?$`)
+	// last friday of month / first weekday of the month / 2nd day of every month
+	ordinalPattern = regexp.MustCompile(`^(.+?) (day|weekday|weekend day|\w+) of (?:the |every )?month$`)
+	// the 15th / 15 / day 15 / the last day
+	monthDayPattern = regexp.MustCompile(`^(?:the |day )?(\d+)(?:st|nd|rd|th)?$`)
 )
 
-// parseRepeatUnit parses a repeat pattern string into a repeatUnit.
-// Accepts: daily, day, weekly, week, monthly, month, yearly, year, annually
-func parseRepeatUnit(raw string) (repeatUnit, error) {
-	raw = strings.TrimSpace(strings.ToLower(raw))
+// parseRepeat parses the --repeat value into a recurrence rule. Besides
+// daily, weekly, monthly and yearly it accepts forms such as "every 2 weeks
+// on mon,thu", "weekdays", "every monday", "every month on the 15th" and
+// "last friday of month". An empty value means no repetition.
+func parseRepeat(raw string) (*rrule.Rule, error) {
+	raw = strings.Join(strings.Fields(strings.ToLower(raw)), " ")
 	if raw == "" {
-		return repeatNone, nil
+		return nil, nil
 	}
+	r := &rrule.Rule{Interval: 1, WeekStart: time.Monday}
 	switch raw {
-	case "daily", "day":
-		return repeatDaily, nil
-	case "weekly", "week":
-		return repeatWeekly, nil
-	case "monthly", "month":
-		return repeatMonthly, nil
-	case "yearly", "year", "annually":
-		return repeatYearly, nil
+	case "daily", "day", "every day":
+		r.Freq = rrule.Daily
+	case "weekly", "week", "every week":
+		r.Freq = rrule.Weekly
+	case "monthly", "month", "every month":
+		r.Freq = rrule.Monthly
+	case "yearly", "year", "annually", "every year":
+		r.Freq = rrule.Yearly
+	case "fortnightly", "biweekly", "every other week":
+		r.Freq = rrule.Weekly
+		r.Interval = 2
+	case "weekdays", "every weekday":
+		r.Freq = rrule.Weekly
+		r.ByDay = byDays(time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday)
+	case "weekends", "every weekend":
+		r.Freq = rrule.Weekly
+		r.ByDay = byDays(time.Saturday, time.Sunday)
 	default:
-		return repeatNone, fmt.Errorf("invalid repeat value %q (must be daily, weekly, monthly, or yearly)", raw)
+		if err := parseRepeatPhrase(raw, r); err != nil {
+			return nil, err
+		}
 	}
+	return r, nil
 }
 
-// expandRepeatSchedule generates a list of dates based on the repeat pattern.
-// If both count and until are provided, stops at whichever limit is reached first.
-// If neither is provided, returns a single occurrence (the start date).
-func expandRepeatSchedule(start time.Time, unit repeatUnit, count int, until *time.Time) []time.Time {
-	if unit == repeatNone {
-		return []time.Time{start}
+func parseRepeatPhrase(raw string, r *rrule.Rule) error {
+	invalid := fmt.Errorf("invalid repeat value %q (e.g. daily, weekly, monthly, yearly, weekdays, \"every 2 weeks on mon,thu\", \"last friday of month\"; or use --rrule)", raw)
+
+	if m := everyPattern.FindStringSubmatch(raw); m != nil {
+		r.Freq = unitFreqs[m[2]]
+		switch m[1] {
+		case "":
+		case "other":
+			r.Interval = 2
+		default:
+			n, err := strconv.Atoi(m[1])
+			if err != nil || n < 1 {
+				return invalid
+			}
+			r.Interval = n
+		}
+		if m[3] == "" {
+			return nil
+		}
+		switch r.Freq {
+		case rrule.Weekly:
+			days, ok := parseWeekdayList(m[3])
+			if !ok {
+				return invalid
+			}
+			r.ByDay = days
+		case rrule.Monthly:
+			if md, ok := parseMonthDay(m[3]); ok {
+				r.ByMonthDay = []int{md}
+				return nil
+			}
+			// every month on the last friday
+			if err := parseRepeatPhrase(strings.TrimPrefix(m[3], "the ")+" of month", r); err != nil {
+				return invalid
+			}
+		default:
+			return invalid
+		}
+		return nil
 	}
-	if count < 0 {
-		count = 0
+
+	// every mon,thu / every monday and friday
+	if rest, ok := strings.CutPrefix(raw, "every "); ok {
+		if days, ok := parseWeekdayList(rest); ok {
+			r.Freq = rrule.Weekly
+			r.ByDay = days
+			return nil
+		}
 	}
-	// Defensive guard: if neither count nor until is set, return single occurrence
-	if count == 0 && until == nil {
-		return []time.Time{start}
+
+	if m := ordinalPattern.FindStringSubmatch(raw); m != nil {
+		n, ok := ordinalNames[m[1]]
+		if !ok {
+			return invalid
+		}
+		r.Freq = rrule.Monthly
+		switch m[2] {
+		case "day":
+			r.ByMonthDay = []int{n}
+		case "weekday":
+			r.ByDay = byDays(time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday)
+			r.BySetPos = []int{n}
+		case "weekend day":
+			r.ByDay = byDays(time.Saturday, time.Sunday)
+			r.BySetPos = []int{n}
+		default:
+			day, ok := weekdayNames[m[2]]
+			if !ok {
+				return invalid
+			}
+			r.ByDay = []rrule.WeekdayNum{{N: n, Day: day}}
+		}
+		return nil
 	}
+	return invalid
+}
 
-	out := []time.Time{}
-	for i := 0; ; i++ {
-		t := addRepeat(start, unit, i)
-		if until != nil && t.After(*until) {
-			break
+// parseWeekdayList parses weekdays separated by commas, spaces or "and".
+func parseWeekdayList(s string) ([]rrule.WeekdayNum, bool) {
+	s = strings.ReplaceAll(s, " and ", ",")
+	var days []time.Weekday
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		day, ok := weekdayNames[strings.TrimSuffix(f, "s")]
+		if !ok {
+			day, ok = weekdayNames[f]
 		}
-		out = append(out, t)
-		if count > 0 && len(out) >= count {
-			break
+		if !ok {
+			return nil, false
 		}
+		days = append(days, day)
+	}
+	if len(days) == 0 {
+		return nil, false
+	}
+	return byDays(days...), true
+}
+
+// parseMonthDay parses "the 15th", "15" or "the last day".
+func parseMonthDay(s string) (int, bool) {
+	if s == "the last day" || s == "last day" {
+		return -1, true
+	}
+	m := monthDayPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil || n < 1 || n > 31 {
+		return 0, false
+	}
+	return n, true
+}
+
+func byDays(days ...time.Weekday) []rrule.WeekdayNum {
+	out := make([]rrule.WeekdayNum, len(days))
+	for i, d := range days {
+		out[i] = rrule.WeekdayNum{Day: d}
 	}
 	return out
 }
 
-// addRepeat adds n units of the repeat pattern to the given time.
-func addRepeat(t time.Time, unit repeatUnit, n int) time.Time {
-	switch unit {
-	case repeatDaily:
-		return t.AddDate(0, 0, n)
-	case repeatWeekly:
-		return t.AddDate(0, 0, 7*n)
-	case repeatMonthly:
-		return t.AddDate(0, n, 0)
-	case repeatYearly:
-		return t.AddDate(n, 0, 0)
+// clampToMonthEnd makes a plain monthly or yearly rule starting on a day that
+// some months lack fall on the last day of those months instead of skipping
+// them: Jan 31 is followed by Feb 28, Mar 31, Apr 30 and so on, and Feb 29 by
+// Feb 28 in common years. It is applied to --repeat, not to --rrule, which
+// follows RFC 5545 to the letter.
+func clampToMonthEnd(r *rrule.Rule, start time.Time) {
+	if len(r.ByMonthDay) > 0 || len(r.ByDay) > 0 || len(r.BySetPos) > 0 || len(r.ByMonth) > 0 {
+		return
+	}
+	day := start.Day()
+	switch {
+	case r.Freq == rrule.Monthly && day > 28:
+	case r.Freq == rrule.Yearly && start.Month() == time.February && day == 29:
+		r.ByMonth = []int{2}
 	default:
-		return t
+		return
+	}
+	for d := 28; d <= day; d++ {
+		r.ByMonthDay = append(r.ByMonthDay, d)
+	}
+	r.BySetPos = []int{-1}
+}
+
+// expandRepeatSchedule generates the due dates of a recurring task from start.
+// count and until add to the rule's own COUNT and UNTIL; whichever limit is
+// reached first applies. Without any limit it returns a single occurrence
+// (the start date).
+func expandRepeatSchedule(start time.Time, rule *rrule.Rule, count int, until *time.Time) []time.Time {
+	if rule == nil {
+		return []time.Time{start}
+	}
+	r := *rule
+	if count > 0 && (r.Count == 0 || count < r.Count) {
+		r.Count = count
+	}
+	if until != nil && (r.Until.IsZero() || until.Before(r.Until)) {
+		r.Until = *until
+	}
+	// Defensive guard: if neither count nor until is set, return single occurrence
+	if r.Count == 0 && r.Until.IsZero() {
+		return []time.Time{start}
 	}
+	return r.Expand(start)
 }
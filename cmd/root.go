@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/BRO3886/gtasks/internal/config"
@@ -15,6 +19,20 @@ import (
 // Version is set during build
 var Version = "DEV"
 
+// newBackend returns the Tasks backend used by commands.
+// Tests can replace it with a function returning an api.MemoryBackend.
+var newBackend = openBackend
+
+// timeoutFlag is set by the global --timeout flag.
+var timeoutFlag time.Duration
+
+// cancelTimeout releases the deadline set from --timeout.
+var cancelTimeout context.CancelFunc = func() {}
+
+// interruptGrace is how long a command may take to wind down after Ctrl-C
+// before the process exits anyway.
+const interruptGrace = 2 * time.Second
+
 // updateResultCh receives the background update check result (if any).
 var updateResultCh = make(chan *update.Result, 1)
 
@@ -32,6 +50,15 @@ var rootCmd = &cobra.Command{
 	Made with ❤ by https://github.com/BRO3886
 `,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if timeoutFlag < 0 {
+			utils.ErrorP("Invalid --timeout %s\n", timeoutFlag)
+		}
+		if timeoutFlag > 0 {
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeoutFlag)
+			cmd.SetContext(ctx)
+			cancelTimeout = cancel
+		}
+
 		if !shouldCheckForUpdate(cmd) {
 			updateResultCh <- nil
 			return
@@ -59,6 +86,8 @@ var rootCmd = &cobra.Command{
 		}
 	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		cancelTimeout()
+		saveCache()
 		printUpdateNotice()
 	},
 }
@@ -66,13 +95,45 @@ var rootCmd = &cobra.Command{
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go exitAfterInterrupt(ctx, stop)
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		utils.ErrorP("%s\n", err.Error())
 	}
 }
 
+// exitAfterInterrupt waits for Ctrl-C or SIGTERM, which cancel ctx so that
+// requests in flight are abandoned and commands stop early. A second signal
+// kills the process as usual, and so does the first if the command has not
+// finished within interruptGrace, for instance because it is waiting for
+// input.
+func exitAfterInterrupt(ctx context.Context, stop context.CancelFunc) {
+	<-ctx.Done()
+	stop()
+	time.Sleep(interruptGrace)
+	os.Exit(130)
+}
+
+// interrupted reports whether err comes from the command being cancelled
+// with Ctrl-C or running past --timeout.
+func interrupted(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// interruptReason describes why ctx ended.
+func interruptReason(ctx context.Context) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "timed out"
+	}
+	return "interrupted"
+}
+
 func init() {
 	cobra.OnInitialize(initConfig)
+	rootCmd.PersistentFlags().DurationVar(&timeoutFlag, "timeout", 0, "give up if the command takes longer than this, e.g. 30s or 2m (default no limit)")
+	rootCmd.PersistentFlags().BoolVar(&offlineFlag, "offline", false, "work from the local cache without contacting Google Tasks; changes are queued for gtasks sync push")
 }
 
 func initConfig() {
@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/BRO3886/gtasks/api"
+	"github.com/BRO3886/gtasks/internal/utils"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search task titles and notes across all tasklists",
+	Long: `
+	Use this command to find tasks in any tasklist whose title
+	or notes contain every word of the query (case-insensitive).
+	Results are ranked with title matches first.
+	Completed and hidden tasks are skipped unless --include-completed is set.
+	You can control output with --format: table (default), json, csv.
+	`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		query := strings.Join(args, " ")
+		terms := strings.Fields(strings.ToLower(query))
+		if len(terms) == 0 {
+			utils.ErrorP("Search query is empty\n")
+		}
+
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
+		if err != nil {
+			utils.ErrorP("Failed to get service: %v\n", err)
+			return
+		}
+		lists, err := api.GetTaskLists(ctx, backend)
+		if err != nil {
+			utils.ErrorP("Error %v\n", err)
+			return
+		}
+
+		results := searchTasks(fetchAllTasks(ctx, backend, lists, searchFlags.includeCompleted), query, terms)
+		if searchFlags.max > 0 && len(results) > searchFlags.max {
+			results = results[:searchFlags.max]
+		}
+		warnIfStale()
+
+		switch searchFlags.format {
+		case "json":
+			outputSearchJSON(results)
+		case "csv":
+			outputSearchCSV(results)
+		default:
+			outputSearchTable(results, terms)
+		}
+	},
+}
+
+var searchFlags struct {
+	includeCompleted bool
+	format           string
+	max              int
+}
+
+func init() {
+	searchCmd.Flags().BoolVarP(&searchFlags.includeCompleted, "include-completed", "i", false, "also search completed and hidden tasks")
+	searchCmd.Flags().StringVar(&searchFlags.format, "format", "table", "output format: table, json, csv")
+	searchCmd.Flags().IntVar(&searchFlags.max, "max", 0, "maximum number of results to show (0 = all)")
+	rootCmd.AddCommand(searchCmd)
+}
+
+type searchResult struct {
+	listTask
+	Score int
+}
+
+// searchTasks returns the items whose title or notes contain every term,
+// best matches first.
+func searchTasks(items []listTask, query string, terms []string) []searchResult {
+	phrase := strings.ToLower(strings.TrimSpace(query))
+
+	var results []searchResult
+	for _, item := range items {
+		if score, ok := scoreTask(item, phrase, terms); ok {
+			results = append(results, searchResult{listTask: item, Score: score})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return strings.ToLower(results[i].Task.Title) < strings.ToLower(results[j].Task.Title)
+	})
+	return results
+}
+
+// scoreTask ranks an item against the query. An exact or phrase match in the
+// title ranks highest, then terms matched in the title at the start of a
+// word, then anywhere in the title, then in the notes. Pending tasks rank
+// above completed ones with the same score.
+func scoreTask(item listTask, phrase string, terms []string) (int, bool) {
+	title := strings.ToLower(item.Task.Title)
+	notes := strings.ToLower(item.Task.Notes)
+
+	score := 0
+	for _, term := range terms {
+		inTitle := strings.Contains(title, term)
+		inNotes := strings.Contains(notes, term)
+		if !inTitle && !inNotes {
+			return 0, false
+		}
+		if inTitle {
+			score += 10
+			if hasWordPrefix(title, term) {
+				score += 5
+			}
+		}
+		if inNotes {
+			score += 3
+		}
+	}
+
+	switch {
+	case title == phrase:
+		score += 100
+	case strings.HasPrefix(title, phrase):
+		score += 60
+	case strings.Contains(title, phrase):
+		score += 40
+	case strings.Contains(notes, phrase):
+		score += 10
+	}
+	if item.Task.Status != "completed" {
+		score++
+	}
+	return score, true
+}
+
+// hasWordPrefix reports whether term occurs in s at the start of a word.
+func hasWordPrefix(s, term string) bool {
+	for i := 0; ; {
+		j := strings.Index(s[i:], term)
+		if j < 0 {
+			return false
+		}
+		at := i + j
+		if at == 0 || !isWordByte(s[at-1]) {
+			return true
+		}
+		i = at + 1
+	}
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// highlight wraps every case-insensitive occurrence of the terms in s with
+// WarnStyle. Overlapping matches are merged.
+func highlight(s string, terms []string) string {
+	lower := strings.ToLower(s)
+	if len(lower) != len(s) {
+		// Case folding changed byte offsets; leave the text as is.
+		return s
+	}
+	marked := make([]bool, len(s))
+	for _, term := range terms {
+		for i := 0; i < len(lower); {
+			j := strings.Index(lower[i:], term)
+			if j < 0 {
+				break
+			}
+			for k := i + j; k < i+j+len(term); k++ {
+				marked[k] = true
+			}
+			i += j + 1
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		j := i
+		for j < len(s) && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			b.WriteString(utils.WarnStyle.Sprint(s[i:j]))
+		} else {
+			b.WriteString(s[i:j])
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// snippet returns up to maxLen bytes of s around the first matching term,
+// so that matches deep inside long notes remain visible. Cuts are made at
+// rune boundaries, so the snippet may come out a few bytes shorter.
+func snippet(s string, terms []string, maxLen int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= maxLen {
+		return s
+	}
+	lower := strings.ToLower(s)
+	first := -1
+	if len(lower) == len(s) {
+		// Otherwise case folding changed byte offsets; show the start.
+		for _, term := range terms {
+			if i := strings.Index(lower, term); i >= 0 && (first < 0 || i < first) {
+				first = i
+			}
+		}
+	}
+	if first < maxLen-10 {
+		return s[:runeStart(s, maxLen-3)] + "..."
+	}
+
+	start := runeStart(s, first-10)
+	for start > 0 && s[start-1] != ' ' && first-start < 20 {
+		start--
+	}
+	start = runeStart(s, start)
+	end := start + maxLen - 6
+	if end >= len(s) {
+		return "..." + s[start:]
+	}
+	return "..." + s[start:runeStart(s, end)] + "..."
+}
+
+// runeStart returns i, moved back to the start of the rune it falls in.
+func runeStart(s string, i int) int {
+	for i > 0 && i < len(s) && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
+
+type SearchOutput struct {
+	List        string `json:"list"`
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Status      string `json:"status"`
+	Due         string `json:"due,omitempty"`
+}
+
+func outputSearchTable(results []searchResult, terms []string) {
+	if len(results) == 0 {
+		utils.Warn("No matching tasks\n")
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"No", "Title", "List", "Description", "Status", "Due"})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetBorder(false)
+	table.SetCenterSeparator("|")
+	table.SetRowLine(false)
+	table.SetRowSeparator("-")
+	table.SetAutoWrapText(false)
+
+	for i, r := range results {
+		table.Append([]string{
+			fmt.Sprintf("%d", i+1),
+			highlight(truncate(r.Task.Title, 40), terms),
+			truncate(r.ListName, 20),
+			highlight(snippet(r.Task.Notes, terms, 40), terms),
+			statusLabel(r.Task.Status),
+			formatDueHuman(r.Task.Due),
+		})
+	}
+	table.Render()
+	utils.Print("\n%d matching tasks\n", len(results))
+}
+
+func outputSearchJSON(results []searchResult) {
+	output := []SearchOutput{}
+	for _, r := range results {
+		output = append(output, SearchOutput{
+			List:        r.ListName,
+			ID:          r.Task.Id,
+			Title:       r.Task.Title,
+			Description: r.Task.Notes,
+			Status:      statusLabel(r.Task.Status),
+			Due:         formatDueISO(r.Task.Due),
+		})
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(output)
+}
+
+func outputSearchCSV(results []searchResult) {
+	writer := csv.NewWriter(os.Stdout)
+	defer writer.Flush()
+
+	_ = writer.Write([]string{"List", "Title", "Description", "Status", "Due", "ID"})
+	for _, r := range results {
+		_ = writer.Write([]string{
+			r.ListName,
+			r.Task.Title,
+			r.Task.Notes,
+			statusLabel(r.Task.Status),
+			formatDueHuman(r.Task.Due),
+			r.Task.Id,
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("x", 60)
+	tests := []struct {
+		name  string
+		notes string
+		terms []string
+		want  string
+	}{
+		{"short", "buy  milk\nand eggs", []string{"milk"}, "buy milk and eggs"},
+		{"match near the start", "milk " + long, []string{"milk"}, "milk " + long[:32] + "..."},
+		{"match deep inside", long + " needle here", []string{"needle"}, "..." + long[41:] + " needle here"},
+		{"multi-byte before match", strings.Repeat("é", 30) + " needle " + long, []string{"needle"}, ""},
+		{"multi-byte at the end", strings.Repeat("日本", 20), []string{"x"}, ""},
+		{"case folding changes length", "İ" + long + " needle", []string{"needle"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := snippet(tt.notes, tt.terms, 40)
+			if !utf8.ValidString(got) {
+				t.Fatalf("invalid UTF-8: %q", got)
+			}
+			if len(got) > 40 {
+				t.Errorf("got %d bytes, want at most 40: %q", len(got), got)
+			}
+			if tt.want != "" && got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		terms []string
+		max   int
+		want  string
+	}{
+		{"Pay rent", []string{"rent"}, 40, "Pay rent"},
+		{strings.Repeat("a", 45), nil, 40, strings.Repeat("a", 37) + "..."},
+		{strings.Repeat("a", 36) + "日本語", []string{"日本"}, 40, strings.Repeat("a", 36) + "..."},
+		{"Réunion " + strings.Repeat("é", 20), []string{"réunion"}, 40, "Réunion " + strings.Repeat("é", 14) + "..."},
+		{"üüü", nil, 3, "ü"},
+	}
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+	for _, tt := range tests {
+		got := highlight(truncate(tt.title, tt.max), tt.terms)
+		if !utf8.ValidString(got) {
+			t.Fatalf("%q: invalid UTF-8: %q", tt.title, got)
+		}
+		if got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
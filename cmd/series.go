@@ -0,0 +1,824 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BRO3886/gtasks/api"
+	"github.com/BRO3886/gtasks/internal/rrule"
+	"github.com/BRO3886/gtasks/internal/utils"
+	"github.com/araddon/dateparse"
+	"github.com/manifoldco/promptui"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"google.golang.org/api/tasks/v1"
+)
+
+// seriesMarkerPrefix starts the line added to the notes of every task of a
+// recurring series, followed by the series ID and the recurrence rule.
+// Google Tasks has no custom fields, so the notes are the only place a tag
+// survives other clients.
+const seriesMarkerPrefix = "gtasks-series: "
+
+// newSeriesID returns a short random ID for a recurring series.
+func newSeriesID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// seriesMarker returns the marker line of series id. The rule is recorded
+// without COUNT and UNTIL so that the series can be extended later.
+func seriesMarker(id string, rule *rrule.Rule) string {
+	if rule == nil {
+		return seriesMarkerPrefix + id
+	}
+	r := *rule
+	r.Count = 0
+	r.Until = time.Time{}
+	return seriesMarkerPrefix + id + " " + r.String()
+}
+
+// seriesRollingFlag ends the marker of the pending occurrence of a rolling
+// series, which gets a successor when it is completed. The flag is removed
+// once the successor exists.
+const seriesRollingFlag = "rolling"
+
+// rollingMarker returns the marker line of an occurrence of rolling series
+// id. rule is kept as given, UNTIL included, so that the series ends.
+func rollingMarker(id, rule string, rolling bool) string {
+	marker := seriesMarkerPrefix + id + " " + rule
+	if rolling {
+		marker += " " + seriesRollingFlag
+	}
+	return marker
+}
+
+// withSeriesMarker returns notes with a marker line appended.
+func withSeriesMarker(notes, marker string) string {
+	if notes == "" {
+		return marker
+	}
+	return notes + "\n\n" + marker
+}
+
+// seriesMarkerLine returns the marker line in notes, or "" if there is none.
+func seriesMarkerLine(notes string) string {
+	for _, line := range strings.Split(notes, "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, seriesMarkerPrefix) {
+			return line
+		}
+	}
+	return ""
+}
+
+// stripSeriesMarker returns notes without the marker line and the blank
+// lines that separated it from the rest.
+func stripSeriesMarker(notes string) string {
+	var kept []string
+	for _, line := range strings.Split(notes, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), seriesMarkerPrefix) {
+			kept = append(kept, line)
+		}
+	}
+	return strings.TrimRight(strings.Join(kept, "\n"), "\n ")
+}
+
+// seriesOf returns the series ID and rule a task was tagged with. Both are ""
+// if it is not part of a series; the rule is also "" for tasks tagged before
+// rules were recorded.
+func seriesOf(t *tasks.Task) (id, rule string) {
+	fields := seriesMarkerFields(t)
+	switch len(fields) {
+	case 0:
+		return "", ""
+	case 1:
+		return fields[0], ""
+	default:
+		return fields[0], fields[1]
+	}
+}
+
+// rollingSeries reports whether t is an occurrence of a rolling series still
+// waiting to get its successor.
+func rollingSeries(t *tasks.Task) bool {
+	fields := seriesMarkerFields(t)
+	return len(fields) == 3 && fields[2] == seriesRollingFlag
+}
+
+func seriesMarkerFields(t *tasks.Task) []string {
+	return strings.Fields(strings.TrimPrefix(seriesMarkerLine(t.Notes), seriesMarkerPrefix))
+}
+
+// createSeries creates one task per date under parentID (empty for the top
+// level), tagged as series id with rule. The tasks are created several at a
+// time (see api.BulkInsert) and end up right after the task after (empty for
+// the first position) in date order. Creation is all or nothing: if any task
+// cannot be created, no further ones are started and the ones that were are
+// deleted again, unless keepPartial is set. It exits with status 1 on
+// failure.
+func createSeries(ctx context.Context, backend api.Backend, tasklistID, parentID, after string, id string, rule *rrule.Rule, title, notes string, dates []time.Time, keepPartial bool) {
+	marker := seriesMarker(id, rule)
+	notes = withSeriesMarker(notes, marker)
+
+	items := make([]api.NewTask, len(dates))
+	for i, date := range dates {
+		task := &tasks.Task{Title: title, Notes: notes, Due: date.Format(time.RFC3339)}
+		items[i] = api.NewTask{Task: task, Parent: parentID, Previous: after}
+	}
+
+	// The first failure cancels the tasks not yet started.
+	createCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	utils.Info("Creating %d recurring tasks...\n", len(dates))
+	p := newProgress("Created", len(dates))
+	results, orderErr := api.BulkInsert(createCtx, backend, tasklistID, items, bulkOptions(p, func(r api.BulkResult) {
+		if r.Err != nil && createCtx.Err() == nil {
+			utils.ErrorStyle.Printf("Unable to create task %d (due %s): %v\n", r.Index+1, dates[r.Index].Format("02 Jan 2006"), r.Err)
+			stop()
+		}
+	}))
+	p.clear()
+
+	var created []string
+	for _, r := range results {
+		if r.Err == nil {
+			created = append(created, r.Task.Id)
+		}
+	}
+	if len(created) == len(dates) {
+		if orderErr != nil {
+			utils.Warn("Created %d tasks (series %s), but %v; use 'view --sort due' to see them by date\n", len(dates), id, orderErr)
+			return
+		}
+		utils.Info("Created %d tasks (series %s)\n", len(dates), id)
+		return
+	}
+
+	if keepPartial {
+		saveCache()
+		utils.ErrorP("Created %d of %d tasks (series %s); kept them because of --keep-partial\n", len(created), len(dates), id)
+	}
+
+	// A request abandoned on Ctrl-C or --timeout may still have created its
+	// task, so look for the series' tasks on the server as well. The rollback
+	// itself runs even though ctx is done.
+	ctx = context.WithoutCancel(ctx)
+	ids := seriesTaskIDs(ctx, backend, tasklistID, id, dates, created)
+	failed := 0
+	if len(ids) > 0 {
+		utils.Warn("Created %d of %d tasks; deleting them again...\n", len(created), len(dates))
+		p = newProgress("Deleted", len(ids))
+		for _, r := range api.BulkDelete(ctx, backend, tasklistID, ids, bulkOptions(p, nil)) {
+			if r.Err != nil {
+				failed++
+			}
+		}
+		p.clear()
+	}
+
+	saveCache()
+	if failed > 0 {
+		utils.ErrorP("Could not delete %d task(s) of series %s; they contain %q in their notes\n", failed, id, marker)
+	}
+	utils.ErrorP("No tasks created; use --keep-partial to keep the ones that succeed\n")
+}
+
+// createRollingSeries creates the first of dates as the only occurrence of a
+// new rolling series. If limited is set, the last of dates ends the series.
+func createRollingSeries(ctx context.Context, create func(ctx context.Context, t *tasks.Task) (*tasks.Task, error), rule *rrule.Rule, limited bool, title, notes string, dates []time.Time) {
+	r := *rule
+	r.Count = 0
+	r.Until = time.Time{}
+	if limited {
+		r.Until = dates[len(dates)-1]
+	}
+	id := newSeriesID()
+	task := &tasks.Task{
+		Title: title,
+		Notes: withSeriesMarker(notes, rollingMarker(id, r.String(), true)),
+		Due:   dates[0].Format(time.RFC3339),
+	}
+	if _, err := create(ctx, task); err != nil {
+		utils.ErrorP("Unable to create task: %v\n", err)
+	}
+	utils.Info("Task created (rolling series %s), due %s\n", id, dates[0].Format("02 Jan 2006"))
+}
+
+// rollSeries adds the next occurrence of the rolling series of done, a
+// completed task in tasklistID, and then clears the rolling flag of done so
+// that it is not rolled again. The next occurrence is the first date of the
+// rule after done's due date that is not in the past, so missed occurrences
+// are skipped. If siblings, the tasks of the list, already hold a later
+// occurrence, or the series has ended, only the flag is cleared and nil is
+// returned.
+func rollSeries(ctx context.Context, backend api.Backend, tasklistID string, done *tasks.Task, siblings []*tasks.Task) (*tasks.Task, error) {
+	id, ruleText := seriesOf(done)
+	rule, err := rrule.Parse(ruleText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence rule in series %s: %v", id, err)
+	}
+	// Due dates are dates at midnight UTC, so the rule is expanded in UTC,
+	// with today's local date, to write back dates as they are meant.
+	start, ok := dueDate(done.Due, time.UTC)
+	if !ok {
+		return nil, fmt.Errorf("task has no due date to continue series %s from", id)
+	}
+
+	var next *tasks.Task
+	if !hasLaterOccurrence(done, siblings) {
+		y, m, d := time.Now().Date()
+		today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		for _, date := range rule.Expand(start) {
+			if date.After(start) && !date.Before(today) {
+				next = &tasks.Task{Title: done.Title, Notes: done.Notes, Due: date.Format(time.RFC3339)}
+				break
+			}
+		}
+	}
+	if next != nil {
+		if done.Parent != "" {
+			next, err = api.CreateSubtask(ctx, backend, next, tasklistID, done.Parent)
+		} else {
+			next, err = api.CreateTask(ctx, backend, next, tasklistID)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Only the notes are patched, so whatever else changed meanwhile stays.
+	patch := &tasks.Task{Id: done.Id, Notes: withSeriesMarker(stripSeriesMarker(done.Notes), rollingMarker(id, ruleText, false))}
+	if _, err := api.UpdateTask(ctx, backend, patch, tasklistID); err != nil {
+		return next, fmt.Errorf("the next occurrence was added, but %q is still marked as rolling: %v", done.Title, err)
+	}
+	return next, nil
+}
+
+// rollCompletedSeries adds the next occurrence of every rolling series in a
+// list whose pending occurrence was completed without gtasks, e.g. in the
+// Google Tasks app. It reports whether all of them could be added.
+func rollCompletedSeries(ctx context.Context, backend api.Backend, tl tasks.TaskList) bool {
+	taskItems, err := api.GetTasks(ctx, backend, tl.Id, true, 0)
+	if errors.Is(err, api.ErrNoTasks) {
+		return true
+	}
+	if err != nil {
+		utils.ErrorStyle.Printf("%s: %v\n", tl.Title, err)
+		return false
+	}
+	ok := true
+	for _, t := range taskItems {
+		if t.Status != "completed" || !rollingSeries(t) {
+			continue
+		}
+		next, err := rollSeries(ctx, backend, tl.Id, t, taskItems)
+		if next != nil {
+			taskItems = append(taskItems, next)
+			utils.Info("%s: added the next %s, due %s\n", tl.Title, next.Title, formatDueHuman(next.Due))
+		}
+		if err != nil {
+			ok = false
+			utils.ErrorStyle.Printf("%s: %s: %v\n", tl.Title, t.Title, err)
+		}
+	}
+	return ok
+}
+
+// hasLaterOccurrence reports whether tasks holds an occurrence of done's
+// series that is still pending or due after done.
+func hasLaterOccurrence(done *tasks.Task, taskList []*tasks.Task) bool {
+	id, _ := seriesOf(done)
+	for _, t := range taskList {
+		if t.Id == done.Id {
+			continue
+		}
+		if tID, _ := seriesOf(t); tID == id && (t.Status != "completed" || t.Due > done.Due) {
+			return true
+		}
+	}
+	return false
+}
+
+// seriesTaskIDs returns the IDs of the tasks of series id in a list that are
+// due on one of dates, together with known, which are included even if
+// listing fails. Limiting the search to dates leaves the occurrences alone
+// that existed before a series was extended.
+func seriesTaskIDs(ctx context.Context, backend api.Backend, tasklistID, id string, dates []time.Time, known []string) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	add := func(taskID string) {
+		if !seen[taskID] {
+			seen[taskID] = true
+			ids = append(ids, taskID)
+		}
+	}
+	for _, taskID := range known {
+		add(taskID)
+	}
+	taskItems, err := api.GetTasks(ctx, backend, tasklistID, true, 0)
+	if err != nil {
+		return ids
+	}
+	days := make(map[string]bool, len(dates))
+	for _, d := range dates {
+		// The server may keep the date as given or as the UTC date.
+		days[d.Format("2006-01-02")] = true
+		days[d.UTC().Format("2006-01-02")] = true
+	}
+	for _, t := range taskItems {
+		due, ok := dueDate(t.Due, time.UTC)
+		if tID, _ := seriesOf(t); tID == id && ok && days[due.Format("2006-01-02")] {
+			add(t.Id)
+		}
+	}
+	return ids
+}
+
+// seriesInfo is a recurring series found in the tasklists.
+type seriesInfo struct {
+	ID string
+	// Rule is the series' recurrence rule, or "" if it was not recorded.
+	Rule string
+	// Rolling is set for series that only have their next occurrence.
+	Rolling bool
+	// Items are the occurrences ordered by due date.
+	Items []listTask
+}
+
+// pending returns the occurrences not completed yet.
+func (s *seriesInfo) pending() []listTask {
+	var out []listTask
+	for _, item := range s.Items {
+		if item.Task.Status != "completed" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// current returns the occurrence that represents the series: the next one
+// pending, or the last one if all are done.
+func (s *seriesInfo) current() listTask {
+	if p := s.pending(); len(p) > 0 {
+		return p[0]
+	}
+	return s.Items[len(s.Items)-1]
+}
+
+var seriesCmd = &cobra.Command{
+	Use:   "series",
+	Short: "View and manage recurring task series",
+	Long: `
+	Use this command to manage the series of tasks created with
+	'gtasks tasks add --repeat' or '--rrule'. Every task of a
+	series carries a "gtasks-series: <id> <rule>" line in its notes.
+
+	Series are looked up in all tasklists unless -l is given. A
+	series can be named by a unique prefix of its ID.
+	`,
+}
+
+var seriesListCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "List recurring series",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
+		if err != nil {
+			utils.ErrorP("Failed to get service: %v\n", err)
+			return
+		}
+		all := loadSeries(ctx, backend)
+		warnIfStale()
+
+		switch seriesFlags.format {
+		case "json":
+			outputSeriesJSON(all)
+		default:
+			outputSeriesTable(all)
+		}
+	},
+}
+
+var seriesShowCmd = &cobra.Command{
+	Use:   "show <series>",
+	Short: "Show the occurrences of a recurring series",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
+		if err != nil {
+			utils.ErrorP("Failed to get service: %v\n", err)
+			return
+		}
+		s := lookupSeries(loadSeries(ctx, backend), args[0])
+		warnIfStale()
+
+		cur := s.current()
+		utils.Info("Series %s: %s\n", s.ID, cur.Task.Title)
+		utils.Print("List: %s\n", cur.ListName)
+		utils.Print("Repeats: %s\n", s.repeats())
+		if notes := stripSeriesMarker(cur.Task.Notes); notes != "" {
+			utils.Print("Note: %s\n", notes)
+		}
+		utils.Print("\n")
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"No", "Title", "Status", "Due"})
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		table.SetBorder(false)
+		table.SetCenterSeparator("|")
+		table.SetRowLine(false)
+		table.SetRowSeparator("-")
+		table.SetAutoWrapText(false)
+		for i, item := range s.Items {
+			table.Append([]string{
+				strconv.Itoa(i + 1),
+				truncate(item.Task.Title, 40),
+				statusLabel(item.Task.Status),
+				formatDueHuman(item.Task.Due),
+			})
+		}
+		table.Render()
+	},
+}
+
+var seriesExtendCmd = &cobra.Command{
+	Use:   "extend <series>",
+	Short: "Add more occurrences to a recurring series",
+	Long: `
+	Use this command to add occurrences after the last one of a
+	series, following the series' recurrence rule. Give the number
+	of occurrences to add with --count, or the date to continue
+	until with --until; with both, whichever ends first applies.
+
+	The new tasks take the title and note of the last occurrence
+	and are created in its tasklist.
+
+	Examples:
+	  gtasks series extend 3f9a1c2b --count 10
+	  gtasks series extend 3f9a --until 2027-06-30
+	`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if seriesFlags.count < 0 {
+			utils.ErrorP("--count must be positive\n")
+		}
+		var until *time.Time
+		if seriesFlags.until != "" {
+			t, err := dateparse.ParseAny(seriesFlags.until)
+			if err != nil {
+				utils.ErrorP("until date format incorrect. Valid examples: https://github.com/araddon/dateparse#extended-example\n")
+			}
+			until = &t
+		}
+
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
+		if err != nil {
+			utils.ErrorP("Failed to get service: %v\n", err)
+			return
+		}
+		s := lookupSeries(loadSeries(ctx, backend), args[0])
+		if s.Rule == "" {
+			utils.ErrorP("Series %s has no recurrence rule recorded in its notes, so it cannot be extended\n", s.ID)
+		}
+		if s.Rolling {
+			utils.ErrorP("Series %s is rolling: its next occurrence is added when the current one is completed\n", s.ID)
+		}
+		rule, err := rrule.Parse(s.Rule)
+		if err != nil {
+			utils.ErrorP("Series %s has an invalid recurrence rule: %v\n", s.ID, err)
+		}
+
+		// Due dates are dates at midnight UTC, so the schedule is expanded
+		// in UTC to write back the same dates.
+		last := s.Items[len(s.Items)-1]
+		start, ok := dueDate(last.Task.Due, time.UTC)
+		if !ok {
+			utils.ErrorP("The last task of series %s has no due date to continue from\n", s.ID)
+		}
+
+		// The schedule is expanded from the last occurrence so that intervals
+		// keep their phase; the last occurrence itself is dropped.
+		count := seriesFlags.count
+		if count > 0 {
+			count++
+		}
+		var dates []time.Time
+		for _, d := range expandRepeatSchedule(start, rule, count, until) {
+			if d.After(start) {
+				dates = append(dates, d)
+			}
+		}
+		if seriesFlags.count > 0 && len(dates) > seriesFlags.count {
+			dates = dates[:seriesFlags.count]
+		}
+		if len(dates) == 0 {
+			utils.ErrorP("The repeat rule gives no dates after %s\n", start.Format("02 Jan 2006"))
+		}
+
+		// The new occurrences follow the last one.
+		createSeries(ctx, backend, last.ListID, last.Task.Parent, last.Task.Id, s.ID, rule, last.Task.Title, stripSeriesMarker(last.Task.Notes), dates, seriesFlags.keepPartial)
+	},
+}
+
+var seriesUpdateCmd = &cobra.Command{
+	Use:   "update <series>",
+	Short: "Change the title or note of a recurring series",
+	Long: `
+	Use this command to change the title and/or note of every
+	occurrence of a series that is not completed yet. With --all,
+	completed occurrences are changed as well. The series marker
+	line is kept in the notes.
+
+	Examples:
+	  gtasks series update 3f9a --title "Team sync (Room 4)"
+	  gtasks series update 3f9a --note "Bring the weekly numbers"
+	`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		titleSet := cmd.Flags().Changed("title")
+		noteSet := cmd.Flags().Changed("note")
+		if titleSet && seriesFlags.title == "" {
+			utils.ErrorP("The title cannot be empty\n")
+		}
+
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
+		if err != nil {
+			utils.ErrorP("Failed to get service: %v\n", err)
+			return
+		}
+		s := lookupSeries(loadSeries(ctx, backend), args[0])
+		selected, listIDs := seriesSelection(s, seriesFlags.all)
+
+		runOnTasks(ctx, selected, "Updated", true, func(ctx context.Context, t *tasks.Task) error {
+			base := *t
+			if titleSet {
+				t.Title = seriesFlags.title
+			}
+			if noteSet {
+				t.Notes = withSeriesMarker(seriesFlags.note, seriesMarkerLine(base.Notes))
+			}
+			return saveTask(ctx, backend, listIDs[t.Id], &base, t, conflictMerge, nil)
+		})
+	},
+}
+
+var seriesDeleteCmd = &cobra.Command{
+	Use:   "rm <series>",
+	Short: "Delete the remaining occurrences of a recurring series",
+	Long: `
+	Use this command to delete every occurrence of a series that is
+	not completed yet. With --all, completed occurrences are deleted
+	as well. You are asked to confirm unless --force is given.
+	Deleting the pending occurrence of a rolling series ends it.
+	`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
+		if err != nil {
+			utils.ErrorP("Failed to get service: %v\n", err)
+			return
+		}
+		s := lookupSeries(loadSeries(ctx, backend), args[0])
+		selected, listIDs := seriesSelection(s, seriesFlags.all)
+		if len(selected) == 0 {
+			utils.Warn("Series %s has no remaining occurrences; use --all to delete the completed ones\n", s.ID)
+			return
+		}
+
+		if !seriesFlags.force {
+			prompt := promptui.Prompt{
+				Label:     fmt.Sprintf("Delete %d task(s) of series %s (%s)", len(selected), s.ID, s.current().Task.Title),
+				IsConfirm: true,
+			}
+			if _, err := prompt.Run(); err != nil {
+				utils.Info("Cancelled\n")
+				return
+			}
+		}
+
+		runOnTasks(ctx, selected, "Deleted", true, func(ctx context.Context, t *tasks.Task) error {
+			return api.DeleteTask(ctx, backend, t.Id, listIDs[t.Id])
+		})
+	},
+}
+
+var seriesFlags struct {
+	tasklist    string
+	format      string
+	count       int
+	until       string
+	keepPartial bool
+	title       string
+	note        string
+	all         bool
+	force       bool
+}
+
+func init() {
+	seriesCmd.PersistentFlags().StringVarP(&seriesFlags.tasklist, "tasklist", "l", "", "only look for series in this tasklist")
+	seriesListCmd.Flags().StringVar(&seriesFlags.format, "format", "table", "output format: table, json")
+	seriesExtendCmd.Flags().IntVar(&seriesFlags.count, "count", 0, "number of occurrences to add")
+	seriesExtendCmd.Flags().StringVar(&seriesFlags.until, "until", "", "add occurrences up to this date (e.g., '2027-03-01')")
+	seriesExtendCmd.Flags().BoolVar(&seriesFlags.keepPartial, "keep-partial", false, "keep the tasks already created if creating one of them fails")
+	seriesExtendCmd.MarkFlagsOneRequired("count", "until")
+	seriesUpdateCmd.Flags().StringVarP(&seriesFlags.title, "title", "t", "", "new title for the occurrences")
+	seriesUpdateCmd.Flags().StringVarP(&seriesFlags.note, "note", "n", "", "new note for the occurrences")
+	seriesUpdateCmd.MarkFlagsOneRequired("title", "note")
+	seriesUpdateCmd.Flags().BoolVar(&seriesFlags.all, "all", false, "also change completed occurrences")
+	seriesDeleteCmd.Flags().BoolVar(&seriesFlags.all, "all", false, "also delete completed occurrences")
+	seriesDeleteCmd.Flags().BoolVarP(&seriesFlags.force, "force", "f", false, "skip confirmation prompt")
+	seriesCmd.AddCommand(seriesListCmd, seriesShowCmd, seriesExtendCmd, seriesUpdateCmd, seriesDeleteCmd)
+	rootCmd.AddCommand(seriesCmd)
+}
+
+// loadSeries finds the series in the tasklist given with -l, or in all
+// tasklists, ordered by their next due date. Finished series come last.
+func loadSeries(ctx context.Context, backend api.Backend) []*seriesInfo {
+	var lists []tasks.TaskList
+	if seriesFlags.tasklist != "" {
+		lists = []tasks.TaskList{findTaskList(ctx, backend, seriesFlags.tasklist)}
+	} else {
+		var err error
+		lists, err = api.GetTaskLists(ctx, backend)
+		if err != nil {
+			utils.ErrorP("Error %v\n", err)
+		}
+	}
+
+	byID := make(map[string]*seriesInfo)
+	var all []*seriesInfo
+	for _, item := range fetchAllTasks(ctx, backend, lists, true) {
+		id, rule := seriesOf(item.Task)
+		if id == "" {
+			continue
+		}
+		s, ok := byID[id]
+		if !ok {
+			s = &seriesInfo{ID: id}
+			byID[id] = s
+			all = append(all, s)
+		}
+		if rollingSeries(item.Task) {
+			s.Rolling = true
+			s.Rule = rule
+		} else if rule != "" && !s.Rolling {
+			s.Rule = rule
+		}
+		s.Items = append(s.Items, item)
+	}
+
+	for _, s := range all {
+		sort.SliceStable(s.Items, func(i, j int) bool {
+			return s.Items[i].Task.Due < s.Items[j].Task.Due
+		})
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		a, b := all[i].current().Task, all[j].current().Task
+		aDone, bDone := a.Status == "completed", b.Status == "completed"
+		if aDone != bDone {
+			return bDone
+		}
+		return a.Due < b.Due
+	})
+	return all
+}
+
+// lookupSeries returns the series whose ID is ref or starts with it, exiting
+// if there is no such series or more than one.
+func lookupSeries(all []*seriesInfo, ref string) *seriesInfo {
+	var matches []*seriesInfo
+	for _, s := range all {
+		if s.ID == ref {
+			return s
+		}
+		if strings.HasPrefix(s.ID, ref) {
+			matches = append(matches, s)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		utils.ErrorP("No series %q found\n", ref)
+	case 1:
+		return matches[0]
+	}
+	ids := make([]string, len(matches))
+	for i, s := range matches {
+		ids[i] = s.ID
+	}
+	utils.ErrorP("%q matches several series: %s\n", ref, strings.Join(ids, ", "))
+	return nil
+}
+
+// seriesSelection returns the pending occurrences of s, or all of them if all
+// is set, together with the tasklist ID of each by task ID.
+func seriesSelection(s *seriesInfo, all bool) ([]*tasks.Task, map[string]string) {
+	items := s.pending()
+	if all {
+		items = s.Items
+	}
+	var selected []*tasks.Task
+	listIDs := make(map[string]string, len(items))
+	for _, item := range items {
+		selected = append(selected, item.Task)
+		listIDs[item.Task.Id] = item.ListID
+	}
+	return selected, listIDs
+}
+
+// repeats describes how series s repeats.
+func (s *seriesInfo) repeats() string {
+	switch {
+	case s.Rule == "":
+		return "(not recorded)"
+	case s.Rolling:
+		return s.Rule + " (rolling)"
+	}
+	return s.Rule
+}
+
+// SeriesOutput is a series in JSON output.
+type SeriesOutput struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	List    string `json:"list"`
+	Rule    string `json:"rule,omitempty"`
+	Rolling bool   `json:"rolling,omitempty"`
+	Done    int    `json:"done"`
+	Total   int    `json:"total"`
+	Next    string `json:"next,omitempty"`
+}
+
+func seriesOutput(s *seriesInfo) SeriesOutput {
+	cur := s.current()
+	out := SeriesOutput{
+		ID:      s.ID,
+		Title:   cur.Task.Title,
+		List:    cur.ListName,
+		Rule:    s.Rule,
+		Rolling: s.Rolling,
+		Done:    len(s.Items) - len(s.pending()),
+		Total:   len(s.Items),
+	}
+	if cur.Task.Status != "completed" {
+		out.Next = formatDueISO(cur.Task.Due)
+	}
+	return out
+}
+
+func outputSeriesTable(all []*seriesInfo) {
+	if len(all) == 0 {
+		utils.Info("No recurring series found\n")
+		return
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Series", "Title", "List", "Repeats", "Done", "Next"})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetBorder(false)
+	table.SetCenterSeparator("|")
+	table.SetRowLine(false)
+	table.SetRowSeparator("-")
+	table.SetAutoWrapText(false)
+	for _, s := range all {
+		out := seriesOutput(s)
+		next := "-"
+		if out.Next != "" {
+			next = formatDueHuman(s.current().Task.Due)
+		}
+		table.Append([]string{
+			out.ID,
+			truncate(out.Title, 30),
+			truncate(out.List, 20),
+			truncate(s.repeats(), 40),
+			fmt.Sprintf("%d/%d", out.Done, out.Total),
+			next,
+		})
+	}
+	table.Render()
+}
+
+func outputSeriesJSON(all []*seriesInfo) {
+	output := []SeriesOutput{}
+	for _, s := range all {
+		output = append(output, seriesOutput(s))
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(output)
+}
@@ -0,0 +1,350 @@
+package cmd
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BRO3886/gtasks/api"
+	"github.com/BRO3886/gtasks/internal/ical"
+	"github.com/BRO3886/gtasks/internal/utils"
+	"github.com/spf13/cobra"
+	"google.golang.org/api/tasks/v1"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve tasks to other apps over HTTP",
+	Long: `
+	Use these commands to make tasks available to other apps
+	while gtasks keeps running:
+
+	  gtasks serve ics    iCalendar feeds for calendar apps
+	`,
+}
+
+var serveICSCmd = &cobra.Command{
+	Use:   "ics",
+	Short: "Serve iCalendar feeds of tasks for calendar apps to subscribe to",
+	Long: `
+	Use this command to serve the tasks as iCalendar feeds that a
+	calendar app can subscribe to. It runs until interrupted.
+
+	  /tasks.ics                  every tasklist as VTODOs
+	  /events.ics                 every tasklist as all-day VEVENTs
+	                              on the due dates
+	  /lists/<list>/tasks.ics     one tasklist, by title or ID
+	  /lists/<list>/events.ics
+
+	Feeds are built from the local task cache, which is brought up to
+	date with Google Tasks at most once every --refresh; with
+	--offline it is never refreshed. Completed tasks are left out
+	unless --include-completed is given.
+
+	With --token the feeds are served under /<token>/ only, so that
+	other users and processes that can reach the port cannot read
+	the tasks without knowing it. By default the server only accepts
+	connections from this machine; use --addr :8765 to listen on
+	every interface.
+
+	Examples:
+	  gtasks serve ics
+	  gtasks serve ics --addr :8765 --token "$(openssl rand -hex 16)"
+	`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if strings.Contains(serveFlags.token, "/") {
+			utils.ErrorP("The token cannot contain \"/\"\n")
+		}
+		if serveFlags.refresh <= 0 {
+			utils.ErrorP("Invalid --refresh %s\n", serveFlags.refresh)
+		}
+
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
+		if err != nil {
+			utils.ErrorP("Failed to get service: %v\n", err)
+			return
+		}
+		fs := &feedServer{backend: backend, refresh: serveFlags.refresh, includeCompleted: serveFlags.includeCompleted}
+		// Fill the cache with every list before serving from it.
+		b, _ := fs.session(true)
+		lists, err := api.GetTaskLists(ctx, b)
+		if err != nil {
+			utils.ErrorP("Error %v\n", err)
+		}
+		fetchAllTasks(ctx, b, lists, true)
+		fs.save()
+		warnIfStale()
+
+		ln, err := net.Listen("tcp", serveFlags.addr)
+		if err != nil {
+			utils.ErrorP("%v\n", err)
+		}
+		base := feedBaseURL(ln.Addr(), serveFlags.addr, serveFlags.token)
+		utils.Info("Serving iCalendar feeds on %s\n", base)
+		utils.Print("  %-20s %s\n", "All tasklists:", base+"tasks.ics")
+		for _, tl := range lists {
+			utils.Print("  %-20s %s\n", truncate(tl.Title, 18)+":", base+feedListPath(tl.Title)+"tasks.ics")
+		}
+		utils.Print("Use events.ics instead of tasks.ics for apps that do not show to-dos. Press Ctrl+C to stop.\n")
+
+		srv := &http.Server{Handler: withFeedToken(serveFlags.token, fs.handler()), ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_ = srv.Shutdown(shutdownCtx)
+		}()
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			utils.ErrorP("%v\n", err)
+		}
+	},
+}
+
+var serveFlags struct {
+	addr             string
+	token            string
+	refresh          time.Duration
+	includeCompleted bool
+}
+
+func init() {
+	serveICSCmd.Flags().StringVar(&serveFlags.addr, "addr", "localhost:8765", "address to listen on, e.g. :8765 for every interface")
+	serveICSCmd.Flags().StringVar(&serveFlags.token, "token", "", "serve the feeds under /<token>/ only")
+	serveICSCmd.Flags().DurationVar(&serveFlags.refresh, "refresh", 5*time.Minute, "how often to fetch changes from Google Tasks at most")
+	serveICSCmd.Flags().BoolVarP(&serveFlags.includeCompleted, "include-completed", "i", false, "include completed tasks in the feeds")
+	serveCmd.AddCommand(serveICSCmd)
+	rootCmd.AddCommand(serveCmd)
+}
+
+// feedServer answers feed requests from the task cache.
+type feedServer struct {
+	backend          api.Backend
+	refresh          time.Duration
+	includeCompleted bool
+
+	mu        sync.Mutex
+	refreshed time.Time
+}
+
+// session returns the backend to answer a request with, and whether it may
+// contact Google Tasks. The cache is brought up to date at most once per
+// refresh interval, or right away with force; in between, feeds are built
+// from the cache alone. Each session keeps its own record of network errors,
+// so that one failed refresh does not keep the server offline.
+func (s *feedServer) session(force bool) (api.Backend, bool) {
+	cached, ok := s.backend.(*api.CachedBackend)
+	if !ok {
+		return s.backend, true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !force && time.Since(s.refreshed) < s.refresh {
+		return cached.Session(false), false
+	}
+	s.refreshed = time.Now()
+	return cached.Session(true), true
+}
+
+// save writes the cache back to disk after a refresh.
+func (s *feedServer) save() {
+	if cached, ok := s.backend.(*api.CachedBackend); ok {
+		if err := cached.Save(); err != nil {
+			utils.WarnStyle.Fprintf(os.Stderr, "Unable to save local cache: %v\n", err)
+		}
+	}
+}
+
+func (s *feedServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.index)
+	mux.HandleFunc("GET /tasks.ics", func(w http.ResponseWriter, r *http.Request) {
+		s.feed(w, r, "", false)
+	})
+	mux.HandleFunc("GET /events.ics", func(w http.ResponseWriter, r *http.Request) {
+		s.feed(w, r, "", true)
+	})
+	mux.HandleFunc("GET /lists/{list}/tasks.ics", func(w http.ResponseWriter, r *http.Request) {
+		s.feed(w, r, r.PathValue("list"), false)
+	})
+	mux.HandleFunc("GET /lists/{list}/events.ics", func(w http.ResponseWriter, r *http.Request) {
+		s.feed(w, r, r.PathValue("list"), true)
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		mux.ServeHTTP(rec, r)
+		fmt.Fprintf(os.Stderr, "%s %s %s %d\n", time.Now().Format("15:04:05"), r.Method, r.URL.EscapedPath(), rec.status)
+	})
+}
+
+// index lists the feeds as plain text.
+func (s *feedServer) index(w http.ResponseWriter, r *http.Request) {
+	b, online := s.session(false)
+	lists, err := api.GetTaskLists(r.Context(), b)
+	if err != nil {
+		feedError(w, err)
+		return
+	}
+	if online {
+		s.save()
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "All tasklists: tasks.ics, events.ics\n")
+	for _, tl := range lists {
+		fmt.Fprintf(w, "%s: %stasks.ics, %sevents.ics\n", tl.Title, feedListPath(tl.Title), feedListPath(tl.Title))
+	}
+}
+
+// feed writes the VTODOs, or with events the VEVENTs, of one list, or of
+// every list if listRef is "". A list that is not in the cache yet, such as
+// one added since the last refresh, makes the cache refresh right away.
+func (s *feedServer) feed(w http.ResponseWriter, r *http.Request, listRef string, events bool) {
+	b, online := s.session(false)
+	cal, err := s.calendar(r.Context(), b, listRef, events)
+	var notFound errFeedList
+	if !online && (errors.Is(err, api.ErrNotCached) || errors.As(err, &notFound)) {
+		b, online = s.session(true)
+		cal, err = s.calendar(r.Context(), b, listRef, events)
+	}
+	if online {
+		s.save()
+	}
+	switch {
+	case errors.As(err, &notFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		feedError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	_ = ical.Write(w, cal)
+}
+
+// errFeedList is returned for a feed of a list that does not exist.
+type errFeedList string
+
+func (e errFeedList) Error() string {
+	return fmt.Sprintf("no tasklist %q", string(e))
+}
+
+func (s *feedServer) calendar(ctx context.Context, b api.Backend, listRef string, events bool) (*ical.Calendar, error) {
+	lists, err := api.GetTaskLists(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	cal := &ical.Calendar{Name: "Google Tasks", Refresh: s.refresh}
+	if listRef != "" {
+		tl, ok := lookupFeedList(lists, listRef)
+		if !ok {
+			return nil, errFeedList(listRef)
+		}
+		lists = []tasks.TaskList{tl}
+		cal.Name = tl.Title
+	}
+
+	for _, tl := range lists {
+		items, err := api.GetTasks(ctx, b, tl.Id, s.includeCompleted, 0)
+		if err != nil && !errors.Is(err, api.ErrNoTasks) {
+			return nil, err
+		}
+		utils.Sort(items, "position")
+		if events {
+			cal.Events = append(cal.Events, icsEvents(items, tl.Title)...)
+		} else {
+			cal.Todos = append(cal.Todos, icsTodos(items, tl.Title)...)
+		}
+	}
+	return cal, nil
+}
+
+// lookupFeedList finds a list by ID or title, case aside.
+func lookupFeedList(lists []tasks.TaskList, ref string) (tasks.TaskList, bool) {
+	for _, tl := range lists {
+		if tl.Id == ref || tl.Title == ref {
+			return tl, true
+		}
+	}
+	for _, tl := range lists {
+		if strings.EqualFold(tl.Title, ref) {
+			return tl, true
+		}
+	}
+	return tasks.TaskList{}, false
+}
+
+func feedError(w http.ResponseWriter, err error) {
+	status := http.StatusBadGateway
+	if errors.Is(err, api.ErrNotCached) {
+		status = http.StatusServiceUnavailable
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// feedListPath returns the path of a list's feeds relative to the root.
+func feedListPath(title string) string {
+	return "lists/" + url.PathEscape(title) + "/"
+}
+
+// feedBaseURL returns the URL the feeds are served under. A server listening
+// on every interface is shown as localhost.
+func feedBaseURL(addr net.Addr, requested, token string) string {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "http://" + requested + "/"
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	} else if h, _, err := net.SplitHostPort(requested); err == nil && h != "" {
+		host = h
+	}
+	base := "http://" + net.JoinHostPort(host, port) + "/"
+	if token != "" {
+		base += url.PathEscape(token) + "/"
+	}
+	return base
+}
+
+// withFeedToken serves h under /<token>/ only, comparing the token in
+// constant time. Without a token h is served as is.
+func withFeedToken(token string, h http.Handler) http.Handler {
+	if token == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		first, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+		given, err := url.PathUnescape(first)
+		if err != nil || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			http.NotFound(w, r)
+			return
+		}
+		r2 := r.Clone(r.Context())
+		r2.URL.RawPath = "/" + rest
+		if r2.URL.Path, err = url.PathUnescape(r2.URL.RawPath); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		h.ServeHTTP(w, r2)
+	})
+}
+
+// statusRecorder remembers the status code of a response for the request log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
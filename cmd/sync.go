@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/BRO3886/gtasks/api"
+	"github.com/BRO3886/gtasks/internal/cache"
+	"github.com/BRO3886/gtasks/internal/utils"
+	"github.com/spf13/cobra"
+	"google.golang.org/api/tasks/v1"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Update the local cache from Google Tasks",
+	Long: `
+	Use this command to bring the local cache used by --offline
+	up to date. Only tasks changed since the last sync are
+	downloaded, so syncing an unchanged list is a single request.
+	Use -l to sync one tasklist instead of all of them.
+
+	Rolling recurring tasks (see 'gtasks tasks add --rolling') that
+	were completed elsewhere get their next occurrence.
+
+	Changes made offline are sent with 'gtasks sync push'.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		cb := onlineCachedBackend(ctx)
+
+		var lists []tasks.TaskList
+		if syncFlags.tasklist != "" {
+			lists = []tasks.TaskList{findTaskList(ctx, cb, syncFlags.tasklist)}
+		} else {
+			var err error
+			lists, err = api.GetTaskLists(ctx, cb)
+			if err != nil {
+				utils.ErrorP("Error %v\n", err)
+				return
+			}
+		}
+
+		failed := false
+		for _, tl := range lists {
+			changed, err := cb.Sync(ctx, tl.Id)
+			if err != nil {
+				failed = true
+				utils.ErrorStyle.Printf("%s: %v\n", tl.Title, err)
+				continue
+			}
+			utils.Info("%s: %s\n", tl.Title, describeChanges(changed))
+			if !rollCompletedSeries(ctx, cb, tl) {
+				failed = true
+			}
+		}
+		if failed {
+			saveCache()
+			utils.ErrorP("Some tasklists could not be synced\n")
+		}
+	},
+}
+
+var syncPushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Send changes made offline to Google Tasks",
+	Long: `
+	Use this command to send the tasks added, changed, moved or
+	deleted while offline, in the order they were made.
+
+	A change to a task that was also changed on the server in the
+	meantime is not applied and stays queued. Review it with
+	'gtasks sync status', then either run push again with --force
+	to overwrite the server's version, or drop it with
+	'gtasks sync discard'.
+	`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		pushing = true
+		ctx := cmd.Context()
+		cb := onlineCachedBackend(ctx)
+		if len(cb.Pending()) == 0 {
+			utils.Info("No offline changes to push\n")
+			return
+		}
+		titles := taskListTitles(ctx, cb)
+
+		results, err := cb.Push(ctx, syncFlags.force)
+		failed := 0
+		for _, r := range results {
+			if r.Err != nil {
+				failed++
+				utils.ErrorStyle.Printf("✘ %s: %v\n", describeOp(r.Op, titles), r.Err)
+				continue
+			}
+			utils.Print("✔ %s\n", describeOp(r.Op, titles))
+		}
+		if err != nil {
+			saveCache()
+			utils.ErrorP("Push stopped: %v\n", err)
+			return
+		}
+
+		utils.Info("Pushed %d of %s\n", len(results)-failed, pluralChanges(len(results)))
+		if failed > 0 {
+			saveCache()
+			utils.ErrorP("%s could not be pushed and stayed queued; see 'gtasks sync status'\n", pluralChanges(failed))
+		}
+	},
+}
+
+var syncStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List changes made offline that are waiting to be pushed",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		pushing = true
+		ctx := cmd.Context()
+		cb := localCachedBackend(ctx)
+		pending := cb.Pending()
+		if len(pending) == 0 {
+			utils.Info("No offline changes waiting\n")
+			return
+		}
+		titles := taskListTitles(ctx, cb)
+		utils.Info("%s waiting to be pushed:\n", pluralChanges(len(pending)))
+		for _, op := range pending {
+			utils.Print("%3d  %s  %s\n", op.ID, op.QueuedAt.Local().Format("02 Jan 15:04"), describeOp(op, titles))
+		}
+	},
+}
+
+var syncDiscardCmd = &cobra.Command{
+	Use:   "discard [change-id...]",
+	Short: "Drop changes made offline without sending them",
+	Long: `
+	Use this command to drop queued offline changes, given by the
+	numbers shown by 'gtasks sync status'. Use --all to drop every
+	queued change. The affected tasklists are fetched again in full
+	the next time they are used online.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		pushing = true
+		if len(args) == 0 && !syncFlags.all {
+			utils.ErrorP("Give the changes to discard, or use --all\n")
+			return
+		}
+		if len(args) > 0 && syncFlags.all {
+			utils.ErrorP("Use either change numbers or --all, not both\n")
+			return
+		}
+		ids := make([]int, 0, len(args))
+		for _, a := range args {
+			id, err := strconv.Atoi(a)
+			if err != nil {
+				utils.ErrorP("Invalid change number: %s\n", a)
+				return
+			}
+			ids = append(ids, id)
+		}
+
+		ctx := cmd.Context()
+		cb := localCachedBackend(ctx)
+		before := len(cb.Pending())
+		if err := cb.Discard(ids...); err != nil {
+			utils.ErrorP("Unable to discard changes: %v\n", err)
+			return
+		}
+		utils.Info("Discarded %s\n", pluralChanges(before-len(cb.Pending())))
+	},
+}
+
+var syncFlags struct {
+	tasklist string
+	force    bool
+	all      bool
+}
+
+// pushing is set by the sync subcommands that deal with queued changes, which
+// report them themselves.
+var pushing bool
+
+func init() {
+	syncCmd.Flags().StringVarP(&syncFlags.tasklist, "tasklist", "l", "", "only sync this tasklist")
+	syncPushCmd.Flags().BoolVar(&syncFlags.force, "force", false, "apply changes even if the task changed on the server")
+	syncDiscardCmd.Flags().BoolVar(&syncFlags.all, "all", false, "discard every queued change")
+	syncCmd.AddCommand(syncPushCmd, syncStatusCmd, syncDiscardCmd)
+	rootCmd.AddCommand(syncCmd)
+}
+
+// cachedBackendOrExit opens the cached backend or exits.
+func cachedBackendOrExit(ctx context.Context) *api.CachedBackend {
+	backend, err := newBackend(ctx)
+	if err != nil {
+		utils.ErrorP("Failed to get service: %v\n", err)
+	}
+	cb, ok := backend.(*api.CachedBackend)
+	if !ok {
+		utils.ErrorP("No local cache available\n")
+	}
+	return cb
+}
+
+// localCachedBackend opens the cached backend without contacting Google
+// Tasks, for commands that only look at local state.
+func localCachedBackend(ctx context.Context) *api.CachedBackend {
+	offlineFlag = true
+	return cachedBackendOrExit(ctx)
+}
+
+// onlineCachedBackend opens the cached backend and exits unless it can reach
+// Google Tasks.
+func onlineCachedBackend(ctx context.Context) *api.CachedBackend {
+	cb := cachedBackendOrExit(ctx)
+	if cb.Offline() {
+		utils.ErrorP("Syncing needs a connection to Google Tasks\n")
+	}
+	return cb
+}
+
+// taskListTitles maps tasklist IDs to titles for reporting, as far as they
+// can be found.
+func taskListTitles(ctx context.Context, b api.Backend) map[string]string {
+	titles := make(map[string]string)
+	lists, err := api.GetTaskLists(ctx, b)
+	if err != nil {
+		return titles
+	}
+	for _, tl := range lists {
+		titles[tl.Id] = tl.Title
+	}
+	return titles
+}
+
+// describeOp summarises a queued change, e.g. `update "alpha" in Work`.
+func describeOp(op *cache.Op, titles map[string]string) string {
+	listName := func(id string) string {
+		if t, ok := titles[id]; ok {
+			return t
+		}
+		return id
+	}
+	title := op.Title
+	if title == "" {
+		title = op.TaskID
+	}
+
+	switch op.Kind {
+	case cache.OpCreate:
+		return fmt.Sprintf("add %q to %s", title, listName(op.ListID))
+	case cache.OpPatch:
+		return fmt.Sprintf("update %q in %s", title, listName(op.ListID))
+	case cache.OpDelete:
+		return fmt.Sprintf("delete %q from %s", title, listName(op.ListID))
+	case cache.OpMove:
+		if op.DestinationList != "" && op.DestinationList != op.ListID {
+			return fmt.Sprintf("move %q from %s to %s", title, listName(op.ListID), listName(op.DestinationList))
+		}
+		return fmt.Sprintf("move %q in %s", title, listName(op.ListID))
+	}
+	return fmt.Sprintf("%s %q", op.Kind, title)
+}
+
+// describeChanges summarises the result of a sync.
+func describeChanges(changed []*tasks.Task) string {
+	if len(changed) == 0 {
+		return "up to date"
+	}
+	removed := 0
+	for _, t := range changed {
+		if t.Deleted {
+			removed++
+		}
+	}
+	var parts []string
+	if n := len(changed) - removed; n > 0 {
+		parts = append(parts, fmt.Sprintf("%d updated", n))
+	}
+	if removed > 0 {
+		parts = append(parts, fmt.Sprintf("%d removed", removed))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -6,7 +6,6 @@ import (
 	"github.com/fatih/color"
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
-	"google.golang.org/api/tasks/v1"
 )
 
 // tasklistsCmd represents the tasklists command
@@ -34,12 +33,13 @@ var showlistsCmd = &cobra.Command{
 	Short: "view tasklists",
 	Long:  `view task lists for the account currently signed in`,
 	Run: func(cmd *cobra.Command, args []string) {
-		srv, err := api.GetService()
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
 		if err != nil {
 			utils.ErrorP("Failed to get service: %v\n", err)
 			return
 		}
-		list, err := api.GetTaskLists(srv)
+		list, err := api.GetTaskLists(ctx, backend)
 		if err != nil {
 			utils.ErrorP("Error: %v\n", err)
 		}
@@ -55,7 +55,8 @@ var addListcmd = &cobra.Command{
 	Short: "add tasklist",
 	Long:  `add tasklist for the currently signed in account`,
 	Run: func(cmd *cobra.Command, args []string) {
-		srv, err := api.GetService()
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
 		if err != nil {
 			utils.ErrorP("Failed to get service: %v\n", err)
 			return
@@ -64,8 +65,7 @@ var addListcmd = &cobra.Command{
 			utils.Warn("%s\n", "Title should not be empty. Use -t for title.\nExamples:\ngtasks tasklists create -t <TITLE>\ngtasks tasklists create --title <TITLE>")
 			return
 		}
-		t := &tasks.TaskList{Title: title}
-		r, err := srv.Tasklists.Insert(t).Do()
+		r, err := api.CreateTaskList(ctx, backend, title)
 		if err != nil {
 			utils.ErrorP("Unable to create task list. %v", err)
 		}
@@ -79,12 +79,13 @@ var removeListCmd = &cobra.Command{
 	Short: "remove tasklist",
 	Long:  `Remove a tasklist for the currently signed in account`,
 	Run: func(cmd *cobra.Command, args []string) {
-		srv, err := api.GetService()
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
 		if err != nil {
 			utils.ErrorP("Failed to get service: %v\n", err)
 			return
 		}
-		list, err := api.GetTaskLists(srv)
+		list, err := api.GetTaskLists(ctx, backend)
 		if err != nil {
 			utils.ErrorP("Error %v", err)
 		}
@@ -106,7 +107,7 @@ var removeListCmd = &cobra.Command{
 		}
 		utils.Print("%s: %s\n", utils.WarnStyle.Sprint("Deleting list..."), result)
 
-		err = api.DeleteTaskList(srv, list[option].Id)
+		err = api.DeleteTaskList(ctx, backend, list[option].Id)
 		if err != nil {
 			utils.ErrorP("Error deleting tasklist: %s", err.Error())
 			return
@@ -120,7 +121,8 @@ var updateTitleCmd = &cobra.Command{
 	Short: "update tasklist title",
 	Long:  `Update tasklist title for the currently signed in account`,
 	Run: func(cmd *cobra.Command, args []string) {
-		srv, err := api.GetService()
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
 		if err != nil {
 			utils.ErrorP("Failed to get service: %v\n", err)
 			return
@@ -130,9 +132,9 @@ var updateTitleCmd = &cobra.Command{
 			return
 		}
 
-		list, err := api.GetTaskLists(srv)
+		list, err := api.GetTaskLists(ctx, backend)
 		if err != nil {
-			utils.ErrorP(utils.Error("Error %v", err))
+			utils.ErrorP("Error %v\n", err)
 		}
 
 		utils.Print("Choose a Tasklist:")
@@ -152,9 +154,9 @@ var updateTitleCmd = &cobra.Command{
 		t := list[option]
 		t.Title = title
 
-		_, err = api.UpdateTaskList(srv, &t)
+		_, err = api.UpdateTaskList(ctx, backend, &t)
 		if err != nil {
-			utils.ErrorP("Error updating tasklist: ", err.Error())
+			utils.ErrorP("Error updating tasklist: %s\n", err.Error())
 		}
 		utils.Info("Tasklist title updated")
 	},
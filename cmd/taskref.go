@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+// minShortIDLen is the minimum length of the ID prefixes printed by `tasks view --ids`.
+const minShortIDLen = 6
+
+// titleRefPrefix selects a task by title instead of by number or ID.
+const titleRefPrefix = "title:"
+
+// resolveTaskRef returns the index in taskList of the task referred to by ref.
+//
+// A reference is one of:
+//   - a 1-based task number as shown by `tasks view`
+//   - a full Google task ID, or a prefix of one that matches a single task
+//   - "title:<text>", matching the title exactly (case-insensitive), or
+//     failing that, the single title that contains text
+func resolveTaskRef(ref string, taskList []*tasks.Task) (int, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return 0, fmt.Errorf("empty task reference")
+	}
+
+	if strings.HasPrefix(strings.ToLower(ref), titleRefPrefix) {
+		return resolveTitleRef(ref[len(titleRefPrefix):], taskList)
+	}
+
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 1 || n > len(taskList) {
+			return 0, fmt.Errorf("incorrect task number %d (expected 1-%d)", n, len(taskList))
+		}
+		return n - 1, nil
+	}
+
+	var matches []int
+	for i, t := range taskList {
+		if t.Id == ref {
+			return i, nil
+		}
+		if strings.HasPrefix(t.Id, ref) {
+			matches = append(matches, i)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Errorf("no task with ID or number %q", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		return 0, ambiguousRefError(ref, matches, taskList)
+	}
+}
+
+func resolveTitleRef(text string, taskList []*tasks.Task) (int, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "" {
+		return 0, fmt.Errorf("empty title in task reference")
+	}
+
+	var exact, partial []int
+	for i, t := range taskList {
+		title := strings.ToLower(strings.TrimSpace(t.Title))
+		if title == text {
+			exact = append(exact, i)
+		} else if strings.Contains(title, text) {
+			partial = append(partial, i)
+		}
+	}
+
+	for _, matches := range [][]int{exact, partial} {
+		switch len(matches) {
+		case 0:
+			continue
+		case 1:
+			return matches[0], nil
+		default:
+			return 0, ambiguousRefError(titleRefPrefix+text, matches, taskList)
+		}
+	}
+	return 0, fmt.Errorf("no task with title matching %q", text)
+}
+
+func ambiguousRefError(ref string, matches []int, taskList []*tasks.Task) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%q matches %d tasks:", ref, len(matches))
+	for i, m := range matches {
+		if i == 5 {
+			fmt.Fprintf(&b, "\n  ...")
+			break
+		}
+		fmt.Fprintf(&b, "\n  %d. %s (%s)", m+1, taskList[m].Title, taskList[m].Id)
+	}
+	return fmt.Errorf("%s", b.String())
+}
+
+// shortIDs maps each task ID to its shortest prefix that is unique within
+// taskList, using at least minShortIDLen characters.
+func shortIDs(taskList []*tasks.Task) map[string]string {
+	out := make(map[string]string, len(taskList))
+	for _, t := range taskList {
+		n := minShortIDLen
+		for ; n < len(t.Id); n++ {
+			unique := true
+			for _, other := range taskList {
+				if other.Id != t.Id && strings.HasPrefix(other.Id, t.Id[:n]) {
+					unique = false
+					break
+				}
+			}
+			if unique {
+				break
+			}
+		}
+		if n > len(t.Id) {
+			n = len(t.Id)
+		}
+		out[t.Id] = t.Id[:n]
+	}
+	return out
+}
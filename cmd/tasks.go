@@ -2,18 +2,21 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"os"
 	"runtime"
 	"sort"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/BRO3886/gtasks/api"
 	"github.com/BRO3886/gtasks/internal/config"
+	"github.com/BRO3886/gtasks/internal/filter"
+	"github.com/BRO3886/gtasks/internal/rrule"
 	"github.com/BRO3886/gtasks/internal/utils"
 	"github.com/araddon/dateparse"
 	"github.com/fatih/color"
@@ -37,6 +40,10 @@ var tasksCmd = &cobra.Command{
 	[WITHOUT LIST FLAG]
 	gtasks tasks view|add|rm|done|info
 	* You would be prompted to select a tasklist
+
+	Commands acting on a single task accept a task number,
+	a task ID (or unique prefix, see "view --ids"), or
+	title:<text> to match a task by its title.
 	`,
 }
 
@@ -46,39 +53,50 @@ var viewTasksCmd = &cobra.Command{
 	Long: `
 	Use this command to view tasks in a selected 
 	tasklist for the currently signed in account.
-	You can control output with --format: table (default), json, csv.
+	You can control output with --format: table (default), json, csv,
+	or ics for an iCalendar file of VTODOs.
+	Use --filter to narrow the tasks shown, for example:
+	--filter 'due<=+7d and status=pending and title~"invoice"'
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		srv, err := api.GetService()
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
 		if err != nil {
 			utils.ErrorP("Failed to get service: %v\n", err)
 			return
 		}
-		tList := getTaskLists(srv)
+		expr := parseFilterFlag(viewTasksFlags.filter)
+		tList := getTaskLists(ctx, backend)
 
-		taskItems, err := api.GetTasks(srv, tList.Id, viewTasksFlags.includeCompleted || viewTasksFlags.onlyCompleted, viewTasksFlags.max)
+		// A filter on status or completion date needs completed tasks to match against.
+		includeCompleted := viewTasksFlags.includeCompleted || viewTasksFlags.onlyCompleted ||
+			(expr != nil && filter.Uses(expr, "status", "completed"))
+		taskItems, err := api.GetTasks(ctx, backend, tList.Id, includeCompleted, viewTasksFlags.max)
 		if err != nil {
 			color.Red(err.Error())
 			return
 		}
 
-		utils.Sort(taskItems, viewTasksFlags.sort)
-
 		var filteredTasks []*tasks.Task
-		for _, task := range taskItems {
+		for _, task := range filterTasks(taskItems, expr) {
 			if viewTasksFlags.onlyCompleted && task.Status == "needsAction" {
 				continue
 			}
 			filteredTasks = append(filteredTasks, task)
 		}
 
+		utils.Sort(filteredTasks, viewTasksFlags.sort)
+		warnIfStale()
+
 		switch viewTasksFlags.format {
 		case "json":
-			outputJSON(filteredTasks)
+			outputJSON(filteredTasks, viewTasksFlags.showIDs)
 		case "csv":
-			outputCSV(filteredTasks)
+			outputCSV(filteredTasks, viewTasksFlags.showIDs)
+		case "ics":
+			outputICS(filteredTasks, tList.Title)
 		default:
-			outputTable(filteredTasks, tList.Title)
+			outputTable(filteredTasks, tList.Title, viewTasksFlags.showIDs)
 		}
 	},
 }
@@ -93,16 +111,58 @@ var createTaskCmd = &cobra.Command{
 	Supports recurring tasks with --repeat flag:
 	  gtasks tasks add -t "Standup" -d "2025-02-10" --repeat daily --repeat-count 5
 	  gtasks tasks add -t "Weekly sync" -d "2025-02-10" --repeat weekly --repeat-until "2025-03-10"
+	  gtasks tasks add -t "Gym" -d "2025-02-10" --repeat "every 2 weeks on mon,thu" --repeat-count 8
+	  gtasks tasks add -t "Report" -d "2025-02-01" --repeat "last friday of month" --repeat-count 6
+
+	or with an RFC 5545 recurrence rule:
+	  gtasks tasks add -t "Review" -d "2025-02-10" --rrule "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;COUNT=6"
+
+	Recurring tasks are created all or nothing: if one cannot be created,
+	the others are deleted again unless --keep-partial is given. Each is
+	tagged with a "gtasks-series:" line in its notes.
+
+	With --rolling only the first occurrence is created. The next one is
+	added when it is completed with 'gtasks tasks done', or by 'gtasks
+	sync' when it was completed elsewhere:
+	  gtasks tasks add -t "Water plants" -d "2025-02-10" --repeat "every 3 days" --rolling
+
+	Use --parent with a task number to create a subtask:
+	  gtasks tasks add -t "Book flights" --parent 2
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		srv, err := api.GetService()
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
 		if err != nil {
 			utils.ErrorP("Failed to get service: %v\n", err)
 			return
 		}
-		tList := getTaskLists(srv)
+		tList := getTaskLists(ctx, backend)
 		utils.Warn("Creating task in %s\n", tList.Title)
 
+		var parentID string
+		if addTaskFlags.parent != "" {
+			pending, err := api.GetTasks(ctx, backend, tList.Id, false, 0)
+			if err != nil {
+				color.Red(err.Error())
+				return
+			}
+			utils.Sort(pending, "position")
+			parent := pending[getTaskIndex([]string{addTaskFlags.parent}, pending, tList.Title)]
+			if parent.Parent != "" {
+				utils.ErrorP("Subtasks cannot have subtasks of their own\n")
+				return
+			}
+			parentID = parent.Id
+			utils.Warn("Adding as subtask of %s\n", parent.Title)
+		}
+
+		create := func(ctx context.Context, task *tasks.Task) (*tasks.Task, error) {
+			if parentID != "" {
+				return api.CreateSubtask(ctx, backend, task, tList.Id, parentID)
+			}
+			return api.CreateTask(ctx, backend, task, tList.Id)
+		}
+
 		var title string
 		var notes string
 		var dateInput string
@@ -127,14 +187,24 @@ var createTaskCmd = &cobra.Command{
 		}
 
 		// Parse repeat pattern if specified
-		repeatPattern, err := parseRepeatUnit(addTaskFlags.repeat)
+		if addTaskFlags.repeat != "" && addTaskFlags.rrule != "" {
+			utils.ErrorP("Use either --repeat or --rrule, not both\n")
+		}
+		repeatRule, err := parseRepeat(addTaskFlags.repeat)
+		if addTaskFlags.rrule != "" {
+			repeatRule, err = rrule.Parse(addTaskFlags.rrule)
+		}
 		if err != nil {
 			utils.ErrorP("%v\n", err)
 			return
 		}
 
+		if addTaskFlags.rolling && repeatRule == nil {
+			utils.ErrorP("--rolling needs --repeat or --rrule\n")
+		}
+
 		// If repeat is specified but no due date, require due date
-		if repeatPattern != repeatNone && dateInput == "" {
+		if repeatRule != nil && dateInput == "" {
 			utils.ErrorP("Due date (--due) is required when using --repeat\n")
 			return
 		}
@@ -163,8 +233,24 @@ var createTaskCmd = &cobra.Command{
 
 		// Generate dates for recurring tasks
 		var dates []time.Time
-		if repeatPattern != repeatNone {
-			dates = expandRepeatSchedule(startDate, repeatPattern, addTaskFlags.repeatCount, untilDate)
+		if repeatRule != nil {
+			if addTaskFlags.repeat != "" {
+				clampToMonthEnd(repeatRule, startDate)
+			}
+			limited := addTaskFlags.repeatCount > 0 || untilDate != nil || repeatRule.Count > 0 || !repeatRule.Until.IsZero()
+			count := addTaskFlags.repeatCount
+			if addTaskFlags.rolling && !limited {
+				// Only the first occurrence is created now.
+				count = 1
+			}
+			dates = expandRepeatSchedule(startDate, repeatRule, count, untilDate)
+			if len(dates) == 0 {
+				utils.ErrorP("The repeat rule gives no dates from %s on\n", startDate.Format("02 Jan 2006"))
+			}
+			if addTaskFlags.rolling {
+				createRollingSeries(ctx, create, repeatRule, limited, title, notes, dates)
+				return
+			}
 		} else if dateInput != "" {
 			dates = []time.Time{startDate}
 		} else {
@@ -175,7 +261,7 @@ var createTaskCmd = &cobra.Command{
 		if len(dates) == 0 {
 			// No due date specified
 			task := &tasks.Task{Title: title, Notes: notes}
-			_, err = api.CreateTask(srv, task, tList.Id)
+			_, err = create(ctx, task)
 			if err != nil {
 				utils.ErrorStyle.Printf("Unable to create task: %v", err)
 				return
@@ -184,81 +270,98 @@ var createTaskCmd = &cobra.Command{
 		} else if len(dates) == 1 {
 			// Single task with due date
 			task := &tasks.Task{Title: title, Notes: notes, Due: dates[0].Format(time.RFC3339)}
-			_, err = api.CreateTask(srv, task, tList.Id)
+			_, err = create(ctx, task)
 			if err != nil {
 				utils.ErrorStyle.Printf("Unable to create task: %v", err)
 				return
 			}
 			utils.Info("Task created\n")
 		} else {
-			// Multiple recurring tasks
-			utils.Info("Creating %d recurring tasks...\n", len(dates))
-			for i, d := range dates {
-				task := &tasks.Task{Title: title, Notes: notes, Due: d.Format(time.RFC3339)}
-				_, err = api.CreateTask(srv, task, tList.Id)
-				if err != nil {
-					utils.ErrorStyle.Printf("Unable to create task %d: %v\n", i+1, err)
-					return
-				}
-			}
-			utils.Info("Created %d tasks\n", len(dates))
+			createSeries(ctx, backend, tList.Id, parentID, "", newSeriesID(), repeatRule, title, notes, dates, addTaskFlags.keepPartial)
 		}
 	},
 }
 
 var markCompletedCmd = &cobra.Command{
-	Use:   "done",
+	Use:   "done [task...]",
 	Short: "Mark tasks as done",
 	Long: `
-	Use this command to mark a task as completed
-	in a selected tasklist for the currently signed in account
+	Use this command to mark tasks as completed
+	in a selected tasklist for the currently signed in account.
+
+	Several tasks can be given at once, including ranges:
+	  gtasks tasks done 1 3 5-8
+	  gtasks tasks done --all-matching "invoice"
+	Without arguments, tasks are picked from a checklist.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		srv, err := api.GetService()
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
 		if err != nil {
 			utils.ErrorP("Failed to get service: %v\n", err)
 			return
 		}
-		tList := getTaskLists(srv)
+		tList := getTaskLists(ctx, backend)
 		tID := tList.Id
 
-		tasks, err := api.GetTasks(srv, tID, false, 0)
+		taskItems, err := api.GetTasks(ctx, backend, tID, false, 0)
 		if err != nil {
 			color.Red(err.Error())
 			return
 		}
 
-		ind := getTaskIndex(args, tasks, tList.Title)
-		t := tasks[ind]
-		t.Status = "completed"
-
-		_, err = api.UpdateTask(srv, t, tID)
-		if err != nil {
-			color.Red("Unable to mark task as completed: %v", err)
-			return
+		utils.Sort(taskItems, "position")
+		selected := selectTasks(args, bulkFlags.allMatching, bulkFlags.filter, taskItems, tList.Title)
+
+		var (
+			mu     sync.Mutex
+			rolled []*tasks.Task
+		)
+		runOnTasks(ctx, selected, "Marked as complete", true, func(ctx context.Context, t *tasks.Task) error {
+			base := *t
+			t.Status = "completed"
+			if err := saveTask(ctx, backend, tID, &base, t, conflictMerge, nil); err != nil {
+				return err
+			}
+			if !rollingSeries(t) {
+				return nil
+			}
+			next, err := rollSeries(ctx, backend, tID, t, taskItems)
+			if next != nil {
+				mu.Lock()
+				rolled = append(rolled, next)
+				mu.Unlock()
+			} else if err != nil {
+				return fmt.Errorf("completed, but the next occurrence could not be added (run 'gtasks sync' to retry): %v", err)
+			}
+			return err
+		})
+		for _, next := range rolled {
+			utils.Info("Next: %s, due %s\n", next.Title, formatDueHuman(next.Due))
 		}
-		utils.Info("Marked as complete: %s\n", t.Title)
 	},
 }
 
 var undoTaskCmd = &cobra.Command{
-	Use:   "undo",
-	Short: "Mark a completed task as incomplete",
+	Use:   "undo [task...]",
+	Short: "Mark completed tasks as incomplete",
 	Long: `
-	Use this command to mark a completed task as incomplete
-	in a selected tasklist for the currently signed in account
+	Use this command to mark completed tasks as incomplete
+	in a selected tasklist for the currently signed in account.
+	Task numbers refer to the list of completed tasks.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		srv, err := api.GetService()
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
 		if err != nil {
 			utils.ErrorP("Failed to get service: %v\n", err)
 			return
 		}
-		tList := getTaskLists(srv)
+		tList := getTaskLists(ctx, backend)
 		tID := tList.Id
 
 		// Get completed tasks only
-		taskItems, err := api.GetTasks(srv, tID, true, 0)
+		taskItems, err := api.GetTasks(ctx, backend, tID, true, 0)
 		if err != nil {
 			color.Red(err.Error())
 			return
@@ -277,17 +380,15 @@ var undoTaskCmd = &cobra.Command{
 			return
 		}
 
-		ind := getTaskIndex(args, completedTasks, tList.Title)
-		t := completedTasks[ind]
-		t.Status = "needsAction"
-		t.Completed = nil
+		utils.Sort(completedTasks, "position")
+		selected := selectTasks(args, bulkFlags.allMatching, bulkFlags.filter, completedTasks, tList.Title)
 
-		_, err = api.UpdateTask(srv, t, tID)
-		if err != nil {
-			color.Red("Unable to mark task as incomplete: %v", err)
-			return
-		}
-		utils.Info("Marked as incomplete: %s\n", t.Title)
+		runOnTasks(ctx, selected, "Marked as incomplete", true, func(ctx context.Context, t *tasks.Task) error {
+			base := *t
+			t.Status = "needsAction"
+			t.Completed = nil
+			return saveTask(ctx, backend, tID, &base, t, conflictMerge, nil)
+		})
 	},
 }
 
@@ -300,12 +401,13 @@ var clearTasksCmd = &cobra.Command{
 	by the API. Primarily affects tasks completed via the CLI.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		srv, err := api.GetService()
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
 		if err != nil {
 			utils.ErrorP("Failed to get service: %v\n", err)
 			return
 		}
-		tList := getTaskLists(srv)
+		tList := getTaskLists(ctx, backend)
 
 		// Confirmation prompt unless --force is set
 		if !clearTasksFlags.force {
@@ -320,7 +422,7 @@ var clearTasksCmd = &cobra.Command{
 			}
 		}
 
-		err = api.ClearTasks(srv, tList.Id)
+		err = api.ClearTasks(ctx, backend, tList.Id)
 		if err != nil {
 			color.Red("Unable to clear completed tasks: %v", err)
 			return
@@ -330,61 +432,75 @@ var clearTasksCmd = &cobra.Command{
 }
 
 var deleteTaskCmd = &cobra.Command{
-	Use:   "rm",
-	Short: "Delete a task in a tasklist",
+	Use:   "rm [task...]",
+	Short: "Delete tasks in a tasklist",
 	Long: `
-	Use this command to delete a task in a tasklist
-	for the currently signed in account
+	Use this command to delete tasks in a tasklist
+	for the currently signed in account.
+	Deleting a task also deletes its subtasks.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		srv, err := api.GetService()
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
 		if err != nil {
 			utils.ErrorP("Failed to get service: %v\n", err)
 			return
 		}
-		tList := getTaskLists(srv)
+		tList := getTaskLists(ctx, backend)
 		tID := tList.Id
 
-		tasks, err := api.GetTasks(srv, tID, false, 0)
+		taskItems, err := api.GetTasks(ctx, backend, tID, false, 0)
 		if err != nil {
 			color.Red(err.Error())
 			return
 		}
 
-		ind := getTaskIndex(args, tasks, tList.Title)
-		t := tasks[ind]
+		utils.Sort(taskItems, "position")
+		selected := selectTasks(args, bulkFlags.allMatching, bulkFlags.filter, taskItems, tList.Title)
 
-		err = api.DeleteTask(srv, t.Id, tID)
-		if err != nil {
-			color.Red("Unable to delete task: %v", err)
-			return
+		// Subtasks are removed along with their parent, so don't delete them twice
+		chosen := make(map[string]bool)
+		for _, t := range selected {
+			chosen[t.Id] = true
+		}
+		var toDelete []*tasks.Task
+		for _, t := range selected {
+			if t.Parent != "" && chosen[t.Parent] {
+				continue
+			}
+			toDelete = append(toDelete, t)
 		}
-		utils.Info("Deleted: %s\n", t.Title)
+
+		runOnTasks(ctx, toDelete, "Deleted", true, func(ctx context.Context, t *tasks.Task) error {
+			return api.DeleteTask(ctx, backend, t.Id, tID)
+		})
 	},
 }
 
 var infoTaskCmd = &cobra.Command{
-	Use:   "info [task-number]",
+	Use:   "info [task]",
 	Short: "View detailed information about a task",
 	Long: `
 	Use this command to view detailed information about a task
 	including links, notes, and other metadata.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		srv, err := api.GetService()
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
 		if err != nil {
 			utils.ErrorP("Failed to get service: %v\n", err)
 			return
 		}
-		tList := getTaskLists(srv)
+		tList := getTaskLists(ctx, backend)
 		tID := tList.Id
 
-		tasks, err := api.GetTasks(srv, tID, infoTaskFlags.includeCompleted, 0)
+		tasks, err := api.GetTasks(ctx, backend, tID, infoTaskFlags.includeCompleted, 0)
 		if err != nil {
 			color.Red(err.Error())
 			return
 		}
 
+		utils.Sort(tasks, "position")
 		ind := getTaskIndex(args, tasks, tList.Title)
 		t := tasks[ind]
 
@@ -438,121 +554,355 @@ var infoTaskCmd = &cobra.Command{
 }
 
 var updateTaskCmd = &cobra.Command{
-	Use:   "update [task-number]",
-	Short: "Update an existing task",
+	Use:   "update [task...]",
+	Short: "Update existing tasks",
 	Long: `
-	Use this command to update an existing task in a tasklist.
+	Use this command to update existing tasks in a tasklist.
 	
 	Interactive mode (no flags): prompts for each field showing current values.
 	Press Enter to keep the current value, or type a new value.
 	
 	Flag mode: only update fields that are explicitly provided.
+	When several tasks are selected, the same changes apply to all of them.
+
+	If a task was changed elsewhere (e.g. in the phone app) after
+	it was loaded, the differences are shown and you can merge
+	both versions, overwrite the server's version or abort.
+	Use --on-conflict to choose without being asked.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := validConflictMode(updateTaskFlags.onConflict); err != nil {
+			utils.ErrorP("%v\n", err)
+			return
+		}
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
+		if err != nil {
+			utils.ErrorP("Failed to get service: %v\n", err)
+			return
+		}
+		tList := getTaskLists(ctx, backend)
+		tID := tList.Id
+
+		taskItems, err := api.GetTasks(ctx, backend, tID, false, 0)
+		if err != nil {
+			color.Red(err.Error())
+			return
+		}
+
+		utils.Sort(taskItems, "position")
+		selected := selectTasks(args, bulkFlags.allMatching, bulkFlags.filter, taskItems, tList.Title)
+
+		reader := bufio.NewReader(os.Stdin)
+
+		runOnTasks(ctx, selected, "Updated", false, func(ctx context.Context, t *tasks.Task) error {
+			return editTask(ctx, cmd, backend, tID, t, reader)
+		})
+	},
+}
+
+// editTask applies the update flags to t, or prompts for each field when no
+// flags were given, and saves the result.
+func editTask(ctx context.Context, cmd *cobra.Command, backend api.Backend, tID string, t *tasks.Task, reader *bufio.Reader) error {
+	utils.Info("Updating task: %s\n\n", t.Title)
+	base := *t
+
+	// Check if any flags were provided
+	titleFlagSet := cmd.Flags().Changed("title")
+	noteFlagSet := cmd.Flags().Changed("note")
+	dueFlagSet := cmd.Flags().Changed("due")
+	flagMode := titleFlagSet || noteFlagSet || dueFlagSet
+
+	var newTitle, newNote, newDue string
+
+	if flagMode {
+		// Flag mode: only update fields that were explicitly set
+		if titleFlagSet {
+			newTitle = updateTaskFlags.title
+		} else {
+			newTitle = t.Title
+		}
+		if noteFlagSet {
+			newNote = updateTaskFlags.note
+		} else {
+			newNote = t.Notes
+		}
+		if dueFlagSet {
+			newDue = updateTaskFlags.due
+		}
+	} else {
+		// Interactive mode: prompt for each field
+		// Title
+		currentTitle := t.Title
+		utils.Print("Title [%s]: ", currentTitle)
+		newTitle = getInput(reader)
+		if newTitle == "" {
+			newTitle = currentTitle
+		}
+
+		// Note
+		currentNote := t.Notes
+		if currentNote == "" {
+			utils.Print("Note []: ")
+		} else {
+			utils.Print("Note [%s]: ", currentNote)
+		}
+		newNote = getInput(reader)
+		if newNote == "" {
+			newNote = currentNote
+		}
+
+		// Due date
+		currentDue := formatDueHuman(t.Due)
+		if currentDue == "-" {
+			utils.Print("Due []: ")
+		} else {
+			utils.Print("Due [%s]: ", currentDue)
+		}
+		newDue = getInput(reader)
+	}
+
+	// Apply changes
+	t.Title = newTitle
+	t.Notes = newNote
+
+	// Parse and set due date if provided
+	if newDue != "" {
+		parsedDue, err := dateparse.ParseAny(newDue)
+		if err != nil {
+			return fmt.Errorf("date format incorrect. Valid examples: https://github.com/araddon/dateparse#extended-example")
+		}
+		t.Due = parsedDue.Format(time.RFC3339)
+	} else if !flagMode && newDue == "" {
+		// Keep existing due date in interactive mode when user presses Enter
+	} else if flagMode && dueFlagSet && newDue == "" {
+		// Clear due date if --due="" was explicitly set
+		t.Due = ""
+		t.NullFields = append(t.NullFields, "Due")
+	}
+
+	return saveTask(ctx, backend, tID, &base, t, updateTaskFlags.onConflict, reader)
+}
+
+var indentTaskCmd = &cobra.Command{
+	Use:   "indent [task]",
+	Short: "Make a task a subtask of the task above it",
+	Long: `
+	Use this command to turn a task into a subtask of the
+	nearest top-level task above it. The task is placed
+	after that task's existing subtasks.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
+		if err != nil {
+			utils.ErrorP("Failed to get service: %v\n", err)
+			return
+		}
+		tList := getTaskLists(ctx, backend)
+		tID := tList.Id
+
+		taskItems, err := api.GetTasks(ctx, backend, tID, false, 0)
+		if err != nil {
+			color.Red(err.Error())
+			return
+		}
+
+		utils.Sort(taskItems, "position")
+		ind := getTaskIndex(args, taskItems, tList.Title)
+		t := taskItems[ind]
+
+		if t.Parent != "" {
+			utils.ErrorP("Task is already a subtask: %s\n", t.Title)
+			return
+		}
+		for _, other := range taskItems {
+			if other.Parent == t.Id {
+				utils.ErrorP("Task has subtasks and cannot be indented: %s\n", t.Title)
+				return
+			}
+		}
+
+		// The new parent is the closest top-level task above; the last row
+		// before the task is either that parent or its last subtask.
+		var parent *tasks.Task
+		for i := ind - 1; i >= 0; i-- {
+			if taskItems[i].Parent == "" {
+				parent = taskItems[i]
+				break
+			}
+		}
+		if parent == nil {
+			utils.ErrorP("No task above to indent under\n")
+			return
+		}
+		previous := ""
+		if prev := taskItems[ind-1]; prev.Parent == parent.Id {
+			previous = prev.Id
+		}
+
+		_, err = api.MoveTask(ctx, backend, tID, t.Id, parent.Id, previous)
+		if err != nil {
+			color.Red("Unable to indent task: %v", err)
+			return
+		}
+		utils.Info("Moved %s under %s\n", t.Title, parent.Title)
+	},
+}
+
+var outdentTaskCmd = &cobra.Command{
+	Use:   "outdent [task]",
+	Short: "Move a subtask out to the top level",
+	Long: `
+	Use this command to turn a subtask back into a top-level
+	task. It is placed directly after its former parent.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		srv, err := api.GetService()
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
 		if err != nil {
 			utils.ErrorP("Failed to get service: %v\n", err)
 			return
 		}
-		tList := getTaskLists(srv)
+		tList := getTaskLists(ctx, backend)
 		tID := tList.Id
 
-		taskItems, err := api.GetTasks(srv, tID, false, 0)
+		taskItems, err := api.GetTasks(ctx, backend, tID, false, 0)
 		if err != nil {
 			color.Red(err.Error())
 			return
 		}
 
+		utils.Sort(taskItems, "position")
 		ind := getTaskIndex(args, taskItems, tList.Title)
 		t := taskItems[ind]
 
-		utils.Info("Updating task: %s\n\n", t.Title)
+		if t.Parent == "" {
+			utils.ErrorP("Task is not a subtask: %s\n", t.Title)
+			return
+		}
+
+		_, err = api.MoveTask(ctx, backend, tID, t.Id, "", t.Parent)
+		if err != nil {
+			color.Red("Unable to outdent task: %v", err)
+			return
+		}
+		utils.Info("Moved to top level: %s\n", t.Title)
+	},
+}
+
+var moveTaskCmd = &cobra.Command{
+	Use:   "move [task]",
+	Short: "Reorder a task or move it to another tasklist",
+	Long: `
+	Use this command to change the position of a task.
+	Exactly one of the following flags is required:
 
-		// Check if any flags were provided
-		titleFlagSet := cmd.Flags().Changed("title")
-		noteFlagSet := cmd.Flags().Changed("note")
-		dueFlagSet := cmd.Flags().Changed("due")
-		flagMode := titleFlagSet || noteFlagSet || dueFlagSet
+	  --before <n>      place the task before task <n>
+	  --after <n>       place the task after task <n>
+	  --top             make the task the first of its siblings
+	  --bottom          make the task the last of its siblings
+	  --to-list <name>  move the task (and its subtasks) to another tasklist
 
-		var newTitle, newNote, newDue string
+	Placing a task before or after a subtask makes it a subtask
+	of the same parent.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		backend, err := newBackend(ctx)
+		if err != nil {
+			utils.ErrorP("Failed to get service: %v\n", err)
+			return
+		}
+		tList := getTaskLists(ctx, backend)
+		tID := tList.Id
 
-		if flagMode {
-			// Flag mode: only update fields that were explicitly set
-			if titleFlagSet {
-				newTitle = updateTaskFlags.title
-			} else {
-				newTitle = t.Title
-			}
-			if noteFlagSet {
-				newNote = updateTaskFlags.note
-			} else {
-				newNote = t.Notes
+		taskItems, err := api.GetTasks(ctx, backend, tID, false, 0)
+		if err != nil {
+			color.Red(err.Error())
+			return
+		}
+
+		utils.Sort(taskItems, "position")
+		ind := getTaskIndex(args, taskItems, tList.Title)
+		t := taskItems[ind]
+
+		if moveTaskFlags.toList != "" {
+			dest := findTaskList(ctx, backend, moveTaskFlags.toList)
+			if dest.Id == tID {
+				utils.ErrorP("Task is already in %s\n", dest.Title)
+				return
 			}
-			if dueFlagSet {
-				newDue = updateTaskFlags.due
+			_, err = api.MoveTaskToList(ctx, backend, tID, t.Id, dest.Id)
+			if err != nil {
+				color.Red("Unable to move task: %v", err)
+				return
 			}
-		} else {
-			// Interactive mode: prompt for each field
-			reader := bufio.NewReader(os.Stdin)
+			utils.Info("Moved %s to %s\n", t.Title, dest.Title)
+			return
+		}
 
-			// Title
-			currentTitle := t.Title
-			utils.Print("Title [%s]: ", currentTitle)
-			newTitle = getInput(reader)
-			if newTitle == "" {
-				newTitle = currentTitle
+		var parent, previous string
+		switch {
+		case moveTaskFlags.top:
+			parent = t.Parent
+		case moveTaskFlags.bottom:
+			parent = t.Parent
+			for _, sibling := range siblingTasks(taskItems, parent, t) {
+				previous = sibling.Id
 			}
-
-			// Note
-			currentNote := t.Notes
-			if currentNote == "" {
-				utils.Print("Note []: ")
-			} else {
-				utils.Print("Note [%s]: ", currentNote)
+		default:
+			refArg := moveTaskFlags.after
+			if moveTaskFlags.before != "" {
+				refArg = moveTaskFlags.before
 			}
-			newNote = getInput(reader)
-			if newNote == "" {
-				newNote = currentNote
+			ref := taskItems[getTaskIndex([]string{refArg}, taskItems, tList.Title)]
+			if ref.Id == t.Id || ref.Parent == t.Id {
+				utils.ErrorP("Cannot move a task relative to itself or its subtasks\n")
+				return
 			}
-
-			// Due date
-			currentDue := formatDueHuman(t.Due)
-			if currentDue == "-" {
-				utils.Print("Due []: ")
+			parent = ref.Parent
+			if moveTaskFlags.after != "" {
+				previous = ref.Id
 			} else {
-				utils.Print("Due [%s]: ", currentDue)
+				for _, sibling := range siblingTasks(taskItems, parent, t) {
+					if sibling.Id == ref.Id {
+						break
+					}
+					previous = sibling.Id
+				}
 			}
-			newDue = getInput(reader)
 		}
 
-		// Apply changes
-		t.Title = newTitle
-		t.Notes = newNote
-
-		// Parse and set due date if provided
-		if newDue != "" {
-			parsedDue, err := dateparse.ParseAny(newDue)
-			if err != nil {
-				utils.ErrorP("Date format incorrect. Valid examples: https://github.com/araddon/dateparse#extended-example\n")
-				return
+		if parent != "" && parent != t.Parent {
+			for _, other := range taskItems {
+				if other.Parent == t.Id {
+					utils.ErrorP("Task has subtasks and cannot become a subtask: %s\n", t.Title)
+					return
+				}
 			}
-			t.Due = parsedDue.Format(time.RFC3339)
-		} else if !flagMode && newDue == "" {
-			// Keep existing due date in interactive mode when user presses Enter
-		} else if flagMode && dueFlagSet && newDue == "" {
-			// Clear due date if --due="" was explicitly set
-			t.Due = ""
 		}
 
-		_, err = api.UpdateTask(srv, t, tID)
+		_, err = api.MoveTask(ctx, backend, tID, t.Id, parent, previous)
 		if err != nil {
-			color.Red("Unable to update task: %v", err)
+			color.Red("Unable to move task: %v", err)
 			return
 		}
-		utils.Info("\nUpdated: %s\n", t.Title)
+		utils.Info("Moved: %s\n", t.Title)
 	},
 }
 
+// siblingTasks returns the tasks under parent in display order, excluding skip.
+func siblingTasks(taskList []*tasks.Task, parent string, skip *tasks.Task) []*tasks.Task {
+	var siblings []*tasks.Task
+	for _, t := range taskList {
+		if t.Parent == parent && t.Id != skip.Id {
+			siblings = append(siblings, t)
+		}
+	}
+	return siblings
+}
+
 var (
 	viewTasksFlags struct {
 		includeCompleted bool
@@ -560,6 +910,8 @@ var (
 		sort             string
 		format           string
 		max              int
+		showIDs          bool
+		filter           string
 	}
 	taskListFlag string
 	addTaskFlags struct {
@@ -569,55 +921,117 @@ var (
 		repeat      string
 		repeatCount int
 		repeatUntil string
+		rrule       string
+		keepPartial bool
+		rolling     bool
+		parent      string
 	}
 	clearTasksFlags struct {
 		force bool
 	}
 	updateTaskFlags struct {
-		title string
-		note  string
-		due   string
+		title      string
+		note       string
+		due        string
+		onConflict string
 	}
 	infoTaskFlags struct {
 		includeCompleted bool
 	}
+	bulkFlags struct {
+		allMatching string
+		filter      string
+	}
+	moveTaskFlags struct {
+		before string
+		after  string
+		top    bool
+		bottom bool
+		toList string
+	}
 )
 
 func init() {
 	createTaskCmd.Flags().StringVarP(&addTaskFlags.title, "title", "t", "", "use this flag to set a tasks title")
 	createTaskCmd.Flags().StringVarP(&addTaskFlags.note, "note", "n", "", "use this flag to set a tasks note")
 	createTaskCmd.Flags().StringVarP(&addTaskFlags.due, "due", "d", "", "due date (e.g., '2024-12-25', 'Dec 25', 'tomorrow')")
-	createTaskCmd.Flags().StringVarP(&addTaskFlags.repeat, "repeat", "r", "", "repeat pattern: daily, weekly, monthly, yearly")
+	createTaskCmd.Flags().StringVarP(&addTaskFlags.repeat, "repeat", "r", "", "repeat pattern: daily, weekly, monthly, yearly, weekdays, \"every 2 weeks on mon,thu\", \"last friday of month\"")
+	createTaskCmd.Flags().StringVar(&addTaskFlags.rrule, "rrule", "", "RFC 5545 recurrence rule, e.g. \"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE,FR\"")
 	createTaskCmd.Flags().IntVar(&addTaskFlags.repeatCount, "repeat-count", 0, "number of occurrences for repeating task")
 	createTaskCmd.Flags().StringVar(&addTaskFlags.repeatUntil, "repeat-until", "", "end date for repeating task (e.g., '2025-03-01')")
+	createTaskCmd.Flags().BoolVar(&addTaskFlags.keepPartial, "keep-partial", false, "keep the recurring tasks already created if creating one of them fails")
+	createTaskCmd.Flags().BoolVar(&addTaskFlags.rolling, "rolling", false, "create only the first occurrence and add the next one each time it is completed")
+	createTaskCmd.Flags().StringVarP(&addTaskFlags.parent, "parent", "p", "", "parent task (number, ID or title:<text>) to create a subtask under")
 	viewTasksCmd.Flags().BoolVarP(&viewTasksFlags.includeCompleted, "include-completed", "i", false, "use this flag to include completed tasks")
 	viewTasksCmd.Flags().BoolVar(&viewTasksFlags.onlyCompleted, "completed", false, "use this flag to only show completed tasks")
 	viewTasksCmd.Flags().StringVar(&viewTasksFlags.sort, "sort", "position", "use this flag to sort by [due,title,position]")
-	viewTasksCmd.Flags().StringVar(&viewTasksFlags.format, "format", "table", "output format: table, json, csv")
+	viewTasksCmd.Flags().StringVar(&viewTasksFlags.format, "format", "table", "output format: table, json, csv, ics")
+	viewTasksCmd.Flags().BoolVar(&viewTasksFlags.showIDs, "ids", false, "show task IDs (short unique prefixes in table output)")
 	viewTasksCmd.Flags().IntVar(&viewTasksFlags.max, "max", 0, "maximum number of tasks to return (0 = all)")
+	viewTasksCmd.Flags().StringVar(&viewTasksFlags.filter, "filter", "", filterFlagUsage)
 	clearTasksCmd.Flags().BoolVarP(&clearTasksFlags.force, "force", "f", false, "skip confirmation prompt")
 	updateTaskCmd.Flags().StringVarP(&updateTaskFlags.title, "title", "t", "", "new title for the task")
 	updateTaskCmd.Flags().StringVarP(&updateTaskFlags.note, "note", "n", "", "new note for the task")
 	updateTaskCmd.Flags().StringVarP(&updateTaskFlags.due, "due", "d", "", "new due date for the task")
+	updateTaskCmd.Flags().StringVar(&updateTaskFlags.onConflict, "on-conflict", "", "if the task changed on the server meanwhile: merge, overwrite or abort (default: ask)")
+	for _, c := range []*cobra.Command{markCompletedCmd, undoTaskCmd, deleteTaskCmd, updateTaskCmd} {
+		c.Flags().StringVar(&bulkFlags.allMatching, "all-matching", "", "act on every task whose title or notes contain this text")
+		c.Flags().StringVar(&bulkFlags.filter, "filter", "", "act on every task matching this filter expression")
+	}
 	infoTaskCmd.Flags().BoolVarP(&infoTaskFlags.includeCompleted, "include-completed", "i", false, "include completed tasks when selecting by number")
+	moveTaskCmd.Flags().StringVar(&moveTaskFlags.before, "before", "", "task (number, ID or title:<text>) to place the task before")
+	moveTaskCmd.Flags().StringVar(&moveTaskFlags.after, "after", "", "task (number, ID or title:<text>) to place the task after")
+	moveTaskCmd.Flags().BoolVar(&moveTaskFlags.top, "top", false, "move the task to the top of its siblings")
+	moveTaskCmd.Flags().BoolVar(&moveTaskFlags.bottom, "bottom", false, "move the task to the bottom of its siblings")
+	moveTaskCmd.Flags().StringVar(&moveTaskFlags.toList, "to-list", "", "name of the tasklist to move the task to")
+	moveTaskCmd.MarkFlagsMutuallyExclusive("before", "after", "top", "bottom", "to-list")
+	moveTaskCmd.MarkFlagsOneRequired("before", "after", "top", "bottom", "to-list")
 	tasksCmd.PersistentFlags().StringVarP(&taskListFlag, "tasklist", "l", "", "use this flag to specify a tasklist")
-	tasksCmd.AddCommand(viewTasksCmd, createTaskCmd, markCompletedCmd, undoTaskCmd, deleteTaskCmd, clearTasksCmd, infoTaskCmd, updateTaskCmd)
+	tasksCmd.AddCommand(viewTasksCmd, createTaskCmd, markCompletedCmd, undoTaskCmd, deleteTaskCmd, clearTasksCmd, infoTaskCmd, updateTaskCmd, indentTaskCmd, outdentTaskCmd, moveTaskCmd)
 	rootCmd.AddCommand(tasksCmd)
 }
 
 type TaskOutput struct {
-	Number      int    `json:"number"`
-	Title       string `json:"title"`
-	Description string `json:"description,omitempty"`
-	Status      string `json:"status"`
-	Due         string `json:"due,omitempty"`
+	Number      int          `json:"number"`
+	ID          string       `json:"id,omitempty"`
+	Title       string       `json:"title"`
+	Description string       `json:"description,omitempty"`
+	Status      string       `json:"status"`
+	Due         string       `json:"due,omitempty"`
+	Children    []TaskOutput `json:"children,omitempty"`
+}
+
+// subtaskPrefix marks subtasks in tables and selection prompts.
+const subtaskPrefix = "  └ "
+
+// subtaskSet returns the IDs of tasks whose parent is also present in the slice.
+// Those tasks are rendered indented beneath their parent.
+func subtaskSet(taskList []*tasks.Task) map[string]bool {
+	present := make(map[string]bool, len(taskList))
+	for _, t := range taskList {
+		present[t.Id] = true
+	}
+	subtasks := make(map[string]bool)
+	for _, t := range taskList {
+		if t.Parent != "" && present[t.Parent] {
+			subtasks[t.Id] = true
+		}
+	}
+	return subtasks
 }
 
-func outputTable(tasks []*tasks.Task, listTitle string) {
+func outputTable(tasks []*tasks.Task, listTitle string, showIDs bool) {
 	utils.Print("Tasks in %s:\n", listTitle)
 
+	header := []string{"No", "Title", "Description", "Status", "Due"}
+	var ids map[string]string
+	if showIDs {
+		header = append([]string{"No", "ID"}, header[1:]...)
+		ids = shortIDs(tasks)
+	}
+
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"No", "Title", "Description", "Status", "Due"})
+	table.SetHeader(header)
 	table.SetAlignment(tablewriter.ALIGN_LEFT)
 	table.SetBorder(false)
 	table.SetCenterSeparator("|")
@@ -625,14 +1039,22 @@ func outputTable(tasks []*tasks.Task, listTitle string) {
 	table.SetRowSeparator("-")
 	table.SetAutoWrapText(false)
 
+	subtasks := subtaskSet(tasks)
 	for ind, task := range tasks {
+		taskTitle := truncate(task.Title, 30)
+		if subtasks[task.Id] {
+			taskTitle = subtaskPrefix + truncate(task.Title, 30-len([]rune(subtaskPrefix)))
+		}
 		row := []string{
 			fmt.Sprintf("%d", ind+1),
-			truncate(task.Title, 30),
+			taskTitle,
 			truncate(task.Notes, 40),
 			statusLabel(task.Status),
 			formatDueHuman(task.Due),
 		}
+		if showIDs {
+			row = append([]string{row[0], ids[task.Id]}, row[1:]...)
+		}
 		table.Append(row)
 	}
 
@@ -643,27 +1065,41 @@ func truncate(s string, maxLen int) string {
 	// Replace newlines with spaces for single-line display
 	s = strings.ReplaceAll(s, "\n", " ")
 	s = strings.ReplaceAll(s, "\r", "")
-	
+
 	if len(s) <= maxLen {
 		return s
 	}
+	// Cut between runes so multi-byte characters are not split.
 	if maxLen <= 3 {
-		return s[:maxLen]
+		return s[:runeStart(s, maxLen)]
 	}
-	return s[:maxLen-3] + "..."
+	return s[:runeStart(s, maxLen-3)] + "..."
 }
 
-func outputJSON(tasks []*tasks.Task) {
+func outputJSON(tasks []*tasks.Task, showIDs bool) {
 	var output []TaskOutput
 
+	// Tasks are in tree order, so a parent is always emitted before its subtasks
+	subtasks := subtaskSet(tasks)
+	rootIndex := make(map[string]int)
 	for ind, task := range tasks {
-		output = append(output, TaskOutput{
+		out := TaskOutput{
 			Number:      ind + 1,
 			Title:       task.Title,
 			Description: task.Notes,
 			Status:      statusLabel(task.Status),
 			Due:         formatDueISO(task.Due),
-		})
+		}
+		if showIDs {
+			out.ID = task.Id
+		}
+		if subtasks[task.Id] {
+			parent := rootIndex[task.Parent]
+			output[parent].Children = append(output[parent].Children, out)
+			continue
+		}
+		rootIndex[task.Id] = len(output)
+		output = append(output, out)
 	}
 
 	encoder := json.NewEncoder(os.Stdout)
@@ -671,20 +1107,38 @@ func outputJSON(tasks []*tasks.Task) {
 	_ = encoder.Encode(output)
 }
 
-func outputCSV(tasks []*tasks.Task) {
+func outputCSV(tasks []*tasks.Task, showIDs bool) {
 	writer := csv.NewWriter(os.Stdout)
 	defer writer.Flush()
 
-	_ = writer.Write([]string{"No", "Title", "Description", "Status", "Due"})
+	header := []string{"No", "Title", "Description", "Status", "Due", "Parent"}
+	if showIDs {
+		header = append(header, "ID")
+	}
+	_ = writer.Write(header)
 
+	numbers := make(map[string]int)
 	for ind, task := range tasks {
-		_ = writer.Write([]string{
+		numbers[task.Id] = ind + 1
+	}
+
+	for ind, task := range tasks {
+		parent := ""
+		if n, ok := numbers[task.Parent]; ok && task.Parent != "" {
+			parent = fmt.Sprintf("%d", n)
+		}
+		row := []string{
 			fmt.Sprintf("%d", ind+1),
 			task.Title,
 			task.Notes,
 			statusLabel(task.Status),
 			formatDueHuman(task.Due),
-		})
+			parent,
+		}
+		if showIDs {
+			row = append(row, task.Id)
+		}
+		_ = writer.Write(row)
 	}
 }
 
@@ -733,21 +1187,23 @@ func getInput(reader *bufio.Reader) string {
 
 func getTaskIndex(args []string, tasks []*tasks.Task, title string) int {
 	var taskIndex int
-	argProvided := false
 	if len(args) == 1 {
-		argProvided = true
-
-		index, err := strconv.Atoi(args[0])
-		if err != nil || index > len(tasks) || index < 1 {
-			utils.ErrorP("%s", "Incorrect task number\n")
+		index, err := resolveTaskRef(args[0], tasks)
+		if err != nil {
+			utils.ErrorP("%v\n", err)
 		}
 
 		taskIndex = index
 	} else {
 		utils.Print("Tasks in %s:\n", title)
 
+		subtasks := subtaskSet(tasks)
 		tString := []string{}
 		for _, i := range tasks {
+			if subtasks[i.Id] {
+				tString = append(tString, subtaskPrefix+i.Title)
+				continue
+			}
 			tString = append(tString, i.Title)
 		}
 
@@ -764,17 +1220,28 @@ func getTaskIndex(args []string, tasks []*tasks.Task, title string) int {
 		taskIndex = option
 	}
 
-	if argProvided {
-		taskIndex--
-	}
-
 	return taskIndex
 }
 
-func getTaskLists(srv *tasks.Service) tasks.TaskList {
-	list, err := api.GetTaskLists(srv)
+// findTaskList returns the tasklist with the given title, exiting if there is none.
+func findTaskList(ctx context.Context, backend api.Backend, name string) tasks.TaskList {
+	list, err := api.GetTaskLists(ctx, backend)
+	if err != nil {
+		utils.ErrorP("Error %v\n", err)
+	}
+	for _, tl := range list {
+		if tl.Title == name {
+			return tl
+		}
+	}
+	utils.ErrorP("%s '%s'\n", "incorrect task-list name", name)
+	return tasks.TaskList{}
+}
+
+func getTaskLists(ctx context.Context, backend api.Backend) tasks.TaskList {
+	list, err := api.GetTaskLists(ctx, backend)
 	if err != nil {
-		utils.ErrorP("Error %v", err)
+		utils.ErrorP("Error %v\n", err)
 	}
 
 	sort.SliceStable(list, func(i, j int) bool {
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/BRO3886/gtasks/api"
+	"github.com/BRO3886/gtasks/internal/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/api/tasks/v1"
+)
+
+// exitCode is the panic value of utils.Exit while a test command runs.
+type exitCode int
+
+// newMemoryTasks returns a MemoryBackend with a list "Test" holding the
+// given tasks in order, and the list's ID. A title starting with "  " is a
+// subtask of the task before it, one starting with "x:" is completed.
+func newMemoryTasks(t *testing.T, titles ...string) (*api.MemoryBackend, string) {
+	t.Helper()
+	ctx := context.Background()
+	m := api.NewMemoryBackend()
+	tl, err := m.InsertTaskList(ctx, &tasks.TaskList{Title: "Test"})
+	if err != nil {
+		t.Fatalf("InsertTaskList: %v", err)
+	}
+	var parent, previous, previousChild string
+	for _, title := range titles {
+		opts := api.MoveOptions{Previous: previous}
+		if sub, ok := strings.CutPrefix(title, "  "); ok {
+			title = sub
+			opts = api.MoveOptions{Parent: parent, Previous: previousChild}
+		}
+		task := &tasks.Task{Title: title}
+		if done, ok := strings.CutPrefix(title, "x:"); ok {
+			task = &tasks.Task{Title: done, Status: "completed"}
+		}
+		created, err := m.InsertTask(ctx, tl.Id, task, opts)
+		if err != nil {
+			t.Fatalf("InsertTask: %v", err)
+		}
+		if opts.Parent != "" {
+			previousChild = created.Id
+		} else {
+			parent, previous, previousChild = created.Id, created.Id, ""
+		}
+	}
+	return m, tl.Id
+}
+
+// runTasks runs "gtasks tasks args..." against m and returns the exit code
+// the command ended with.
+func runTasks(t *testing.T, m *api.MemoryBackend, args ...string) (code int) {
+	t.Helper()
+	newBackend = func(context.Context) (api.Backend, error) { return m, nil }
+	utils.Exit = func(code int) { panic(exitCode(code)) }
+	t.Cleanup(func() {
+		newBackend = openBackend
+		utils.Exit = os.Exit
+		resetFlags(rootCmd)
+	})
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("GTASKS_DEFAULT_TASKLIST", "")
+
+	defer func() {
+		if r := recover(); r != nil {
+			c, ok := r.(exitCode)
+			if !ok {
+				panic(r)
+			}
+			code = int(c)
+			// PersistentPostRun did not run to collect the update check.
+			select {
+			case <-updateResultCh:
+			default:
+			}
+		}
+	}()
+	rootCmd.SetArgs(append([]string{"tasks", "-l", "Test"}, args...))
+	if err := rootCmd.ExecuteContext(context.Background()); err != nil {
+		t.Logf("%v", err)
+		return 1
+	}
+	return 0
+}
+
+// resetFlags puts every flag of c and its subcommands back to its default,
+// so that one test run does not leak into the next.
+func resetFlags(c *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if s, ok := f.Value.(pflag.SliceValue); ok {
+			s.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	c.Flags().VisitAll(reset)
+	c.PersistentFlags().VisitAll(reset)
+	for _, sub := range c.Commands() {
+		resetFlags(sub)
+	}
+}
+
+// listState returns the tasks of a list in display order, completed ones
+// marked with "x:" and subtasks indented with ">".
+func listState(t *testing.T, m *api.MemoryBackend, listID string) string {
+	t.Helper()
+	items, err := api.GetTasks(context.Background(), m, listID, true, 0)
+	if err != nil {
+		t.Fatalf("GetTasks: %v", err)
+	}
+	utils.Sort(items, "position")
+	var out []string
+	for _, task := range items {
+		s := task.Title
+		if task.Status == "completed" {
+			s = "x:" + s
+		}
+		if task.Parent != "" {
+			s = ">" + s
+		}
+		out = append(out, s)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestTasksCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		tasks []string
+		args  []string
+		code  int
+		want  string
+	}{
+		{"done several with a range", []string{"a", "b", "c", "d", "e"}, []string{"done", "1", "3-4"}, 0, "x:a b x:c x:d e"},
+		{"done by title", []string{"a", "b", "c"}, []string{"done", "title:b"}, 0, "a x:b c"},
+		{"done with a filter", []string{"pay rent", "call", "pay bills"}, []string{"done", "--filter", `title~"pay"`}, 0, "x:pay rent call x:pay bills"},
+		{"done out of range", []string{"a", "b"}, []string{"done", "1-3"}, 1, "a b"},
+		{"done references and filter", []string{"a", "b"}, []string{"done", "1", "--filter", "title~a"}, 1, "a b"},
+		{"undo counts completed tasks only", []string{"x:a", "b", "x:c"}, []string{"undo", "2"}, 0, "x:a b c"},
+		{"rm skips subtasks of removed parents", []string{"a", "  a1", "b", "c"}, []string{"rm", "1-3"}, 0, "c"},
+		{"move after", []string{"a", "b", "c", "d"}, []string{"move", "1", "--after", "3"}, 0, "b c a d"},
+		{"move before", []string{"a", "b", "c", "d"}, []string{"move", "4", "--before", "2"}, 0, "a d b c"},
+		{"move to top", []string{"a", "b", "c"}, []string{"move", "3", "--top"}, 0, "c a b"},
+		{"move to bottom", []string{"a", "b", "c"}, []string{"move", "1", "--bottom"}, 0, "b c a"},
+		{"move after a subtask", []string{"a", "  a1", "b"}, []string{"move", "3", "--after", "2"}, 0, "a >a1 >b"},
+		{"move subtask to top level", []string{"a", "  a1", "b"}, []string{"move", "2", "--after", "3"}, 0, "a b a1"},
+		{"move after itself", []string{"a", "b"}, []string{"move", "1", "--after", "1"}, 1, "a b"},
+		{"move parent under another", []string{"a", "  a1", "b", "  b1"}, []string{"move", "1", "--after", "4"}, 1, "a >a1 b >b1"},
+		{"move needs a position", []string{"a", "b"}, []string{"move", "1"}, 1, "a b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, list := newMemoryTasks(t, tt.tasks...)
+			if code := runTasks(t, m, tt.args...); code != tt.code {
+				t.Errorf("exit code %d, want %d", code, tt.code)
+			}
+			if got := listState(t, m, list); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
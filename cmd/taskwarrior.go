@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/BRO3886/gtasks/internal/taskwarrior"
+	"google.golang.org/api/tasks/v1"
+)
+
+// TaskwarriorOutput is a task in the JSON read by Taskwarrior's 'task
+// import'.
+type TaskwarriorOutput struct {
+	UUID        string                   `json:"uuid"`
+	Description string                   `json:"description"`
+	Status      string                   `json:"status"`
+	Entry       string                   `json:"entry"`
+	Modified    string                   `json:"modified,omitempty"`
+	Due         string                   `json:"due,omitempty"`
+	End         string                   `json:"end,omitempty"`
+	Project     string                   `json:"project,omitempty"`
+	Priority    string                   `json:"priority,omitempty"`
+	Tags        []string                 `json:"tags,omitempty"`
+	Annotations []taskwarrior.Annotation `json:"annotations,omitempty"`
+}
+
+// taskwarriorOutput converts a task of the list titled project. Fields that
+// an earlier import from Taskwarrior kept in the notes are restored from
+// there; the other lines of the notes become annotations. A task without a
+// recorded creation time is taken to be created when it was last updated.
+func taskwarriorOutput(t *tasks.Task, project string) TaskwarriorOutput {
+	extra := taskwarrior.ParseNotes(stripSeriesMarker(t.Notes))
+	updated, _ := time.Parse(time.RFC3339, t.Updated)
+
+	out := TaskwarriorOutput{
+		UUID:        extra.UUID,
+		Description: t.Title,
+		Status:      "pending",
+		Entry:       taskwarrior.FormatTime(extra.Entry),
+		Modified:    taskwarrior.FormatTime(updated),
+		Project:     project,
+		Priority:    extra.Priority,
+		Tags:        extra.Tags,
+	}
+	if out.UUID == "" {
+		out.UUID = taskwarrior.UUIDFor(t.Id)
+	}
+	if out.Entry == "" {
+		out.Entry = out.Modified
+	}
+	if due, ok := dueDate(t.Due, time.Local); ok {
+		out.Due = taskwarrior.FormatTime(due)
+	}
+	if t.Status == "completed" {
+		out.Status = "completed"
+		out.End = out.Modified
+		if t.Completed != nil {
+			if completed, err := time.Parse(time.RFC3339, *t.Completed); err == nil {
+				out.End = taskwarrior.FormatTime(completed)
+			}
+		}
+	}
+	for _, a := range extra.Annotations {
+		out.Annotations = append(out.Annotations, taskwarrior.Annotation{Entry: out.Entry, Description: a})
+	}
+	return out
+}
+
+// exportTaskwarrior writes the tasks of all lists as a JSON array for 'task
+// import', with each list as the project of its tasks. Taskwarrior has no
+// subtasks, so subtasks are written as tasks of their own.
+func exportTaskwarrior(w io.Writer, lists []tasks.TaskList, items [][]*tasks.Task) error {
+	output := []TaskwarriorOutput{}
+	for i, tl := range lists {
+		for _, t := range items[i] {
+			output = append(output, taskwarriorOutput(t, tl.Title))
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
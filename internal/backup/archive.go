@@ -0,0 +1,65 @@
+// Package backup reads and writes the archives made by 'gtasks backup': every
+// tasklist of an account with all the fields the API returns for its tasks,
+// so that they can be restored with their hierarchy and order.
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+// Format identifies gtasks backup archives.
+const Format = "gtasks-backup"
+
+// Version is the archive version written by this package. Read accepts
+// archives up to this version.
+const Version = 1
+
+// Archive is the content of a backup.
+type Archive struct {
+	Format  string    `json:"format"`
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	Lists   []List    `json:"tasklists"`
+}
+
+// List is a tasklist and all its tasks as returned by the API, including
+// hidden and deleted ones where they were available.
+type List struct {
+	ID      string        `json:"id"`
+	Title   string        `json:"title"`
+	Updated string        `json:"updated,omitempty"`
+	Tasks   []*tasks.Task `json:"tasks"`
+}
+
+// New returns an empty archive of the current version.
+func New() *Archive {
+	return &Archive{Format: Format, Version: Version, Created: time.Now().UTC().Truncate(time.Second)}
+}
+
+// Write writes a as indented JSON.
+func Write(w io.Writer, a *Archive) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(a)
+}
+
+// Read reads an archive, checking that it is one and that its version is
+// supported.
+func Read(r io.Reader) (*Archive, error) {
+	var a Archive
+	if err := json.NewDecoder(r).Decode(&a); err != nil {
+		return nil, fmt.Errorf("not a gtasks backup: %v", err)
+	}
+	if a.Format != Format {
+		return nil, fmt.Errorf("not a gtasks backup (format %q)", a.Format)
+	}
+	if a.Version < 1 || a.Version > Version {
+		return nil, fmt.Errorf("unsupported backup version %d (this gtasks reads up to version %d)", a.Version, Version)
+	}
+	return &a, nil
+}
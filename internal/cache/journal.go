@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/BRO3886/gtasks/internal/config"
+	"google.golang.org/api/tasks/v1"
+)
+
+const journalFileName = "journal.json"
+
+// LocalIDPrefix starts the IDs given to tasks created offline until they are
+// pushed and receive a real ID.
+const LocalIDPrefix = "local-"
+
+// JournalPath returns the location of the offline journal in the gtasks
+// config directory. Unlike the cache it holds changes that exist nowhere
+// else, so it is kept in a separate file.
+func JournalPath() string {
+	return filepath.Join(config.GetInstallLocation(), journalFileName)
+}
+
+// OpKind is the kind of change recorded in the journal.
+type OpKind string
+
+const (
+	OpCreate OpKind = "create"
+	OpPatch  OpKind = "patch"
+	OpDelete OpKind = "delete"
+	OpMove   OpKind = "move"
+)
+
+// Op is a change made while offline, waiting to be sent to Google Tasks.
+type Op struct {
+	ID     int    `json:"id"`
+	Kind   OpKind `json:"kind"`
+	ListID string `json:"list_id"`
+	// TaskID is the affected task; for OpCreate it is the local ID given to
+	// the new task.
+	TaskID string `json:"task_id"`
+	// Title is the task's title when the change was made, for reporting.
+	Title string `json:"title,omitempty"`
+	// Task is the payload of OpCreate and OpPatch.
+	Task *tasks.Task `json:"task,omitempty"`
+	// NullFields and ForceSendFields carry the matching tasks.Task fields of
+	// OpPatch, which are not serialised with the task.
+	NullFields      []string `json:"null_fields,omitempty"`
+	ForceSendFields []string `json:"force_send_fields,omitempty"`
+	// Parent, Previous and DestinationList position OpCreate and OpMove.
+	Parent          string `json:"parent,omitempty"`
+	Previous        string `json:"previous,omitempty"`
+	DestinationList string `json:"destination_list,omitempty"`
+	// ETag is the task's ETag when the change was made, and Base the task
+	// itself. Replay refuses to apply the change if the task has changed on
+	// the server since; Base tells real edits from ETag changes caused only
+	// by reordering.
+	ETag     string      `json:"etag,omitempty"`
+	Base     *tasks.Task `json:"base,omitempty"`
+	QueuedAt time.Time   `json:"queued_at"`
+}
+
+type journalData struct {
+	Version int   `json:"version"`
+	NextID  int   `json:"next_id"`
+	Ops     []*Op `json:"ops"`
+}
+
+// Journal is the list of changes made offline. Every change is written to
+// disk immediately. It is safe for concurrent use.
+type Journal struct {
+	mu   sync.Mutex
+	path string
+	data journalData
+}
+
+// OpenJournal loads the journal at path; a missing file yields an empty one.
+func OpenJournal(path string) (*Journal, error) {
+	j := &Journal{path: path, data: journalData{Version: storeVersion, NextID: 1}}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read offline journal: %v", err)
+	}
+	if err := json.Unmarshal(raw, &j.data); err != nil {
+		return nil, fmt.Errorf("unable to parse offline journal %s: %v", path, err)
+	}
+	return j, nil
+}
+
+func (j *Journal) save() error {
+	raw, err := json.MarshalIndent(j.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o755); err != nil {
+		return err
+	}
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.path)
+}
+
+// localSeq tells apart local IDs made at the same instant by tasks created
+// in parallel.
+var localSeq atomic.Int64
+
+// NewLocalID returns an ID for a task created offline.
+func NewLocalID() string {
+	return LocalIDPrefix + strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatInt(localSeq.Add(1), 36)
+}
+
+// Append records a change and saves the journal.
+func (j *Journal) Append(op *Op) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	c := *op
+	c.ID = j.data.NextID
+	if c.QueuedAt.IsZero() {
+		c.QueuedAt = time.Now()
+	}
+	j.data.NextID++
+	j.data.Ops = append(j.data.Ops, &c)
+	return j.save()
+}
+
+// Ops returns the queued changes in the order they were made.
+func (j *Journal) Ops() []*Op {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	out := make([]*Op, 0, len(j.data.Ops))
+	for _, op := range j.data.Ops {
+		c := *op
+		out = append(out, &c)
+	}
+	return out
+}
+
+// Len returns the number of queued changes.
+func (j *Journal) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.data.Ops)
+}
+
+// Remove drops the changes with the given IDs and saves the journal.
+func (j *Journal) Remove(ids ...int) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	drop := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
+	kept := j.data.Ops[:0]
+	for _, op := range j.data.Ops {
+		if !drop[op.ID] {
+			kept = append(kept, op)
+		}
+	}
+	j.data.Ops = kept
+	return j.save()
+}
+
+// DropLocalTask forgets every queued change to a task that was created
+// offline, so deleting it before it is pushed leaves nothing to replay.
+// Subtasks created under it are dropped as well. Creates and moves placed
+// after a dropped task are placed where it was instead.
+func (j *Journal) DropLocalTask(taskID string) error {
+	if !IsLocalID(taskID) {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	gone := map[string]bool{taskID: true}
+	// previous holds the task each dropped task was last placed after.
+	previous := make(map[string]string)
+	kept := j.data.Ops[:0]
+	for _, op := range j.data.Ops {
+		if op.Kind == OpCreate && gone[op.Parent] {
+			gone[op.TaskID] = true
+		}
+		if gone[op.Previous] {
+			op.Previous = previous[op.Previous]
+		}
+		if !gone[op.TaskID] {
+			kept = append(kept, op)
+		} else if op.Kind == OpCreate || op.Kind == OpMove {
+			previous[op.TaskID] = op.Previous
+		}
+	}
+	j.data.Ops = kept
+	return j.save()
+}
+
+// IsLocalID reports whether id belongs to a task created offline.
+func IsLocalID(id string) bool {
+	return strings.HasPrefix(id, LocalIDPrefix)
+}
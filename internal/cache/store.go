@@ -0,0 +1,391 @@
+// Package cache keeps a local copy of task lists and tasks so that gtasks can
+// show them without network access.
+//
+// The store is a single JSON file under the gtasks config directory. Task
+// lists and tasks are kept exactly as the API returned them, including their
+// ETags and updated timestamps, together with the time they were fetched.
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/BRO3886/gtasks/internal/config"
+	"google.golang.org/api/tasks/v1"
+)
+
+// storeVersion is bumped whenever the file layout changes incompatibly.
+// Files written with another version are discarded.
+const storeVersion = 1
+
+const fileName = "cache.json"
+
+// Path returns the location of the store in the gtasks config directory.
+func Path() string {
+	return filepath.Join(config.GetInstallLocation(), fileName)
+}
+
+// List is a cached task list together with its tasks.
+type List struct {
+	TaskList *tasks.TaskList `json:"tasklist"`
+	Tasks    []*tasks.Task   `json:"tasks,omitempty"`
+	// FetchedAt is when Tasks were last refreshed; zero if never.
+	FetchedAt time.Time `json:"fetched_at,omitempty"`
+	// FullSyncAt is when every task of the list was last fetched.
+	FullSyncAt time.Time `json:"full_sync_at,omitempty"`
+	// SyncedUpdated is the latest Task.Updated seen while syncing. Changes
+	// made at or after it are requested on the next incremental sync.
+	SyncedUpdated string `json:"synced_updated,omitempty"`
+}
+
+type storeData struct {
+	Version        int       `json:"version"`
+	ListsFetchedAt time.Time `json:"lists_fetched_at,omitempty"`
+	Lists          []*List   `json:"lists"`
+}
+
+// Store is the on-disk cache. It is safe for concurrent use.
+type Store struct {
+	mu    sync.Mutex
+	path  string
+	data  storeData
+	dirty bool
+}
+
+// Open loads the store at path. A missing file, or one written by an
+// incompatible version, yields an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, data: storeData{Version: storeVersion}}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("unable to read cache: %v", err)
+	}
+
+	var data storeData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return s, fmt.Errorf("unable to parse cache %s: %v", path, err)
+	}
+	if data.Version == storeVersion {
+		s.data = data
+	}
+	return s, nil
+}
+
+// Save writes the store back to disk if it changed since it was opened.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+	raw, err := json.Marshal(s.data)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	// Write to a temporary file first so an interrupted save never leaves a
+	// truncated cache behind.
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+func (s *Store) list(id string) *List {
+	for _, l := range s.data.Lists {
+		if l.TaskList.Id == id {
+			return l
+		}
+	}
+	return nil
+}
+
+// TaskLists returns the cached task lists and when they were fetched.
+// The time is zero if the lists were never fetched.
+func (s *Store) TaskLists() ([]*tasks.TaskList, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]*tasks.TaskList, 0, len(s.data.Lists))
+	for _, l := range s.data.Lists {
+		c := *l.TaskList
+		out = append(out, &c)
+	}
+	return out, s.data.ListsFetchedAt
+}
+
+// SetTaskLists replaces the cached task lists. Tasks of lists that still
+// exist are kept.
+func (s *Store) SetTaskLists(lists []*tasks.TaskList, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated := make([]*List, 0, len(lists))
+	for _, tl := range lists {
+		c := *tl
+		l := s.list(tl.Id)
+		if l == nil {
+			l = &List{}
+		}
+		l.TaskList = &c
+		updated = append(updated, l)
+	}
+	s.data.Lists = updated
+	s.data.ListsFetchedAt = at
+	s.dirty = true
+}
+
+// PutTaskList adds or replaces a single task list.
+func (s *Store) PutTaskList(tl *tasks.TaskList) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := *tl
+	if l := s.list(tl.Id); l != nil {
+		l.TaskList = &c
+	} else {
+		s.data.Lists = append(s.data.Lists, &List{TaskList: &c})
+	}
+	s.dirty = true
+}
+
+// RemoveTaskList drops a task list and its tasks.
+func (s *Store) RemoveTaskList(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, l := range s.data.Lists {
+		if l.TaskList.Id == id {
+			s.data.Lists = append(s.data.Lists[:i], s.data.Lists[i+1:]...)
+			s.dirty = true
+			return
+		}
+	}
+}
+
+// Tasks returns copies of the cached tasks of a list and when they were
+// fetched. ok is false if the list's tasks were never fetched.
+func (s *Store) Tasks(listID string) (items []*tasks.Task, fetchedAt time.Time, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.list(listID)
+	if l == nil || l.FetchedAt.IsZero() {
+		return nil, time.Time{}, false
+	}
+	items = make([]*tasks.Task, 0, len(l.Tasks))
+	for _, t := range l.Tasks {
+		items = append(items, copyTask(t))
+	}
+	return items, l.FetchedAt, true
+}
+
+// Task returns a copy of a single cached task.
+func (s *Store) Task(listID, taskID string) (*tasks.Task, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if l := s.list(listID); l != nil {
+		for _, t := range l.Tasks {
+			if t.Id == taskID {
+				return copyTask(t), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// SyncState returns the sync cursor of a list and when it was last fully
+// fetched. The cursor is empty if the list's tasks were never fetched.
+func (s *Store) SyncState(listID string) (cursor string, fullSyncAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.list(listID)
+	if l == nil || l.FetchedAt.IsZero() {
+		return "", time.Time{}
+	}
+	return l.SyncedUpdated, l.FullSyncAt
+}
+
+// ResetSync makes the next sync of a list fetch every task again.
+func (s *Store) ResetSync(listID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if l := s.list(listID); l != nil {
+		l.FullSyncAt = time.Time{}
+		s.dirty = true
+	}
+}
+
+// SetTasks replaces all cached tasks of a list with a complete fetch.
+func (s *Store) SetTasks(listID string, items []*tasks.Task, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.list(listID)
+	if l == nil {
+		l = &List{TaskList: &tasks.TaskList{Id: listID}}
+		s.data.Lists = append(s.data.Lists, l)
+	}
+	l.Tasks = make([]*tasks.Task, 0, len(items))
+	l.SyncedUpdated = ""
+	for _, t := range items {
+		l.Tasks = append(l.Tasks, copyTask(t))
+		l.SyncedUpdated = laterTimestamp(l.SyncedUpdated, t.Updated)
+	}
+	l.FetchedAt = at
+	l.FullSyncAt = at
+	s.dirty = true
+}
+
+// MergeTasks applies tasks changed since the last sync to a list whose tasks
+// are cached: deleted tasks are dropped, others added or replaced. The sync
+// cursor advances to the latest Updated among them.
+func (s *Store) MergeTasks(listID string, changed []*tasks.Task, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.list(listID)
+	if l == nil || l.FetchedAt.IsZero() {
+		return
+	}
+
+	index := make(map[string]int, len(l.Tasks))
+	for i, t := range l.Tasks {
+		index[t.Id] = i
+	}
+	removed := make(map[string]bool)
+	for _, t := range changed {
+		l.SyncedUpdated = laterTimestamp(l.SyncedUpdated, t.Updated)
+		if t.Deleted {
+			removed[t.Id] = true
+			continue
+		}
+		if i, ok := index[t.Id]; ok {
+			l.Tasks[i] = copyTask(t)
+		} else {
+			index[t.Id] = len(l.Tasks)
+			l.Tasks = append(l.Tasks, copyTask(t))
+		}
+	}
+	if len(removed) > 0 {
+		kept := l.Tasks[:0]
+		for _, t := range l.Tasks {
+			if !removed[t.Id] {
+				kept = append(kept, t)
+			}
+		}
+		l.Tasks = kept
+	}
+	l.FetchedAt = at
+	s.dirty = true
+}
+
+// laterTimestamp returns whichever RFC 3339 timestamp is later. Empty or
+// unparseable values lose.
+func laterTimestamp(a, b string) string {
+	ta, errA := time.Parse(time.RFC3339, a)
+	tb, errB := time.Parse(time.RFC3339, b)
+	switch {
+	case errB != nil:
+		return a
+	case errA != nil:
+		return b
+	case tb.After(ta):
+		return b
+	default:
+		return a
+	}
+}
+
+// PutTask adds or replaces a single task in a list whose tasks are cached.
+// Lists that were never fetched are left alone so that a partial list is
+// never mistaken for a complete one.
+func (s *Store) PutTask(listID string, t *tasks.Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.list(listID)
+	if l == nil || l.FetchedAt.IsZero() {
+		return
+	}
+	for i, existing := range l.Tasks {
+		if existing.Id == t.Id {
+			l.Tasks[i] = copyTask(t)
+			s.dirty = true
+			return
+		}
+	}
+	l.Tasks = append(l.Tasks, copyTask(t))
+	s.dirty = true
+}
+
+// RemoveTask drops a task and its subtasks from a list.
+func (s *Store) RemoveTask(listID, taskID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.list(listID)
+	if l == nil {
+		return
+	}
+	kept := l.Tasks[:0]
+	for _, t := range l.Tasks {
+		if t.Id != taskID && t.Parent != taskID {
+			kept = append(kept, t)
+		}
+	}
+	l.Tasks = kept
+	s.dirty = true
+}
+
+// HideCompleted marks every completed task in a list as hidden, mirroring
+// tasks.clear.
+func (s *Store) HideCompleted(listID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.list(listID)
+	if l == nil {
+		return
+	}
+	for _, t := range l.Tasks {
+		if t.Status == "completed" {
+			t.Hidden = true
+		}
+	}
+	s.dirty = true
+}
+
+func copyTask(t *tasks.Task) *tasks.Task {
+	c := *t
+	if t.Completed != nil {
+		completed := *t.Completed
+		c.Completed = &completed
+	}
+	c.Links = nil
+	for _, l := range t.Links {
+		link := *l
+		c.Links = append(c.Links, &link)
+	}
+	return &c
+}
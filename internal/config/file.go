@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/knadh/koanf/parsers/json"
 	"github.com/knadh/koanf/parsers/toml"
@@ -61,6 +63,14 @@ func LoadAppConfig() {
 			return "credentials.client_secret"
 		case "default_tasklist":
 			return "tasks.default_task_list"
+		case "api_endpoint":
+			return "api.endpoint"
+		case "api_max_retries":
+			return "api.max_retries"
+		case "api_timeout":
+			return "api.timeout"
+		case "api_concurrency":
+			return "api.concurrency"
 		}
 		return "" // skip unrecognized GTASKS_* vars
 	}), nil)
@@ -71,6 +81,54 @@ func GetDefaultTaskList() string {
 	return k.String("tasks.default_task_list")
 }
 
+// GetAPIEndpoint returns an override for the Google Tasks API base URL, or empty string.
+// It is intended for pointing gtasks at a local stand-in such as internal/taskstest.
+func GetAPIEndpoint() string {
+	return k.String("api.endpoint")
+}
+
+// Defaults for the [api] settings.
+const (
+	DefaultAPIMaxRetries  = 3
+	DefaultAPITimeout     = 60 * time.Second
+	DefaultAPIConcurrency = 4
+)
+
+// GetAPIMaxRetries returns how many times a failed API request is retried.
+func GetAPIMaxRetries() int {
+	if !k.Exists("api.max_retries") {
+		return DefaultAPIMaxRetries
+	}
+	return max(k.Int("api.max_retries"), 0)
+}
+
+// GetAPIConcurrency returns how many requests bulk operations send at once.
+func GetAPIConcurrency() int {
+	if n := k.Int("api.concurrency"); n > 0 {
+		return n
+	}
+	return DefaultAPIConcurrency
+}
+
+// GetAPITimeout returns the time limit for a single API call, retries
+// included. The setting is a duration such as "30s" or a number of seconds;
+// 0 disables the limit.
+func GetAPITimeout() time.Duration {
+	if !k.Exists("api.timeout") {
+		return DefaultAPITimeout
+	}
+	raw := k.String("api.timeout")
+	if secs, err := strconv.ParseFloat(raw, 64); err == nil {
+		return max(time.Duration(secs*float64(time.Second)), 0)
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		utils.Warn("Ignoring invalid api.timeout %q: %v\n", raw, err)
+		return DefaultAPITimeout
+	}
+	return max(d, 0)
+}
+
 // GetCredentials returns client ID and secret from config/env.
 func GetCredentials() (clientID, clientSecret string) {
 	return k.String("credentials.client_id"), k.String("credentials.client_secret")
@@ -0,0 +1,209 @@
+// Package filter implements the expression language used by --filter to
+// select tasks, for example:
+//
+//	due<=+7d and status=pending and title~"invoice"
+//
+// Expressions are parsed once with Parse into an Expr tree that can then be
+// matched against any number of tasks.
+package filter
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+const (
+	statusPending   = "needsAction"
+	statusCompleted = "completed"
+)
+
+// Expr is a node of a parsed filter expression.
+type Expr interface {
+	// Match reports whether t satisfies the expression.
+	Match(t *tasks.Task) bool
+}
+
+// And matches tasks matching both Left and Right.
+type And struct{ Left, Right Expr }
+
+// Or matches tasks matching either Left or Right.
+type Or struct{ Left, Right Expr }
+
+// Not matches tasks that X does not match.
+type Not struct{ X Expr }
+
+// Has matches tasks where Field is set.
+type Has struct{ Field string }
+
+// Compare matches tasks where Field compares to Value using Op, one of
+// = != < <= > >= ~ (contains) and !~ (does not contain).
+type Compare struct {
+	Field string
+	Op    string
+	Value string
+
+	date time.Time
+}
+
+func (e *And) Match(t *tasks.Task) bool { return e.Left.Match(t) && e.Right.Match(t) }
+
+func (e *Or) Match(t *tasks.Task) bool { return e.Left.Match(t) || e.Right.Match(t) }
+
+func (e *Not) Match(t *tasks.Task) bool { return !e.X.Match(t) }
+
+func (e *Has) Match(t *tasks.Task) bool {
+	if e.Field == "links" {
+		return len(t.Links) > 0
+	}
+	f, _ := lookupField(e.Field)
+	return f.get(t) != ""
+}
+
+// Match compares the task's field with the value. Text comparisons ignore
+// case. Dates are compared by calendar day; a task without the date set only
+// matches !=.
+func (e *Compare) Match(t *tasks.Task) bool {
+	f, _ := lookupField(e.Field)
+	raw := f.get(t)
+
+	switch f.kind {
+	case kindDate:
+		d, ok := f.day(raw, e.date.Location())
+		if !ok {
+			return e.Op == "!="
+		}
+		switch e.Op {
+		case "=":
+			return d.Equal(e.date)
+		case "!=":
+			return !d.Equal(e.date)
+		case "<":
+			return d.Before(e.date)
+		case "<=":
+			return !d.After(e.date)
+		case ">":
+			return d.After(e.date)
+		case ">=":
+			return !d.Before(e.date)
+		}
+		return false
+	case kindStatus:
+		if e.Op == "!=" {
+			return raw != e.Value
+		}
+		return raw == e.Value
+	default:
+		value := strings.ToLower(e.Value)
+		raw = strings.ToLower(raw)
+		switch e.Op {
+		case "=":
+			return raw == value
+		case "!=":
+			return raw != value
+		case "~":
+			return strings.Contains(raw, value)
+		case "!~":
+			return !strings.Contains(raw, value)
+		}
+		return false
+	}
+}
+
+type fieldKind int
+
+const (
+	kindText fieldKind = iota
+	kindStatus
+	kindDate
+)
+
+type field struct {
+	name string
+	kind fieldKind
+	get  func(t *tasks.Task) string
+	// utc is set for date-only fields, which the API stores as midnight UTC.
+	utc bool
+}
+
+var fields = map[string]field{
+	"title":     {name: "title", kind: kindText, get: func(t *tasks.Task) string { return t.Title }},
+	"notes":     {name: "notes", kind: kindText, get: func(t *tasks.Task) string { return t.Notes }},
+	"parent":    {name: "parent", kind: kindText, get: func(t *tasks.Task) string { return t.Parent }},
+	"status":    {name: "status", kind: kindStatus, get: func(t *tasks.Task) string { return t.Status }},
+	"due":       {name: "due", kind: kindDate, utc: true, get: func(t *tasks.Task) string { return t.Due }},
+	"completed": {name: "completed", kind: kindDate, get: func(t *tasks.Task) string { return derefString(t.Completed) }},
+	"updated":   {name: "updated", kind: kindDate, get: func(t *tasks.Task) string { return t.Updated }},
+	// links only supports has:links.
+	"links": {name: "links", kind: kindText, get: func(t *tasks.Task) string { return "" }},
+}
+
+func lookupField(name string) (field, bool) {
+	f, ok := fields[strings.ToLower(name)]
+	return f, ok
+}
+
+func fieldNames() string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		if name != "links" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// day returns the calendar day of a timestamp field in loc.
+func (f field) day(raw string, loc *time.Location) (time.Time, bool) {
+	if raw == "" {
+		return time.Time{}, false
+	}
+	ts, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if f.utc {
+		ts = ts.UTC()
+	} else {
+		ts = ts.In(loc)
+	}
+	y, m, d := ts.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc), true
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// Uses reports whether the expression refers to any of the given fields.
+// Commands use it to decide whether completed tasks must be fetched.
+func Uses(e Expr, names ...string) bool {
+	switch e := e.(type) {
+	case *And:
+		return Uses(e.Left, names...) || Uses(e.Right, names...)
+	case *Or:
+		return Uses(e.Left, names...) || Uses(e.Right, names...)
+	case *Not:
+		return Uses(e.X, names...)
+	case *Has:
+		return containsFold(names, e.Field)
+	case *Compare:
+		return containsFold(names, e.Field)
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
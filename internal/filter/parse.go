@@ -0,0 +1,327 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/araddon/dateparse"
+)
+
+// Now returns the current time. Relative dates such as "today" or "+7d" are
+// resolved against it when an expression is parsed.
+var Now = time.Now
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// operators are matched longest first.
+var operators = []string{"<=", ">=", "!=", "!~", "=", "<", ">", "~"}
+
+func isWordRune(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune(`()"=!<>~`, r)
+}
+
+func lex(input string) ([]token, error) {
+	var toks []token
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case r == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case r == '"':
+			start := i
+			var b strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start+1)
+			}
+			i++
+			toks = append(toks, token{tokString, b.String(), start})
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					toks = append(toks, token{tokOp, op, i})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if matched {
+				continue
+			}
+			if r == '!' {
+				toks = append(toks, token{tokWord, "not", i})
+				i++
+				continue
+			}
+			start := i
+			for i < len(runes) && isWordRune(runes[i]) {
+				i++
+			}
+			toks = append(toks, token{tokWord, string(runes[start:i]), start})
+		}
+	}
+	return append(toks, token{tokEOF, "", len(runes)}), nil
+}
+
+type parser struct {
+	toks []token
+	pos  int
+	now  time.Time
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword reports whether the next token is the given case-insensitive word.
+func (p *parser) keyword(words ...string) bool {
+	t := p.peek()
+	if t.kind != tokWord {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse parses a filter expression such as
+//
+//	due<=+7d and status=pending and title~"invoice"
+//
+// Comparisons are combined with and, or and not (also written &&, || and !)
+// and may be grouped with parentheses. Adjacent terms without an operator
+// between them are joined with and.
+func Parse(input string) (Expr, error) {
+	toks, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks, now: Now()}
+	if p.peek().kind == tokEOF {
+		return nil, fmt.Errorf("empty filter")
+	}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos+1)
+	}
+	return e, nil
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or", "||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if p.keyword("and", "&&") {
+			p.next()
+		} else if t.kind == tokEOF || t.kind == tokRParen || p.keyword("or", "||") {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.keyword("not") {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{X: x}, nil
+	}
+
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, fmt.Errorf("missing ) for ( at position %d", t.pos+1)
+		}
+		return e, nil
+	case tokWord:
+		return p.parseTerm(t)
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of filter")
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos+1)
+	}
+}
+
+// parseTerm parses has:<field> or <field> <op> <value>.
+func (p *parser) parseTerm(t token) (Expr, error) {
+	name := strings.ToLower(t.text)
+	if strings.HasPrefix(name, "has:") {
+		f, ok := lookupField(strings.TrimPrefix(name, "has:"))
+		if !ok {
+			return nil, fmt.Errorf("unknown field in %q at position %d", t.text, t.pos+1)
+		}
+		return &Has{Field: f.name}, nil
+	}
+
+	f, ok := lookupField(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q at position %d (expected one of %s)", t.text, t.pos+1, fieldNames())
+	}
+	if f.name == "links" {
+		return nil, fmt.Errorf("links can only be used as has:links")
+	}
+	op := p.next()
+	if op.kind != tokOp {
+		return nil, fmt.Errorf("expected an operator after %q at position %d", t.text, op.pos+1)
+	}
+	val := p.next()
+	if val.kind != tokWord && val.kind != tokString {
+		return nil, fmt.Errorf("expected a value after %s%s at position %d", t.text, op.text, val.pos+1)
+	}
+
+	c := &Compare{Field: f.name, Op: op.text, Value: val.text}
+	switch f.kind {
+	case kindDate:
+		if op.text == "~" || op.text == "!~" {
+			return nil, fmt.Errorf("operator %s is not supported for %s", op.text, f.name)
+		}
+		d, err := parseDateValue(val.text, p.now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q for %s: %v", val.text, f.name, err)
+		}
+		c.date = d
+	case kindStatus:
+		if op.text != "=" && op.text != "!=" {
+			return nil, fmt.Errorf("operator %s is not supported for status", op.text)
+		}
+		s, ok := statusValues[strings.ToLower(val.text)]
+		if !ok {
+			return nil, fmt.Errorf("invalid status %q (expected pending or completed)", val.text)
+		}
+		c.Value = s
+	default:
+		switch op.text {
+		case "=", "!=", "~", "!~":
+		default:
+			return nil, fmt.Errorf("operator %s is not supported for %s", op.text, f.name)
+		}
+	}
+	return c, nil
+}
+
+var statusValues = map[string]string{
+	"pending":     statusPending,
+	"needsaction": statusPending,
+	"completed":   statusCompleted,
+	"done":        statusCompleted,
+}
+
+// parseDateValue resolves a date literal to a calendar day in now's location.
+//
+// Accepted forms are today, tomorrow and yesterday, offsets from today such as
+// +7d, -2w, +1m or +1y, and any absolute date understood by dateparse.
+func parseDateValue(s string, now time.Time) (time.Time, error) {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+
+	switch strings.ToLower(s) {
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+
+	if len(s) >= 3 && (s[0] == '+' || s[0] == '-') {
+		n, err := strconv.Atoi(s[1 : len(s)-1])
+		if err == nil {
+			if s[0] == '-' {
+				n = -n
+			}
+			switch unicode.ToLower(rune(s[len(s)-1])) {
+			case 'd':
+				return today.AddDate(0, 0, n), nil
+			case 'w':
+				return today.AddDate(0, 0, 7*n), nil
+			case 'm':
+				return today.AddDate(0, n, 0), nil
+			case 'y':
+				return today.AddDate(n, 0, 0), nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("expected an offset like +7d, -2w, +1m or +1y")
+	}
+
+	t, err := dateparse.ParseIn(s, now.Location())
+	if err != nil {
+		return time.Time{}, err
+	}
+	y, m, d = t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, now.Location()), nil
+}
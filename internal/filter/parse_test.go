@@ -0,0 +1,239 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+// show renders an expression with explicit grouping.
+func show(e Expr) string {
+	switch e := e.(type) {
+	case *And:
+		return "(and " + show(e.Left) + " " + show(e.Right) + ")"
+	case *Or:
+		return "(or " + show(e.Left) + " " + show(e.Right) + ")"
+	case *Not:
+		return "(not " + show(e.X) + ")"
+	case *Has:
+		return "has:" + e.Field
+	case *Compare:
+		return e.Field + e.Op + e.Value
+	}
+	return fmt.Sprintf("%T", e)
+}
+
+func TestParsePrecedence(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"title=a or title=b and title=c", "(or title=a (and title=b title=c))"},
+		{"title=a and title=b or title=c", "(or (and title=a title=b) title=c)"},
+		{"not title=a and title=b", "(and (not title=a) title=b)"},
+		{"not not title=a", "(not (not title=a))"},
+		{"!(title=a or title=b)", "(not (or title=a title=b))"},
+		{"title=a title=b or title=c", "(or (and title=a title=b) title=c)"},
+		{"(title=a or title=b) title=c", "(and (or title=a title=b) title=c)"},
+		{`title~"a or b" && (status=done || has:notes)`, "(and title~a or b (or status=completed has:notes))"},
+		{"NOT Title=a OR title!~b", "(or (not title=a) title!~b)"},
+		{"title=a or title=b or title=c", "(or (or title=a title=b) title=c)"},
+		{`notes="say \"hi\""`, `notes=say "hi"`},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if got := show(e); got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", "empty filter"},
+		{"   ", "empty filter"},
+		{"title=a)", `unexpected ")" at position 8`},
+		{"(title=a", "missing ) for ( at position 1"},
+		{"title=a and (title=b or (title=c)", "missing ) for ( at position 13"},
+		{"title=a and", "unexpected end of filter"},
+		{"foo=1", `unknown field "foo" at position 1`},
+		{"title=a or bar~x", `unknown field "bar" at position 12`},
+		{"title", `expected an operator after "title" at position 6`},
+		{"title=", "expected a value after title= at position 7"},
+		{`title="abc`, "unterminated string at position 7"},
+		{"has:foo", `unknown field in "has:foo" at position 1`},
+		{"links=x", "links can only be used as has:links"},
+		{"title<b", "operator < is not supported for title"},
+		{"due~today", "operator ~ is not supported for due"},
+		{"status<done", "operator < is not supported for status"},
+		{"status=maybe", `invalid status "maybe"`},
+		{"due<=+7x", `invalid date "+7x" for due`},
+		{"due<=soon", `invalid date "soon" for due`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.in)
+		if err == nil {
+			t.Errorf("Parse(%q) succeeded", tt.in)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q): got %q, want %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+// at pins Now to the given local time for the rest of the test.
+func at(t *testing.T, now time.Time) {
+	saved := Now
+	Now = func() time.Time { return now }
+	t.Cleanup(func() { Now = saved })
+}
+
+func TestMatchDates(t *testing.T) {
+	// Due dates are stored as midnight UTC and keep their date in every
+	// zone; completion times are compared by their local date.
+	task := &tasks.Task{
+		Due:       "2025-01-05T00:00:00.000Z",
+		Completed: ptr("2025-01-05T02:00:00.000Z"),
+	}
+	tests := []struct {
+		filter string
+		// east and west are the expected matches in UTC+9 early in the
+		// morning and in UTC-8 late in the evening.
+		east, west bool
+	}{
+		{"due=today", true, true},
+		{"due!=today", false, false},
+		{"due<tomorrow", true, true},
+		{"due>yesterday", true, true},
+		{"due=2025-01-05", true, true},
+		{"due<=+7d and due>=-7d", true, true},
+		{"due<-1d", false, false},
+		{"due>=+1w", false, false},
+		{"completed=today", true, false},
+		{"completed=yesterday", false, true},
+		{"updated=today", false, false},
+		{"updated!=today", true, true},
+	}
+	zones := []struct {
+		name  string
+		now   time.Time
+		match func(east, west bool) bool
+	}{
+		{"UTC+9", time.Date(2025, 1, 5, 1, 0, 0, 0, time.FixedZone("UTC+9", 9*60*60)), func(e, _ bool) bool { return e }},
+		{"UTC-8", time.Date(2025, 1, 5, 23, 0, 0, 0, time.FixedZone("UTC-8", -8*60*60)), func(_, w bool) bool { return w }},
+	}
+	for _, z := range zones {
+		t.Run(z.name, func(t *testing.T) {
+			at(t, z.now)
+			for _, tt := range tests {
+				e, err := Parse(tt.filter)
+				if err != nil {
+					t.Fatalf("Parse(%q): %v", tt.filter, err)
+				}
+				if got, want := e.Match(task), z.match(tt.east, tt.west); got != want {
+					t.Errorf("%s: got %v, want %v", tt.filter, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRelativeDates(t *testing.T) {
+	at(t, time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC))
+	tests := []struct {
+		value, want string
+	}{
+		{"today", "2025-01-31"},
+		{"Tomorrow", "2025-02-01"},
+		{"yesterday", "2025-01-30"},
+		{"+7d", "2025-02-07"},
+		{"-2w", "2025-01-17"},
+		{"+1m", "2025-03-03"},
+		{"-1y", "2024-01-31"},
+		{"+10D", "2025-02-10"},
+		{"Feb 3 2025", "2025-02-03"},
+	}
+	for _, tt := range tests {
+		e, err := Parse(`due="` + tt.value + `"`)
+		if err != nil {
+			t.Errorf("%s: %v", tt.value, err)
+			continue
+		}
+		if got := e.(*Compare).date.Format("2006-01-02"); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tasks := map[string]*tasks.Task{
+		"invoice": {Title: "Send Invoice", Notes: "to ACME", Status: "needsAction", Links: []*tasks.TaskLinks{{Type: "email", Link: "https://mail.google.com/x"}}},
+		"done":    {Title: "Call mom", Status: "completed", Parent: "p1"},
+		"bare":    {Title: "Water plants", Status: "needsAction"},
+	}
+	tests := []struct {
+		filter string
+		want   string
+	}{
+		{`title~invoice`, "invoice"},
+		{`title~"CALL"`, "done"},
+		{`title!~a`, "invoice"},
+		{`title="call mom"`, "done"},
+		{`notes~acme or status=done`, "done invoice"},
+		{`status=pending`, "bare invoice"},
+		{`status!=pending`, "done"},
+		{`has:links`, "invoice"},
+		{`not has:links`, "bare done"},
+		{`has:notes or has:parent`, "done invoice"},
+		{`due!=today`, "bare done invoice"},
+		{`due=today or due<today or due>today`, ""},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.filter)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.filter, err)
+		}
+		var got []string
+		for _, name := range []string{"bare", "done", "invoice"} {
+			if e.Match(tasks[name]) {
+				got = append(got, name)
+			}
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.filter, strings.Join(got, " "), tt.want)
+		}
+	}
+}
+
+func TestUses(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{"title~a", false},
+		{"Status=done", true},
+		{"title~a or not (completed>yesterday)", true},
+		{"has:completed", true},
+		{"title~a and has:notes", false},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.filter)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.filter, err)
+		}
+		if got := Uses(e, "status", "completed"); got != tt.want {
+			t.Errorf("Uses(%q) = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func ptr(s string) *string { return &s }
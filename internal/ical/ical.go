@@ -0,0 +1,220 @@
+// Package ical writes and reads the to-dos of iCalendar files (RFC 5545), and
+// writes all-day events for calendar apps that do not show to-dos:
+//
+//	BEGIN:VCALENDAR
+//	VERSION:2.0
+//	BEGIN:VTODO
+//	UID:MTIzNDU2Nzg5
+//	SUMMARY:Pay rent
+//	DUE;VALUE=DATE:20250105
+//	STATUS:NEEDS-ACTION
+//	END:VTODO
+//	END:VCALENDAR
+//
+// Only the properties that have a counterpart in Google Tasks are handled.
+// Tasks have due dates but no due times, so dates are written as DATE values.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ProdID identifies gtasks as the producer of the calendars it writes.
+const ProdID = "-//gtasks//gtasks//EN"
+
+// Status values of a to-do.
+const (
+	NeedsAction = "NEEDS-ACTION"
+	Completed   = "COMPLETED"
+	InProcess   = "IN-PROCESS"
+	Cancelled   = "CANCELLED"
+)
+
+// Todo is one VTODO.
+type Todo struct {
+	UID         string
+	Summary     string
+	Description string
+	// Start and Due are dates; zero if not set. Read returns DATE values
+	// as midnight UTC, and Write writes the date of the time as given.
+	Start  time.Time
+	Due    time.Time
+	Status string
+	// Completed is when the to-do was completed; zero if it was not.
+	Completed time.Time
+	// RelatedTo is the UID of the parent to-do.
+	RelatedTo  string
+	RRule      string
+	Categories []string
+	Modified   time.Time
+	// Line is where the to-do starts in the input, for messages.
+	Line int
+}
+
+// Done reports whether the to-do is completed.
+func (t *Todo) Done() bool {
+	return strings.EqualFold(t.Status, Completed) || !t.Completed.IsZero()
+}
+
+// Event is an all-day VEVENT.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Date        time.Time
+	RRule       string
+	Categories  []string
+	Modified    time.Time
+}
+
+// Calendar is a VCALENDAR of to-dos and events.
+type Calendar struct {
+	// Name is shown by calendar apps as the name of the calendar.
+	Name   string
+	Todos  []Todo
+	Events []Event
+	// Stamp is the DTSTAMP of every component; the current time if zero.
+	Stamp time.Time
+	// Refresh, if set, suggests to subscribers how often to fetch the
+	// calendar again.
+	Refresh time.Duration
+}
+
+// Write writes c as an iCalendar stream.
+func Write(w io.Writer, c *Calendar) error {
+	stamp := c.Stamp
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+	bw := bufio.NewWriter(w)
+	e := &encoder{w: bw}
+	e.line("BEGIN", "VCALENDAR")
+	e.line("VERSION", "2.0")
+	e.line("PRODID", ProdID)
+	e.line("CALSCALE", "GREGORIAN")
+	if c.Name != "" {
+		e.line("X-WR-CALNAME", escape(c.Name))
+	}
+	if c.Refresh > 0 {
+		e.line("REFRESH-INTERVAL;VALUE=DURATION", formatDuration(c.Refresh))
+		e.line("X-PUBLISHED-TTL", formatDuration(c.Refresh))
+	}
+	for _, t := range c.Todos {
+		e.line("BEGIN", "VTODO")
+		e.line("UID", escape(t.UID))
+		e.line("DTSTAMP", formatTime(stamp))
+		if !t.Modified.IsZero() {
+			e.line("LAST-MODIFIED", formatTime(t.Modified))
+		}
+		e.line("SUMMARY", escape(t.Summary))
+		if t.Description != "" {
+			e.line("DESCRIPTION", escape(t.Description))
+		}
+		if !t.Start.IsZero() {
+			e.line("DTSTART;VALUE=DATE", formatDate(t.Start))
+		}
+		if !t.Due.IsZero() {
+			e.line("DUE;VALUE=DATE", formatDate(t.Due))
+		}
+		if t.RRule != "" {
+			e.line("RRULE", t.RRule)
+		}
+		if t.Status != "" {
+			e.line("STATUS", t.Status)
+		}
+		if !t.Completed.IsZero() {
+			e.line("COMPLETED", formatTime(t.Completed))
+		}
+		if t.RelatedTo != "" {
+			e.line("RELATED-TO;RELTYPE=PARENT", escape(t.RelatedTo))
+		}
+		e.categories(t.Categories)
+		e.line("END", "VTODO")
+	}
+	for _, ev := range c.Events {
+		e.line("BEGIN", "VEVENT")
+		e.line("UID", escape(ev.UID))
+		e.line("DTSTAMP", formatTime(stamp))
+		if !ev.Modified.IsZero() {
+			e.line("LAST-MODIFIED", formatTime(ev.Modified))
+		}
+		e.line("SUMMARY", escape(ev.Summary))
+		if ev.Description != "" {
+			e.line("DESCRIPTION", escape(ev.Description))
+		}
+		e.line("DTSTART;VALUE=DATE", formatDate(ev.Date))
+		e.line("DTEND;VALUE=DATE", formatDate(ev.Date.AddDate(0, 0, 1)))
+		if ev.RRule != "" {
+			e.line("RRULE", ev.RRule)
+		}
+		e.line("TRANSP", "TRANSPARENT")
+		e.categories(ev.Categories)
+		e.line("END", "VEVENT")
+	}
+	e.line("END", "VCALENDAR")
+	if e.err != nil {
+		return e.err
+	}
+	return bw.Flush()
+}
+
+// encoder writes content lines, folded at 75 octets as RFC 5545 requires.
+type encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (e *encoder) line(name, value string) {
+	if e.err != nil {
+		return
+	}
+	s := name + ":" + value
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		size := len(string(r))
+		if n+size > 75 {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	b.WriteString("\r\n")
+	_, e.err = e.w.WriteString(b.String())
+}
+
+func (e *encoder) categories(cats []string) {
+	if len(cats) == 0 {
+		return
+	}
+	escaped := make([]string, len(cats))
+	for i, cat := range cats {
+		escaped[i] = escape(cat)
+	}
+	e.line("CATEGORIES", strings.Join(escaped, ","))
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// escape escapes a TEXT value.
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+func formatDate(t time.Time) string {
+	return t.Format("20060102")
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// formatDuration formats d as a DURATION value in whole minutes, at least one.
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("PT%dM", max(int((d+time.Minute-1)/time.Minute), 1))
+}
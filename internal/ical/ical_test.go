@@ -0,0 +1,151 @@
+package ical
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadDates(t *testing.T) {
+	berlin := time.FixedZone("CET", 60*60)
+	tests := []struct {
+		name  string
+		value string
+		want  time.Time
+	}{
+		{"date", "DUE;VALUE=DATE:20250105", time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"date without VALUE", "DUE:20250105", time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"UTC date-time", "DUE:20250105T093000Z", time.Date(2025, 1, 5, 9, 30, 0, 0, time.UTC)},
+		{"floating date-time", "DUE:20250105T093000", time.Date(2025, 1, 5, 9, 30, 0, 0, berlin)},
+		{"unknown TZID", "DUE;TZID=Nowhere/Special:20250105T093000", time.Date(2025, 1, 5, 9, 30, 0, 0, berlin)},
+	}
+
+	// Dates have no zone, so they must read the same in any local zone.
+	local := time.Local
+	time.Local = berlin
+	defer func() { time.Local = local }()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nSUMMARY:a\r\n" + tt.value + "\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+			todos, err := Read(strings.NewReader(in))
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if len(todos) != 1 {
+				t.Fatalf("got %d to-dos, want 1", len(todos))
+			}
+			if got := todos[0].Due; !got.Equal(tt.want) || got.Location().String() != tt.want.Location().String() {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"missing END", "BEGIN:VCALENDAR\nBEGIN:VTODO\nSUMMARY:a\nEND:VCALENDAR\n"},
+		{"END without BEGIN", "END:VTODO\n"},
+		{"bad date", "BEGIN:VCALENDAR\nBEGIN:VTODO\nDUE;VALUE=DATE:2025-01-05\nEND:VTODO\nEND:VCALENDAR\n"},
+		{"no colon", "BEGIN:VCALENDAR\nBEGIN:VTODO\nSUMMARY\nEND:VTODO\nEND:VCALENDAR\n"},
+		{"unterminated quote", "BEGIN:VCALENDAR\nBEGIN:VTODO\nDUE;TZID=\"Europe/Berlin:20250105T090000\nEND:VTODO\nEND:VCALENDAR\n"},
+	}
+	for _, tt := range tests {
+		if _, err := Read(strings.NewReader(tt.in)); err == nil {
+			t.Errorf("%s: got no error", tt.name)
+		}
+	}
+}
+
+func TestReadProperties(t *testing.T) {
+	in := "\ufeffBEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nSUMMARY:skipped\r\nEND:VEVENT\r\n" +
+		"BEGIN:VTODO\r\n" +
+		"UID:child\r\n" +
+		"SUMMARY:Buy milk\\, eggs\\; and bread\r\n" +
+		"DESCRIPTION:line one\\nline two with a long text that is folded onto the n\r\n" +
+		" ext line\r\n" +
+		"RELATED-TO;RELTYPE=PARENT:parent\r\n" +
+		"CATEGORIES:Home,Shop\\,ping\r\n" +
+		"PERCENT-COMPLETE:100\r\n" +
+		"X-UNKNOWN;X-PARAM=\"a:b;c\":ignored\r\n" +
+		"BEGIN:VALARM\r\nACTION:DISPLAY\r\nSUMMARY:not the title\r\nEND:VALARM\r\n" +
+		"END:VTODO\r\n" +
+		"END:VCALENDAR\r\n"
+	todos, err := Read(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(todos) != 1 {
+		t.Fatalf("got %d to-dos, want 1", len(todos))
+	}
+	got := todos[0]
+	want := Todo{
+		UID:         "child",
+		Summary:     "Buy milk, eggs; and bread",
+		Description: "line one\nline two with a long text that is folded onto the next line",
+		Status:      Completed,
+		RelatedTo:   "parent",
+		Categories:  []string{"Home", "Shop,ping"},
+		Line:        5,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+	if !got.Done() {
+		t.Errorf("PERCENT-COMPLETE:100 is not done")
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	todos := []Todo{
+		{
+			UID:         "MTIz",
+			Summary:     "Pay rent; call the bank, then the landlord \\ and write it all down somewhere",
+			Description: "first line\nsecond line: ünïcödé text that will need folding at seventy-five octets",
+			Due:         time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC),
+			Start:       time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC),
+			Status:      NeedsAction,
+			RRule:       "FREQ=MONTHLY;BYMONTHDAY=5",
+			Categories:  []string{"Home", "Bills, and more"},
+			Modified:    time.Date(2025, 1, 4, 18, 12, 45, 0, time.UTC),
+		},
+		{
+			UID:       "NDU2",
+			Summary:   "Done",
+			Status:    Completed,
+			Completed: time.Date(2025, 1, 3, 8, 0, 0, 0, time.UTC),
+			RelatedTo: "MTIz",
+		},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, &Calendar{Name: "Home", Todos: todos, Refresh: 5 * time.Minute}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+	if !strings.Contains(buf.String(), "REFRESH-INTERVAL;VALUE=DURATION:PT5M\r\n") {
+		t.Errorf("no refresh interval in\n%s", buf.String())
+	}
+
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(got) != len(todos) {
+		t.Fatalf("got %d to-dos, want %d", len(got), len(todos))
+	}
+	for i := range todos {
+		got[i].Line = 0
+		if !reflect.DeepEqual(got[i], todos[i]) {
+			t.Errorf("to-do %d:\ngot  %+v\nwant %+v", i, got[i], todos[i])
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Read returns the to-dos of every calendar in an iCalendar stream, in the
+// order they appear. Other components, such as events and the alarms of a
+// to-do, are skipped, and so are properties this package does not know.
+func Read(r io.Reader) ([]Todo, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var todos []Todo
+	var stack []string
+	var cur *Todo
+	for _, l := range lines {
+		p, err := parseLine(l.text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", l.num, err)
+		}
+		switch p.name {
+		case "BEGIN":
+			stack = append(stack, strings.ToUpper(p.value))
+			if len(stack) == 2 && stack[0] == "VCALENDAR" && stack[1] == "VTODO" {
+				cur = &Todo{Line: l.num}
+			}
+			continue
+		case "END":
+			if len(stack) == 0 || !strings.EqualFold(stack[len(stack)-1], p.value) {
+				return nil, fmt.Errorf("line %d: END:%s without BEGIN", l.num, p.value)
+			}
+			if len(stack) == 2 && cur != nil {
+				todos = append(todos, *cur)
+				cur = nil
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		if cur == nil || len(stack) != 2 {
+			continue
+		}
+		if err := cur.set(p); err != nil {
+			return nil, fmt.Errorf("line %d: %s: %v", l.num, p.name, err)
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("missing END:%s", stack[len(stack)-1])
+	}
+	return todos, nil
+}
+
+// set sets the field of t that property p holds.
+func (t *Todo) set(p property) error {
+	var err error
+	switch p.name {
+	case "UID":
+		t.UID = unescape(p.value)
+	case "SUMMARY":
+		t.Summary = unescape(p.value)
+	case "DESCRIPTION":
+		t.Description = unescape(p.value)
+	case "DTSTART":
+		t.Start, err = parseDateTime(p)
+	case "DUE":
+		t.Due, err = parseDateTime(p)
+	case "STATUS":
+		t.Status = strings.ToUpper(p.value)
+	case "COMPLETED":
+		t.Completed, err = parseDateTime(p)
+	case "PERCENT-COMPLETE":
+		if strings.TrimSpace(p.value) == "100" && t.Status == "" {
+			t.Status = Completed
+		}
+	case "RELATED-TO":
+		if rel := p.params["RELTYPE"]; rel == "" || strings.EqualFold(rel, "PARENT") {
+			t.RelatedTo = unescape(p.value)
+		}
+	case "RRULE":
+		t.RRule = p.value
+	case "CATEGORIES":
+		for _, cat := range splitList(p.value) {
+			t.Categories = append(t.Categories, unescape(cat))
+		}
+	case "LAST-MODIFIED":
+		t.Modified, err = parseDateTime(p)
+	}
+	return err
+}
+
+type numberedLine struct {
+	num  int
+	text string
+}
+
+// unfold joins folded lines, which continue on the next line after a space
+// or tab, and drops empty ones.
+func unfold(r io.Reader) ([]numberedLine, error) {
+	var lines []numberedLine
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	num := 0
+	for sc.Scan() {
+		num++
+		text := strings.TrimRight(sc.Text(), "\r")
+		if num == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1].text += text[1:]
+			continue
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		lines = append(lines, numberedLine{num: num, text: text})
+	}
+	return lines, sc.Err()
+}
+
+// property is a parsed content line: NAME;PARAM=VALUE:value.
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// parseLine splits a content line into name, parameters and value.
+// Parameter values may be quoted to contain ";", ":" and ",".
+func parseLine(s string) (property, error) {
+	p := property{params: make(map[string]string)}
+	i := strings.IndexAny(s, ";:")
+	if i <= 0 {
+		return p, fmt.Errorf("invalid content line %q", s)
+	}
+	p.name = strings.ToUpper(s[:i])
+	for s[i] == ';' {
+		s = s[i+1:]
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return p, fmt.Errorf("invalid parameter in %s", p.name)
+		}
+		key := strings.ToUpper(s[:eq])
+		s = s[eq+1:]
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				return p, fmt.Errorf("unterminated quote in %s", p.name)
+			}
+			value = s[1 : end+1]
+			s = s[end+2:]
+		} else {
+			end := strings.IndexAny(s, ";:")
+			if end < 0 {
+				return p, fmt.Errorf("missing value in %s", p.name)
+			}
+			value = s[:end]
+			s = s[end:]
+		}
+		p.params[key] = value
+		if s == "" {
+			return p, fmt.Errorf("missing value in %s", p.name)
+		}
+		i = 0
+	}
+	if s[i] != ':' {
+		return p, fmt.Errorf("invalid content line for %s", p.name)
+	}
+	p.value = s[i+1:]
+	return p, nil
+}
+
+// parseDateTime parses a DATE or DATE-TIME value. Dates, which have no time
+// zone, are returned as midnight UTC so that they keep their date wherever
+// they are read; times without a zone are taken in the zone of the TZID
+// parameter, or as local time.
+func parseDateTime(p property) (time.Time, error) {
+	v := strings.TrimSpace(p.value)
+	if len(v) == 8 {
+		t, err := time.Parse("20060102", v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", v)
+		}
+		return t, nil
+	}
+	if strings.HasSuffix(v, "Z") {
+		t, err := time.Parse("20060102T150405Z", v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date-time %q", v)
+		}
+		return t, nil
+	}
+	loc := time.Local
+	if tzid := p.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", v, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date-time %q", v)
+	}
+	return t, nil
+}
+
+var unescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+// unescape decodes a TEXT value.
+func unescape(s string) string {
+	return unescaper.Replace(s)
+}
+
+// splitList splits a list of TEXT values at the commas that are not escaped.
+func splitList(s string) []string {
+	var out []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			out = append(out, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, s[start:])
+}
@@ -0,0 +1,142 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvColumns lists the header names accepted for each field, in lower case.
+var csvColumns = map[string][]string{
+	"title":  {"title", "name", "task", "summary", "subject", "content"},
+	"notes":  {"notes", "note", "description", "details"},
+	"due":    {"due", "due date", "duedate", "deadline"},
+	"status": {"status", "done", "completed", "state"},
+	"parent": {"parent"},
+	"no":     {"no", "number", "#"},
+	"id":     {"id"},
+}
+
+// ParseCSV reads a CSV file with a header row. Columns are found by name, so
+// their order does not matter and unknown columns are ignored; a title
+// column is required. The parent column refers to another row by its No or
+// ID column, or by title, as in the output of 'gtasks tasks view --format
+// csv'.
+func ParseCSV(r io.Reader) ([]*Item, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	col := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		for field, names := range csvColumns {
+			for _, n := range names {
+				if n == name {
+					if _, seen := col[field]; !seen {
+						col[field] = i
+					}
+				}
+			}
+		}
+	}
+	if _, ok := col["title"]; !ok {
+		return nil, fmt.Errorf("no title column (name one of: %s)", strings.Join(csvColumns["title"], ", "))
+	}
+
+	type row struct {
+		item   *Item
+		parent string
+	}
+	var rows []row
+	byRef := make(map[string]*Item)
+	byTitle := make(map[string]*Item)
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		get := func(field string) string {
+			if i, ok := col[field]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		it := &Item{Title: get("title"), Notes: get("notes"), Line: line}
+		if it.Title == "" {
+			if strings.TrimSpace(strings.Join(record, "")) == "" {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: empty title", line)
+		}
+		if it.Due, err = parseDue(get("due")); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if it.Completed, err = parseStatus(get("status")); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		for _, field := range []string{"no", "id"} {
+			if ref := get(field); ref != "" {
+				byRef[ref] = it
+			}
+		}
+		if _, ok := byTitle[it.Title]; !ok {
+			byTitle[it.Title] = it
+		}
+		rows = append(rows, row{it, get("parent")})
+	}
+
+	parents := make(map[*Item]*Item)
+	for _, r := range rows {
+		if r.parent == "" {
+			continue
+		}
+		parent, ok := byRef[r.parent]
+		if !ok {
+			parent, ok = byTitle[r.parent]
+		}
+		if !ok || parent == r.item {
+			return nil, fmt.Errorf("line %d: unknown parent %q", r.item.Line, r.parent)
+		}
+		parents[r.item] = parent
+	}
+
+	var items []*Item
+	for _, r := range rows {
+		parent := parents[r.item]
+		if parent == nil {
+			items = append(items, r.item)
+			continue
+		}
+		if inParentCycle(parents, r.item) {
+			return nil, fmt.Errorf("line %d: parent %q leads back to this task", r.item.Line, r.parent)
+		}
+		parent.Children = append(parent.Children, r.item)
+	}
+	return items, nil
+}
+
+// inParentCycle reports whether following parents from it leads back to it.
+func inParentCycle(parents map[*Item]*Item, it *Item) bool {
+	seen := make(map[*Item]bool)
+	for p := parents[it]; p != nil && !seen[p]; p = parents[p] {
+		if p == it {
+			return true
+		}
+		seen[p] = true
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/BRO3886/gtasks/internal/ical"
+)
+
+// ParseICS reads the VTODOs of an iCalendar file. A to-do whose RELATED-TO
+// names another to-do of the file becomes its subtask; events and cancelled
+// to-dos are skipped.
+func ParseICS(r io.Reader) ([]*Item, error) {
+	todos, err := ical.Read(r)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*Item, len(todos))
+	byUID := make(map[string]*Item)
+	index := make(map[string]int)
+	for i, t := range todos {
+		it := &Item{
+			Title:       strings.TrimSpace(t.Summary),
+			Notes:       strings.TrimSpace(t.Description),
+			Due:         dueOn(t.Due),
+			Completed:   t.Done(),
+			CompletedAt: t.Completed,
+			Rule:        t.RRule,
+			Line:        t.Line,
+		}
+		if it.Title == "" {
+			return nil, fmt.Errorf("line %d: to-do without SUMMARY", t.Line)
+		}
+		if t.Status == ical.Cancelled {
+			continue
+		}
+		items[i] = it
+		if t.UID != "" {
+			byUID[t.UID] = it
+			index[t.UID] = i
+		}
+	}
+
+	var roots []*Item
+	for i, it := range items {
+		if it == nil {
+			continue
+		}
+		parent := byUID[todos[i].RelatedTo]
+		if parent == nil || inCycle(todos, index, i) {
+			roots = append(roots, it)
+			continue
+		}
+		parent.Children = append(parent.Children, it)
+	}
+	return roots, nil
+}
+
+// inCycle reports whether following RELATED-TO from the i'th to-do leads
+// back to it, in which case it is kept at the top level. index maps the UIDs
+// of the to-dos being imported to their position.
+func inCycle(todos []ical.Todo, index map[string]int, i int) bool {
+	seen := make(map[int]bool)
+	for j := i; !seen[j]; {
+		seen[j] = true
+		next, ok := index[todos[j].RelatedTo]
+		if !ok {
+			return false
+		}
+		if next == i {
+			return true
+		}
+		j = next
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+// Package importer reads tasks from files written by other tools: CSV, JSON
+// (as written by 'gtasks tasks view --format json'), iCalendar, Taskwarrior's
+// JSON, todo.txt and Markdown checklists. Each format is parsed into the
+// same tree of Items, which the caller turns into Google Tasks.
+package importer
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/araddon/dateparse"
+)
+
+// Item is a task read from a file.
+type Item struct {
+	Title string
+	Notes string
+	// Due is the due date at midnight UTC, as the API keeps due dates, or
+	// zero if there is none.
+	Due       time.Time
+	Completed bool
+	// CompletedAt is when a completed item was completed, if known.
+	CompletedAt time.Time
+	// List is the title of the tasklist the item belongs in, for formats
+	// that name one; "" for the list imported into.
+	List string
+	// Rule is the recurrence rule of the task (an RRULE value), for formats
+	// that have one.
+	Rule string
+	// Line is where the item starts in the input, for messages.
+	Line     int
+	Children []*Item
+}
+
+// A Parser reads the items of one format.
+type Parser func(r io.Reader) ([]*Item, error)
+
+var parsers = map[string]Parser{
+	"csv":         ParseCSV,
+	"ics":         ParseICS,
+	"json":        ParseJSON,
+	"todotxt":     ParseTodoTxt,
+	"markdown":    ParseMarkdown,
+	"taskwarrior": ParseTaskwarrior,
+}
+
+var extensions = map[string]string{
+	".csv":      "csv",
+	".ics":      "ics",
+	".ical":     "ics",
+	".json":     "json",
+	".txt":      "todotxt",
+	".md":       "markdown",
+	".markdown": "markdown",
+}
+
+// Formats returns the names of the supported formats.
+func Formats() []string {
+	names := make([]string, 0, len(parsers))
+	for name := range parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse reads items in the named format.
+func Parse(format string, r io.Reader) ([]*Item, error) {
+	p, ok := parsers[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q (use %s)", format, strings.Join(Formats(), ", "))
+	}
+	return p(r)
+}
+
+// FormatOf guesses the format of a file from its extension.
+func FormatOf(path string) (string, bool) {
+	format, ok := extensions[strings.ToLower(filepath.Ext(path))]
+	return format, ok
+}
+
+// Count returns the number of items including all their descendants.
+func Count(items []*Item) int {
+	n := 0
+	for _, it := range items {
+		n += 1 + Count(it.Children)
+	}
+	return n
+}
+
+// parseDue parses a due date in any of the forms dateparse knows, the same
+// way 'gtasks tasks add' does. An empty value or "-" means no due date.
+func parseDue(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "-" {
+		return time.Time{}, nil
+	}
+	t, err := dateparse.ParseAny(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid due date %q", s)
+	}
+	return dueOn(t), nil
+}
+
+// dueOn returns the date of t, in t's own zone, as a due date: midnight UTC
+// of that date. Written with a zone offset instead, the date would be read
+// back as the day before east of UTC.
+func dueOn(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// parseStatus maps the usual ways of writing a task's state to whether it
+// is completed.
+func parseStatus(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "pending", "needsaction", "open", "todo", "no", "false", "0":
+		return false, nil
+	case "completed", "complete", "done", "x", "yes", "true", "1":
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown status %q", s)
+}
@@ -0,0 +1,170 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// zones are the local time zones the tests run in: due dates must not
+// depend on them.
+var zones = []*time.Location{
+	time.UTC,
+	time.FixedZone("UTC+9", 9*60*60),
+	time.FixedZone("UTC-8", -8*60*60),
+}
+
+// inZones runs f once with each of zones as the local time zone.
+func inZones(t *testing.T, f func(t *testing.T)) {
+	local := time.Local
+	t.Cleanup(func() { time.Local = local })
+	for _, loc := range zones {
+		time.Local = loc
+		t.Run(loc.String(), f)
+	}
+}
+
+// dues returns the due dates of items and their children in order, as
+// written to the API.
+func dues(items []*Item) []string {
+	var out []string
+	for _, it := range items {
+		due := "-"
+		if !it.Due.IsZero() {
+			due = it.Due.Format(time.RFC3339)
+		}
+		out = append(out, due)
+		out = append(out, dues(it.Children)...)
+	}
+	return out
+}
+
+func TestParseDueDates(t *testing.T) {
+	tests := []struct {
+		format string
+		input  string
+		want   string
+	}{
+		{"csv", "title,due\nPay rent,2025-01-05\nCall,\n", "2025-01-05T00:00:00Z -"},
+		{"csv", "title,due\nPay rent,01/05/2025\nCall,January 6 2025\n", "2025-01-05T00:00:00Z 2025-01-06T00:00:00Z"},
+		{"json", `[{"title":"Pay rent","due":"2025-01-05","children":[{"title":"Bank","due":"2025-01-31"}]}]`, "2025-01-05T00:00:00Z 2025-01-31T00:00:00Z"},
+		{"json", `[{"title":"Late","due":"2025-01-05T23:30:00Z"},{"title":"Early","due":"2025-01-05T00:30:00+02:00"}]`, "2025-01-05T00:00:00Z 2025-01-05T00:00:00Z"},
+		{"todotxt", "(A) Call mom due:2025-01-05\nx 2025-01-03 Pay rent due:2025-12-31\n", "2025-01-05T00:00:00Z 2025-12-31T00:00:00Z"},
+		{"ics", ics("DUE;VALUE=DATE:20250105", "DUE:20250105", "DUE;TZID=Asia/Tokyo:20250105T080000", ""), "2025-01-05T00:00:00Z 2025-01-05T00:00:00Z 2025-01-05T00:00:00Z -"},
+		{"markdown", "- [ ] Buy milk due:2025-01-05\n  - [ ] Whole 📅 2025-01-06\n- [x] Bread\n", "2025-01-05T00:00:00Z 2025-01-06T00:00:00Z -"},
+	}
+	inZones(t, func(t *testing.T) {
+		for _, tt := range tests {
+			items, err := Parse(tt.format, strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("%s %q: %v", tt.format, tt.input, err)
+			}
+			if got := strings.Join(dues(items), " "); got != tt.want {
+				t.Errorf("%s %q: got %s, want %s", tt.format, tt.input, got, tt.want)
+			}
+		}
+	})
+}
+
+func TestParseDueErrors(t *testing.T) {
+	tests := []struct {
+		format string
+		input  string
+	}{
+		{"csv", "title,due\nPay rent,someday\n"},
+		{"json", `[{"title":"Pay rent","due":"someday"}]`},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.format, strings.NewReader(tt.input)); err == nil {
+			t.Errorf("%s %q: got no error", tt.format, tt.input)
+		}
+	}
+}
+
+// ics returns a calendar with a to-do for each of the given DUE lines.
+func ics(dues ...string) string {
+	var b strings.Builder
+	b.WriteString("BEGIN:VCALENDAR\r\n")
+	for _, due := range dues {
+		b.WriteString("BEGIN:VTODO\r\nSUMMARY:Task\r\n")
+		if due != "" {
+			b.WriteString(due + "\r\n")
+		}
+		b.WriteString("END:VTODO\r\n")
+	}
+	b.WriteString("END:VCALENDAR\r\n")
+	return b.String()
+}
+
+func TestParseTaskwarriorDueDates(t *testing.T) {
+	// Taskwarrior writes due times in UTC; the due date is the local date.
+	inZones(t, func(t *testing.T) {
+		var lines []string
+		for _, h := range []int{0, 12, 23} {
+			due := time.Date(2025, 1, 5, h, 0, 0, 0, time.Local).UTC().Format("20060102T150405Z")
+			lines = append(lines, `{"description":"Task","status":"pending","entry":"20250101T000000Z","due":"`+due+`"}`)
+		}
+		lines = append(lines, `{"description":"No due","status":"pending","entry":"20250101T000000Z"}`)
+		items, err := Parse("taskwarrior", strings.NewReader(strings.Join(lines, "\n")))
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		want := "2025-01-05T00:00:00Z 2025-01-05T00:00:00Z 2025-01-05T00:00:00Z -"
+		if got := strings.Join(dues(items), " "); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	})
+}
+
+// tree returns the titles of items, each followed by its children in
+// parentheses.
+func tree(items []*Item) string {
+	var out []string
+	for _, it := range items {
+		s := it.Title
+		if len(it.Children) > 0 {
+			s += "(" + tree(it.Children) + ")"
+		}
+		out = append(out, s)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestParseCSVParents(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"no,title,parent\n1,a,\n2,b,1\n3,c,a\n", "a(b c)"},
+		{"title,parent\nb,a\na,\n", "a(b)"},
+		{"id,title,parent\nx,a,\ny,b,x\n", "a(b)"},
+	}
+	for _, tt := range tests {
+		items, err := ParseCSV(strings.NewReader(tt.input))
+		if err != nil {
+			t.Errorf("%q: %v", tt.input, err)
+			continue
+		}
+		if got := tree(items); got != tt.want {
+			t.Errorf("%q: got %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseCSVParentErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"title,parent\na,b\n", `line 2: unknown parent "b"`},
+		{"title,parent\na,a\n", `line 2: unknown parent "a"`},
+		{"no,title,parent\n1,a,2\n2,b,1\n", `line 2: parent "2" leads back to this task`},
+		{"title,parent\nc,\na,b\nb,d\nd,a\n", `line 3: parent "b" leads back to this task`},
+	}
+	for _, tt := range tests {
+		_, err := ParseCSV(strings.NewReader(tt.input))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%q: got %v, want %s", tt.input, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// jsonItem is one task of the JSON written by 'gtasks tasks view --format
+// json'. "notes" is accepted as well as "description".
+type jsonItem struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Notes       string     `json:"notes"`
+	Status      string     `json:"status"`
+	Due         string     `json:"due"`
+	Children    []jsonItem `json:"children"`
+}
+
+// ParseJSON reads an array of tasks, each of which may have children.
+func ParseJSON(r io.Reader) ([]*Item, error) {
+	var in []jsonItem
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	return convertJSON(in, "")
+}
+
+func convertJSON(in []jsonItem, path string) ([]*Item, error) {
+	var items []*Item
+	for i, j := range in {
+		where := fmt.Sprintf("%stask %d", path, i+1)
+		it := &Item{Title: j.Title, Notes: j.Description}
+		if j.Notes != "" {
+			it.Notes = j.Notes
+		}
+		if it.Title == "" {
+			return nil, fmt.Errorf("%s: empty title", where)
+		}
+		var err error
+		if it.Due, err = parseDue(j.Due); err != nil {
+			return nil, fmt.Errorf("%s: %v", where, err)
+		}
+		if it.Completed, err = parseStatus(j.Status); err != nil {
+			return nil, fmt.Errorf("%s: %v", where, err)
+		}
+		if it.Children, err = convertJSON(j.Children, where+", "); err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	return items, nil
+}
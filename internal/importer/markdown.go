@@ -0,0 +1,103 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	// - [ ] task / * [x] task / 1. [ ] task
+	mdCheckbox = regexp.MustCompile(`^(?:[-*+]|\d+[.)])\s+\[([ xX])\]\s*(.*)$`)
+	// due:2025-01-05 or the 📅 2025-01-05 of the Obsidian Tasks plugin.
+	mdDue = regexp.MustCompile(`(?:^|\s)(?:due:|📅\s*)(\d{4}-\d{2}-\d{2})\b`)
+	// ✅ 2025-01-04, the completion date of the Obsidian Tasks plugin.
+	mdDone = regexp.MustCompile(`(?:^|\s)✅\s*\d{4}-\d{2}-\d{2}\b`)
+)
+
+// ParseMarkdown reads the checklist items of a Markdown file. Items indented
+// under another become its children. Other lines indented under an item are
+// added to its notes; headings and text outside items are ignored. A due
+// date can be given as "due:2025-01-05" or "📅 2025-01-05".
+func ParseMarkdown(r io.Reader) ([]*Item, error) {
+	type open struct {
+		indent int
+		item   *Item
+	}
+	var (
+		items []*Item
+		stack []open
+	)
+	sc := bufio.NewScanner(r)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := sc.Text()
+		trimmed := strings.TrimSpace(text)
+		indent := indentWidth(text)
+
+		// Close the items this line is not indented under.
+		for len(stack) > 0 && indent <= stack[len(stack)-1].indent && trimmed != "" {
+			stack = stack[:len(stack)-1]
+		}
+
+		m := mdCheckbox.FindStringSubmatch(trimmed)
+		if m == nil {
+			if trimmed != "" && len(stack) > 0 && !strings.HasPrefix(trimmed, "#") {
+				top := stack[len(stack)-1].item
+				if top.Notes != "" {
+					top.Notes += "\n"
+				}
+				top.Notes += strings.TrimLeft(strings.TrimLeft(trimmed, "-*+"), " ")
+			}
+			if strings.HasPrefix(trimmed, "#") {
+				stack = nil
+			}
+			continue
+		}
+
+		it := &Item{Completed: m[1] != " ", Line: line}
+		title := m[2]
+		if d := mdDue.FindStringSubmatchIndex(title); d != nil {
+			due, err := parseDue(title[d[2]:d[3]])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			it.Due = due
+			title = title[:d[0]] + title[d[1]:]
+		}
+		title = mdDone.ReplaceAllString(title, "")
+		it.Title = strings.Join(strings.Fields(title), " ")
+		if it.Title == "" {
+			return nil, fmt.Errorf("line %d: empty task", line)
+		}
+
+		if len(stack) == 0 {
+			items = append(items, it)
+		} else {
+			parent := stack[len(stack)-1].item
+			parent.Children = append(parent.Children, it)
+		}
+		stack = append(stack, open{indent, it})
+	}
+	return items, sc.Err()
+}
+
+// indentWidth returns the width of the leading whitespace of s, counting a
+// tab as four spaces.
+func indentWidth(s string) int {
+	w := 0
+	for _, c := range s {
+		switch c {
+		case ' ':
+			w++
+		case '\t':
+			w += 4
+		default:
+			return w
+		}
+	}
+	return w
+}
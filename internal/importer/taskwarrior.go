@@ -0,0 +1,103 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/BRO3886/gtasks/internal/taskwarrior"
+)
+
+// twItem is one task of Taskwarrior's JSON, as written by 'task export'.
+type twItem struct {
+	UUID        string                   `json:"uuid"`
+	Description string                   `json:"description"`
+	Status      string                   `json:"status"`
+	Entry       string                   `json:"entry"`
+	Due         string                   `json:"due"`
+	End         string                   `json:"end"`
+	Project     string                   `json:"project"`
+	Priority    string                   `json:"priority"`
+	Tags        []string                 `json:"tags"`
+	Annotations []taskwarrior.Annotation `json:"annotations"`
+}
+
+// ParseTaskwarrior reads the output of 'task export': a JSON array of tasks,
+// or one task object per line as older versions write it. The project names
+// the tasklist of a task. Annotations, priority, tags, UUID and creation
+// time go into the notes; see package taskwarrior. Deleted tasks and the
+// templates of recurring tasks are skipped.
+func ParseTaskwarrior(r io.Reader) ([]*Item, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var in []twItem
+	if raw[0] == '[' {
+		if err := json.Unmarshal(raw, &in); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		for dec.More() {
+			var t twItem
+			if err := dec.Decode(&t); err != nil {
+				return nil, fmt.Errorf("invalid JSON: %v", err)
+			}
+			in = append(in, t)
+		}
+	}
+
+	var items []*Item
+	for i, t := range in {
+		where := fmt.Sprintf("task %d", i+1)
+		switch t.Status {
+		case "deleted", "recurring":
+			continue
+		case "", "pending", "waiting", "completed":
+		default:
+			return nil, fmt.Errorf("%s: unknown status %q", where, t.Status)
+		}
+		it := &Item{
+			Title:     strings.TrimSpace(t.Description),
+			Completed: t.Status == "completed",
+			List:      t.Project,
+			Line:      i + 1,
+		}
+		if it.Title == "" {
+			return nil, fmt.Errorf("%s: empty description", where)
+		}
+		due, err := taskwarrior.ParseTime(t.Due)
+		if err != nil {
+			return nil, fmt.Errorf("%s: due: %v", where, err)
+		}
+		if !due.IsZero() {
+			// Due times are kept in UTC; the due date is the date they fall
+			// on in the local time zone.
+			it.Due = dueOn(due.Local())
+		}
+		if it.Completed {
+			if it.CompletedAt, err = taskwarrior.ParseTime(t.End); err != nil {
+				return nil, fmt.Errorf("%s: end: %v", where, err)
+			}
+		}
+
+		extra := taskwarrior.Extra{UUID: t.UUID, Priority: t.Priority, Tags: t.Tags}
+		if extra.Entry, err = taskwarrior.ParseTime(t.Entry); err != nil {
+			return nil, fmt.Errorf("%s: entry: %v", where, err)
+		}
+		for _, a := range t.Annotations {
+			extra.Annotations = append(extra.Annotations, a.Description)
+		}
+		it.Notes = extra.Notes()
+		items = append(items, it)
+	}
+	return items, nil
+}
@@ -0,0 +1,80 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	todoDate     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	todoPriority = regexp.MustCompile(`^\(([A-Z])\)$`)
+)
+
+// ParseTodoTxt reads the todo.txt format, one task per line:
+//
+//	x 2025-01-03 2025-01-01 (A) Call mom +Family @phone due:2025-01-05
+//
+// A leading "x" marks the task completed and is followed by the completion
+// and creation dates, which are dropped. The priority and any key:value tags
+// other than due: go into the notes; projects and contexts stay in the title.
+func ParseTodoTxt(r io.Reader) ([]*Item, error) {
+	var items []*Item
+	sc := bufio.NewScanner(r)
+	line := 0
+	for sc.Scan() {
+		line++
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		it := &Item{Line: line}
+		if fields[0] == "x" {
+			it.Completed = true
+			fields = fields[1:]
+			// Completion date, then creation date.
+			for i := 0; i < 2 && len(fields) > 0 && todoDate.MatchString(fields[0]); i++ {
+				fields = fields[1:]
+			}
+		}
+
+		var notes []string
+		if len(fields) > 0 {
+			if m := todoPriority.FindStringSubmatch(fields[0]); m != nil {
+				notes = append(notes, "Priority: "+m[1])
+				fields = fields[1:]
+			}
+		}
+		if !it.Completed && len(fields) > 0 && todoDate.MatchString(fields[0]) {
+			fields = fields[1:] // creation date
+		}
+
+		var words []string
+		for _, f := range fields {
+			key, value, ok := strings.Cut(f, ":")
+			if !ok || key == "" || value == "" || strings.ContainsAny(key, "+@") || strings.Contains(value, "//") {
+				words = append(words, f)
+				continue
+			}
+			if key == "due" {
+				due, err := parseDue(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", line, err)
+				}
+				it.Due = due
+				continue
+			}
+			// Other tags, e.g. rec:1w or t:2025-01-01.
+			notes = append(notes, f)
+		}
+		it.Title = strings.Join(words, " ")
+		if it.Title == "" {
+			return nil, fmt.Errorf("line %d: empty task", line)
+		}
+		it.Notes = strings.Join(notes, "\n")
+		items = append(items, it)
+	}
+	return items, sc.Err()
+}
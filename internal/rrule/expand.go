@@ -0,0 +1,289 @@
+package rrule
+
+import (
+	"sort"
+	"time"
+)
+
+// MaxOccurrences caps Expand for rules without COUNT, and stops rules that
+// never match from being searched forever.
+const MaxOccurrences = 1000
+
+// maxGapYears is how long Expand searches past the last occurrence, or past
+// start, before giving up. Leap days may take 8 years to come round. Rules
+// with a long INTERVAL are also given at least maxGapYears periods.
+const maxGapYears = 8
+
+// Expand returns the dates the rule produces from start on, keeping start's
+// time of day and location. start itself is only included if it matches the
+// rule. Without COUNT or UNTIL at most MaxOccurrences dates are returned.
+//
+// Following RFC 5545, dates that do not exist are skipped rather than moved:
+// a monthly rule on the 31st has no occurrence in April. Use
+// BYMONTHDAY=28,29,30,31;BYSETPOS=-1 for "the 31st or the last day of the
+// month".
+func (r *Rule) Expand(start time.Time) []time.Time {
+	limit := MaxOccurrences
+	if r.Count > 0 {
+		limit = r.Count
+	}
+	interval := max(r.Interval, 1)
+
+	var out []time.Time
+	last, empty := dateOf(start), 0
+	for k := 0; len(out) < limit; k++ {
+		from := r.periodStart(start, k*interval)
+		if !r.Until.IsZero() && from.After(dateOf(r.Until)) {
+			break
+		}
+		if empty >= maxGapYears && from.After(last.AddDate(maxGapYears, 0, 0)) {
+			break
+		}
+		candidates := r.setPos(r.period(start, k*interval))
+		found := false
+		for _, d := range candidates {
+			if d.Before(dateOf(start)) {
+				continue
+			}
+			if !r.Until.IsZero() && d.After(dateOf(r.Until)) {
+				return out
+			}
+			found = true
+			last = d
+			out = append(out, atTimeOf(d, start))
+			if len(out) == limit {
+				break
+			}
+		}
+		if found {
+			empty = 0
+		} else {
+			empty++
+		}
+	}
+	return out
+}
+
+// dateOf returns midnight UTC of t's calendar date. Candidates are built as
+// such dates so they can be compared regardless of location.
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// atTimeOf returns date d at the time of day and in the location of ref.
+func atTimeOf(d, ref time.Time) time.Time {
+	return time.Date(d.Year(), d.Month(), d.Day(), ref.Hour(), ref.Minute(), ref.Second(), ref.Nanosecond(), ref.Location())
+}
+
+// periodStart returns the first day of the period n units after start's.
+func (r *Rule) periodStart(start time.Time, n int) time.Time {
+	s := dateOf(start)
+	switch r.Freq {
+	case Daily:
+		return s.AddDate(0, 0, n)
+	case Weekly:
+		return r.weekStart(s).AddDate(0, 0, 7*n)
+	case Monthly:
+		return time.Date(s.Year(), s.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(s.Year()+n, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// weekStart returns the first day, per WeekStart, of the week containing d.
+func (r *Rule) weekStart(d time.Time) time.Time {
+	back := (int(d.Weekday()) - int(r.WeekStart) + 7) % 7
+	return d.AddDate(0, 0, -back)
+}
+
+// period returns the sorted candidate dates of the period n units after
+// start's, before BYSETPOS is applied.
+func (r *Rule) period(start time.Time, n int) []time.Time {
+	s := dateOf(start)
+	p := r.periodStart(start, n)
+	var out []time.Time
+	switch r.Freq {
+	case Daily:
+		if r.matchMonth(p) && r.matchMonthDay(p) && r.matchWeekday(p) {
+			out = append(out, p)
+		}
+	case Weekly:
+		for i := 0; i < 7; i++ {
+			d := p.AddDate(0, 0, i)
+			if !r.matchMonth(d) {
+				continue
+			}
+			if len(r.ByDay) == 0 && d.Weekday() != s.Weekday() {
+				continue
+			}
+			if len(r.ByDay) > 0 && !r.matchWeekday(d) {
+				continue
+			}
+			out = append(out, d)
+		}
+	case Monthly:
+		if r.matchMonth(p) {
+			out = r.daysOfMonth(p, s)
+		}
+	case Yearly:
+		out = r.daysOfYear(p, s)
+	}
+	return out
+}
+
+// daysOfMonth returns the days of the month starting at first that match
+// BYMONTHDAY and BYDAY, or start's day of the month when neither is given.
+func (r *Rule) daysOfMonth(first, start time.Time) []time.Time {
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		d := time.Date(first.Year(), first.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+		if d.Month() != first.Month() {
+			return nil // the month is too short
+		}
+		return []time.Time{d}
+	}
+	last := first.AddDate(0, 1, -1)
+	var out []time.Time
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		if len(r.ByMonthDay) > 0 && !r.matchMonthDay(d) {
+			continue
+		}
+		if len(r.ByDay) > 0 && !matchWeekdayIn(r.ByDay, d, first, last) {
+			continue
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// daysOfYear returns the candidate days of the year starting at first.
+func (r *Rule) daysOfYear(first, start time.Time) []time.Time {
+	year := first.Year()
+	if len(r.ByMonth) == 0 && len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		d := time.Date(year, start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+		if d.Month() != start.Month() {
+			return nil // February 29th in a common year
+		}
+		return []time.Time{d}
+	}
+
+	if len(r.ByMonth) == 0 && len(r.ByMonthDay) == 0 {
+		// BYDAY ordinals count within the whole year.
+		last := time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC)
+		var out []time.Time
+		for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+			if matchWeekdayIn(r.ByDay, d, first, last) {
+				out = append(out, d)
+			}
+		}
+		return out
+	}
+
+	months := r.ByMonth
+	if len(months) == 0 {
+		months = []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+	}
+	var out []time.Time
+	for _, m := range sortedCopy(months) {
+		monthFirst := time.Date(year, time.Month(m), 1, 0, 0, 0, 0, time.UTC)
+		if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+			d := time.Date(year, time.Month(m), start.Day(), 0, 0, 0, 0, time.UTC)
+			if d.Month() == time.Month(m) {
+				out = append(out, d)
+			}
+			continue
+		}
+		out = append(out, r.daysOfMonth(monthFirst, start)...)
+	}
+	return out
+}
+
+func (r *Rule) matchMonth(d time.Time) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if time.Month(m) == d.Month() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchMonthDay(d time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	daysIn := time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, md := range r.ByMonthDay {
+		if md > 0 && md == d.Day() || md < 0 && daysIn+1+md == d.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWeekday matches d against BYDAY ignoring ordinals, as DAILY and
+// WEEKLY rules do.
+func (r *Rule) matchWeekday(d time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, w := range r.ByDay {
+		if w.Day == d.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWeekdayIn reports whether d matches one of days, with ordinals
+// counted within the span first..last.
+func matchWeekdayIn(days []WeekdayNum, d, first, last time.Time) bool {
+	for _, w := range days {
+		if w.Day != d.Weekday() {
+			continue
+		}
+		switch {
+		case w.N == 0:
+			return true
+		case w.N > 0 && int(d.Sub(first).Hours()/24)/7+1 == w.N:
+			return true
+		case w.N < 0 && int(last.Sub(d).Hours()/24)/7+1 == -w.N:
+			return true
+		}
+	}
+	return false
+}
+
+// setPos keeps the candidates of a period at the BYSETPOS positions.
+func (r *Rule) setPos(candidates []time.Time) []time.Time {
+	if len(r.BySetPos) == 0 {
+		return candidates
+	}
+	var out []time.Time
+	for _, pos := range r.BySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(candidates) + pos
+		}
+		if i >= 0 && i < len(candidates) {
+			out = append(out, candidates[i])
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	// Positions may name the same day twice, e.g. 1 and -1 in a period with
+	// one candidate.
+	uniq := out[:0]
+	for i, d := range out {
+		if i == 0 || !d.Equal(out[i-1]) {
+			uniq = append(uniq, d)
+		}
+	}
+	return uniq
+}
+
+func sortedCopy(ns []int) []int {
+	out := append([]int(nil), ns...)
+	sort.Ints(out)
+	return out
+}
@@ -0,0 +1,255 @@
+// Package rrule parses and expands the recurrence rules of RFC 5545
+// (iCalendar), for example:
+//
+//	FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10
+//
+// Tasks only have due dates, so the rule parts that work on times of day
+// (BYHOUR, BYMINUTE, BYSECOND) and the secondly to hourly frequencies are not
+// supported.
+package rrule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Freq is the FREQ of a rule.
+type Freq int
+
+const (
+	Daily Freq = iota + 1
+	Weekly
+	Monthly
+	Yearly
+)
+
+var freqNames = map[Freq]string{
+	Daily:   "DAILY",
+	Weekly:  "WEEKLY",
+	Monthly: "MONTHLY",
+	Yearly:  "YEARLY",
+}
+
+func (f Freq) String() string {
+	return freqNames[f]
+}
+
+var dayNames = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// WeekdayNum is one BYDAY value: a weekday, optionally with the ordinal N of
+// that weekday in the month or year. N is 0 for every such weekday, negative
+// to count from the end.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+func (w WeekdayNum) String() string {
+	if w.N == 0 {
+		return dayNames[w.Day]
+	}
+	return strconv.Itoa(w.N) + dayNames[w.Day]
+}
+
+// Rule is a parsed recurrence rule.
+type Rule struct {
+	Freq     Freq
+	Interval int
+	// Count limits the number of occurrences; 0 means no limit.
+	Count int
+	// Until is the last date an occurrence may fall on; zero means no limit.
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []int
+	BySetPos   []int
+	// WeekStart is the first day of the week for WEEKLY rules with an
+	// interval above 1. It defaults to Monday.
+	WeekStart time.Weekday
+}
+
+// Parse parses a rule such as "FREQ=MONTHLY;BYDAY=-1FR". A leading "RRULE:"
+// is ignored, and so is the case of names and values.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 6 && strings.EqualFold(s[:6], "RRULE:") {
+		s = s[6:]
+	}
+	r := &Rule{Interval: 1, WeekStart: time.Monday}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		name = strings.ToUpper(strings.TrimSpace(name))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if seen[name] {
+			return nil, fmt.Errorf("%s given twice", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			r.Freq, err = parseFreq(value)
+		case "INTERVAL":
+			r.Interval, err = parsePositive(name, value)
+		case "COUNT":
+			r.Count, err = parsePositive(name, value)
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+		case "BYDAY":
+			r.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseInts(name, value, 31, true)
+		case "BYMONTH":
+			r.ByMonth, err = parseInts(name, value, 12, false)
+		case "BYSETPOS":
+			r.BySetPos, err = parseInts(name, value, 366, true)
+		case "WKST":
+			r.WeekStart, err = parseDay(value)
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.Freq == 0 {
+		return nil, fmt.Errorf("rule has no FREQ")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, fmt.Errorf("COUNT and UNTIL cannot both be given")
+	}
+	for _, d := range r.ByDay {
+		if d.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return nil, fmt.Errorf("BYDAY %s needs FREQ=MONTHLY or YEARLY", d)
+		}
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq == Weekly {
+		return nil, fmt.Errorf("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	}
+	return r, nil
+}
+
+func parseFreq(v string) (Freq, error) {
+	for f, name := range freqNames {
+		if name == v {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported FREQ %q (use DAILY, WEEKLY, MONTHLY or YEARLY)", v)
+}
+
+func parsePositive(name, v string) (int, error) {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid %s %q", name, v)
+	}
+	return n, nil
+}
+
+// parseInts parses a comma-separated list of non-zero numbers up to limit,
+// which may be negative if signed is set.
+func parseInts(name, v string, limit int, signed bool) ([]int, error) {
+	var out []int
+	for _, f := range strings.Split(v, ",") {
+		n, err := strconv.Atoi(f)
+		if err != nil || n == 0 || n > limit || n < -limit || n < 0 && !signed {
+			return nil, fmt.Errorf("invalid %s value %q", name, f)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func parseUntil(v string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %q (use YYYYMMDD)", v)
+}
+
+func parseDay(v string) (time.Weekday, error) {
+	for i, name := range dayNames {
+		if name == v {
+			return time.Weekday(i), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", v)
+}
+
+func parseByDay(v string) ([]WeekdayNum, error) {
+	var out []WeekdayNum
+	for _, f := range strings.Split(v, ",") {
+		if len(f) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY value %q", f)
+		}
+		day, err := parseDay(f[len(f)-2:])
+		if err != nil {
+			return nil, err
+		}
+		w := WeekdayNum{Day: day}
+		if num := f[:len(f)-2]; num != "" {
+			n, err := strconv.Atoi(num)
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, fmt.Errorf("invalid BYDAY value %q", f)
+			}
+			w.N = n
+		}
+		out = append(out, w)
+	}
+	return out, nil
+}
+
+// String formats the rule in its canonical form, without the "RRULE:"
+// prefix.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq.String()}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = d.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(r.BySetPos))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+dayNames[r.WeekStart])
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	return strings.Join(parts, ";")
+}
+
+func joinInts(ns []int) string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ",")
+}
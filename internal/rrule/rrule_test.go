@@ -0,0 +1,258 @@
+package rrule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// dates formats ts as YYYYMMDD, separated by spaces.
+func dates(ts []time.Time) string {
+	out := make([]string, len(ts))
+	for i, t := range ts {
+		out[i] = t.Format("20060102")
+	}
+	return strings.Join(out, " ")
+}
+
+func day(s string) time.Time {
+	t, err := time.Parse("20060102", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// TestExpandRFC5545 runs the examples of RFC 5545, section 3.8.5.3, that
+// only use dates. Rules without COUNT or UNTIL are compared on their first
+// occurrences.
+func TestExpandRFC5545(t *testing.T) {
+	tests := []struct {
+		name  string
+		start string
+		rule  string
+		want  string
+	}{
+		{
+			"daily for 10 occurrences", "19970902", "FREQ=DAILY;COUNT=10",
+			"19970902 19970903 19970904 19970905 19970906 19970907 19970908 19970909 19970910 19970911",
+		},
+		{
+			"every other day", "19970902", "FREQ=DAILY;INTERVAL=2",
+			"19970902 19970904 19970906 19970908 19970910",
+		},
+		{
+			"every 10 days, 5 occurrences", "19970902", "FREQ=DAILY;INTERVAL=10;COUNT=5",
+			"19970902 19970912 19970922 19971002 19971012",
+		},
+		{
+			"weekly for 10 occurrences", "19970902", "FREQ=WEEKLY;COUNT=10",
+			"19970902 19970909 19970916 19970923 19970930 19971007 19971014 19971021 19971028 19971104",
+		},
+		{
+			"every other week", "19970902", "FREQ=WEEKLY;INTERVAL=2;WKST=SU",
+			"19970902 19970916 19970930 19971014 19971028",
+		},
+		{
+			"weekly on Tuesday and Thursday for five weeks", "19970902", "FREQ=WEEKLY;COUNT=10;WKST=SU;BYDAY=TU,TH",
+			"19970902 19970904 19970909 19970911 19970916 19970918 19970923 19970925 19970930 19971002",
+		},
+		{
+			// The RFC's occurrences are at 09:00 New York time, which puts
+			// December 24 after UNTIL. Tasks have no time of day, so the
+			// date of UNTIL is the last date an occurrence may fall on.
+			"every other week on Monday, Wednesday and Friday until December 24", "19970901", "FREQ=WEEKLY;INTERVAL=2;WKST=SU;UNTIL=19971224T000000Z;BYDAY=MO,WE,FR",
+			"19970901 19970903 19970905 19970915 19970917 19970919 19970929 19971001 19971003 19971013 19971015 19971017 19971027 19971029 19971031 " +
+				"19971110 19971112 19971114 19971124 19971126 19971128 19971208 19971210 19971212 19971222 19971224",
+		},
+		{
+			"every other week on Tuesday and Thursday, 8 occurrences", "19970902", "FREQ=WEEKLY;INTERVAL=2;COUNT=8;WKST=SU;BYDAY=TU,TH",
+			"19970902 19970904 19970916 19970918 19970930 19971002 19971014 19971016",
+		},
+		{
+			"monthly on the first Friday for 10 occurrences", "19970905", "FREQ=MONTHLY;COUNT=10;BYDAY=1FR",
+			"19970905 19971003 19971107 19971205 19980102 19980206 19980306 19980403 19980501 19980605",
+		},
+		{
+			"every other month on the first and last Sunday", "19970907", "FREQ=MONTHLY;INTERVAL=2;COUNT=10;BYDAY=1SU,-1SU",
+			"19970907 19970928 19971102 19971130 19980104 19980125 19980301 19980329 19980503 19980531",
+		},
+		{
+			"monthly on the second-to-last Monday for 6 months", "19970922", "FREQ=MONTHLY;COUNT=6;BYDAY=-2MO",
+			"19970922 19971020 19971117 19971222 19980119 19980216",
+		},
+		{
+			"monthly on the third-to-last day", "19970928", "FREQ=MONTHLY;BYMONTHDAY=-3",
+			"19970928 19971029 19971128 19971229 19980129 19980226",
+		},
+		{
+			"monthly on the 2nd and 15th for 10 occurrences", "19970902", "FREQ=MONTHLY;COUNT=10;BYMONTHDAY=2,15",
+			"19970902 19970915 19971002 19971015 19971102 19971115 19971202 19971215 19980102 19980115",
+		},
+		{
+			"monthly on the first and last day for 10 occurrences", "19970930", "FREQ=MONTHLY;COUNT=10;BYMONTHDAY=1,-1",
+			"19970930 19971001 19971031 19971101 19971130 19971201 19971231 19980101 19980131 19980201",
+		},
+		{
+			"every 18 months on the 10th to 15th for 10 occurrences", "19970910", "FREQ=MONTHLY;INTERVAL=18;COUNT=10;BYMONTHDAY=10,11,12,13,14,15",
+			"19970910 19970911 19970912 19970913 19970914 19970915 19990310 19990311 19990312 19990313",
+		},
+		{
+			"every Tuesday, every other month", "19970902", "FREQ=MONTHLY;INTERVAL=2;BYDAY=TU",
+			"19970902 19970909 19970916 19970923 19970930 19971104 19971111 19971118 19971125 19980106",
+		},
+		{
+			"yearly in June and July for 10 occurrences", "19970610", "FREQ=YEARLY;COUNT=10;BYMONTH=6,7",
+			"19970610 19970710 19980610 19980710 19990610 19990710 20000610 20000710 20010610 20010710",
+		},
+		{
+			"every 20th Monday of the year", "19970519", "FREQ=YEARLY;BYDAY=20MO",
+			"19970519 19980518 19990517",
+		},
+		{
+			"every Thursday in March", "19970313", "FREQ=YEARLY;BYMONTH=3;BYDAY=TH",
+			"19970313 19970320 19970327 19980305 19980312 19980319 19980326 19990304 19990311 19990318 19990325",
+		},
+		{
+			"every Thursday in June, July and August", "19970605", "FREQ=YEARLY;BYDAY=TH;BYMONTH=6,7,8",
+			"19970605 19970612 19970619 19970626 19970703 19970710 19970717 19970724 19970731 19970807",
+		},
+		{
+			"every Friday the 13th", "19970902", "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13",
+			"19980213 19980313 19981113 19990813 20001013",
+		},
+		{
+			"the first Saturday that follows the first Sunday of the month", "19970913", "FREQ=MONTHLY;BYDAY=SA;BYMONTHDAY=7,8,9,10,11,12,13",
+			"19970913 19971011 19971108 19971213 19980110 19980207 19980307 19980411 19980509 19980613",
+		},
+		{
+			"US Presidential Election day every 4 years", "19961105", "FREQ=YEARLY;INTERVAL=4;BYMONTH=11;BYDAY=TU;BYMONTHDAY=2,3,4,5,6,7,8",
+			"19961105 20001107 20041102",
+		},
+		{
+			"the third Tuesday, Wednesday or Thursday for the next 3 months", "19970904", "FREQ=MONTHLY;COUNT=3;BYDAY=TU,WE,TH;BYSETPOS=3",
+			"19970904 19971007 19971106",
+		},
+		{
+			"the second-to-last weekday of the month", "19970929", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-2",
+			"19970929 19971030 19971127 19971230 19980129 19980226 19980330",
+		},
+		{
+			"WKST=MO changes the week of an every-other-week rule", "19970805", "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO",
+			"19970805 19970810 19970819 19970824",
+		},
+		{
+			"WKST=SU changes the week of an every-other-week rule", "19970805", "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU",
+			"19970805 19970817 19970819 19970831",
+		},
+		{
+			"invalid dates are skipped", "20070115", "FREQ=MONTHLY;BYMONTHDAY=15,30;COUNT=5",
+			"20070115 20070130 20070215 20070315 20070330",
+		},
+		{
+			"a daily rule waits years for a leap day", "20250101", "FREQ=DAILY;BYMONTH=2;BYMONTHDAY=29;COUNT=3",
+			"20280229 20320229 20360229",
+		},
+		{
+			"a long interval is searched for more than 8 years", "20000229", "FREQ=YEARLY;INTERVAL=10;BYMONTH=2;BYMONTHDAY=29;COUNT=3",
+			"20000229 20200229 20400229",
+		},
+		{
+			"a date that never exists ends the search", "20250101", "FREQ=DAILY;BYMONTH=2;BYMONTHDAY=30",
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			got := r.Expand(day(tt.start))
+			n := len(strings.Fields(tt.want))
+			if r.Count == 0 && r.Until.IsZero() && len(got) > n {
+				got = got[:n]
+			}
+			if dates(got) != tt.want {
+				t.Errorf("%s from %s:\ngot  %s\nwant %s", tt.rule, tt.start, dates(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandUntil(t *testing.T) {
+	tests := []struct {
+		start, rule string
+		n           int
+		last        string
+	}{
+		{"19970902", "FREQ=DAILY;UNTIL=19971224", 114, "19971224"},
+		// Every day in January, for 3 years.
+		{"19980101", "FREQ=DAILY;UNTIL=20000131T140000Z;BYMONTH=1", 93, "20000131"},
+		{"19980101", "FREQ=YEARLY;UNTIL=20000131T140000Z;BYMONTH=1;BYDAY=SU,MO,TU,WE,TH,FR,SA", 93, "20000131"},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.rule, err)
+		}
+		got := r.Expand(day(tt.start))
+		if len(got) != tt.n || dates(got[len(got)-1:]) != tt.last {
+			t.Errorf("%s: got %d dates up to %s, want %d up to %s", tt.rule, len(got), dates(got[len(got)-1:]), tt.n, tt.last)
+		}
+	}
+}
+
+func TestExpandKeepsTimeAndLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+9", 9*60*60)
+	r, err := Parse("FREQ=WEEKLY;COUNT=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2025, 1, 5, 8, 30, 0, 0, loc)
+	got := r.Expand(start)
+	want := []time.Time{start, time.Date(2025, 1, 12, 8, 30, 0, 0, loc)}
+	if len(got) != 2 || !got[0].Equal(want[0]) || !got[1].Equal(want[1]) || got[1].Location() != loc {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;COUNT=3;UNTIL=20250101",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=YEARLY;BYMONTH=-1",
+		"FREQ=DAILY;BYYEARDAY=1",
+		"FREQ=DAILY;UNTIL=2025-01-01",
+		"FREQ",
+	} {
+		if _, err := Parse(rule); err == nil {
+			t.Errorf("Parse(%q) succeeded", rule)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"RRULE:freq=weekly;byday=mo,th;interval=2", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"},
+		{"FREQ=MONTHLY;BYDAY=-1FR;UNTIL=20251231T000000Z", "FREQ=MONTHLY;BYDAY=-1FR;UNTIL=20251231"},
+		{"FREQ=YEARLY;BYMONTH=6,7;COUNT=10;WKST=SU", "FREQ=YEARLY;BYMONTH=6,7;WKST=SU;COUNT=10"},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.in)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.in, err)
+		}
+		if got := r.String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,420 @@
+// Package taskstest serves the Google Tasks v1 REST API from memory so the
+// real google.golang.org/api client can be exercised without network access.
+//
+// Point gtasks at a running Server by setting GTASKS_API_ENDPOINT (or
+// api.endpoint in the config file) to Server.URL.
+package taskstest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	"github.com/BRO3886/gtasks/api"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/tasks/v1"
+)
+
+// Server is an httptest.Server speaking the Tasks v1 REST surface.
+type Server struct {
+	*httptest.Server
+	// Backend holds the server's state and can be used to seed or inspect it.
+	Backend *api.MemoryBackend
+}
+
+// NewServer starts a Server backed by an empty api.MemoryBackend.
+// The caller must call Close when done.
+func NewServer() *Server {
+	b := api.NewMemoryBackend()
+	return &Server{Server: httptest.NewServer(Handler(b)), Backend: b}
+}
+
+// Service returns a Tasks client that sends its requests to the server.
+func (s *Server) Service(ctx context.Context) (*tasks.Service, error) {
+	return tasks.NewService(ctx,
+		option.WithEndpoint(s.URL+"/"),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(s.Client()))
+}
+
+// Handler returns an http.Handler implementing the Tasks v1 REST API on top of b.
+func Handler(b api.Backend) http.Handler {
+	h := &handler{b: b}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /tasks/v1/users/@me/lists", h.listTaskLists)
+	mux.HandleFunc("POST /tasks/v1/users/@me/lists", h.insertTaskList)
+	mux.HandleFunc("GET /tasks/v1/users/@me/lists/{tasklist}", h.getTaskList)
+	mux.HandleFunc("PATCH /tasks/v1/users/@me/lists/{tasklist}", h.patchTaskList)
+	mux.HandleFunc("PUT /tasks/v1/users/@me/lists/{tasklist}", h.patchTaskList)
+	mux.HandleFunc("DELETE /tasks/v1/users/@me/lists/{tasklist}", h.deleteTaskList)
+
+	mux.HandleFunc("GET /tasks/v1/lists/{tasklist}/tasks", h.listTasks)
+	mux.HandleFunc("POST /tasks/v1/lists/{tasklist}/tasks", h.insertTask)
+	mux.HandleFunc("POST /tasks/v1/lists/{tasklist}/clear", h.clearTasks)
+	mux.HandleFunc("GET /tasks/v1/lists/{tasklist}/tasks/{task}", h.getTask)
+	mux.HandleFunc("PATCH /tasks/v1/lists/{tasklist}/tasks/{task}", h.patchTask)
+	mux.HandleFunc("PUT /tasks/v1/lists/{tasklist}/tasks/{task}", h.updateTask)
+	mux.HandleFunc("DELETE /tasks/v1/lists/{tasklist}/tasks/{task}", h.deleteTask)
+	mux.HandleFunc("POST /tasks/v1/lists/{tasklist}/tasks/{task}/move", h.moveTask)
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "notFound", fmt.Sprintf("no route for %s %s", r.Method, r.URL.Path))
+	})
+	return mux
+}
+
+type handler struct {
+	b api.Backend
+}
+
+// writeError writes an error body in the format parsed by googleapi.CheckResponse.
+func writeError(w http.ResponseWriter, code int, reason, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{
+			"code":    code,
+			"message": message,
+			"errors":  []map[string]string{{"reason": reason, "message": message}},
+		},
+	})
+}
+
+// writeBackendError maps errors from the backend onto HTTP responses.
+func writeBackendError(w http.ResponseWriter, err error) {
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		reason := "backendError"
+		switch gerr.Code {
+		case http.StatusNotFound:
+			reason = "notFound"
+		case http.StatusBadRequest:
+			reason = "invalid"
+		case http.StatusPreconditionFailed:
+			reason = "conditionNotMet"
+		}
+		writeError(w, gerr.Code, reason, gerr.Message)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "backendError", err.Error())
+}
+
+// writeResource writes v as JSON, honouring If-None-Match against etag.
+func writeResource(w http.ResponseWriter, r *http.Request, etag string, v any) {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+		if r.Method == http.MethodGet && r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+// checkIfMatch rejects the request with 412 when If-Match does not match etag.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, etag string) bool {
+	want := r.Header.Get("If-Match")
+	if want == "" || want == "*" || want == etag {
+		return true
+	}
+	writeError(w, http.StatusPreconditionFailed, "conditionNotMet", "Precondition Failed")
+	return false
+}
+
+// collectionETag derives a stable ETag for a page of resources.
+func collectionETag(etags []string) string {
+	h := fnv.New64a()
+	for _, e := range etags {
+		h.Write([]byte(e))
+	}
+	return fmt.Sprintf("\"%x\"", h.Sum64())
+}
+
+func boolParam(r *http.Request, name string, def bool) (bool, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return def, nil
+	}
+	return strconv.ParseBool(raw)
+}
+
+func (h *handler) listTaskLists(w http.ResponseWriter, r *http.Request) {
+	res, err := h.b.ListTaskLists(r.Context(), r.URL.Query().Get("pageToken"))
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	var etags []string
+	for _, tl := range res.Items {
+		tl.SelfLink = selfLink(r, "tasks/v1/users/@me/lists/"+tl.Id)
+		etags = append(etags, tl.Etag)
+	}
+	res.Etag = collectionETag(etags)
+	writeResource(w, r, res.Etag, res)
+}
+
+func (h *handler) getTaskList(w http.ResponseWriter, r *http.Request) {
+	tl, err := h.b.GetTaskList(r.Context(), r.PathValue("tasklist"))
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	tl.SelfLink = selfLink(r, "tasks/v1/users/@me/lists/"+tl.Id)
+	writeResource(w, r, tl.Etag, tl)
+}
+
+func (h *handler) insertTaskList(w http.ResponseWriter, r *http.Request) {
+	var tl tasks.TaskList
+	if err := json.NewDecoder(r.Body).Decode(&tl); err != nil {
+		writeError(w, http.StatusBadRequest, "parseError", err.Error())
+		return
+	}
+	created, err := h.b.InsertTaskList(r.Context(), &tl)
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	created.SelfLink = selfLink(r, "tasks/v1/users/@me/lists/"+created.Id)
+	writeResource(w, r, created.Etag, created)
+}
+
+func (h *handler) patchTaskList(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("tasklist")
+	current, err := h.b.GetTaskList(r.Context(), id)
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	if !checkIfMatch(w, r, current.Etag) {
+		return
+	}
+	var tl tasks.TaskList
+	if err := json.NewDecoder(r.Body).Decode(&tl); err != nil {
+		writeError(w, http.StatusBadRequest, "parseError", err.Error())
+		return
+	}
+	tl.Id = id
+	updated, err := h.b.PatchTaskList(r.Context(), &tl)
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	updated.SelfLink = selfLink(r, "tasks/v1/users/@me/lists/"+updated.Id)
+	writeResource(w, r, updated.Etag, updated)
+}
+
+func (h *handler) deleteTaskList(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("tasklist")
+	current, err := h.b.GetTaskList(r.Context(), id)
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	if !checkIfMatch(w, r, current.Etag) {
+		return
+	}
+	if err := h.b.DeleteTaskList(r.Context(), id); err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) listTasks(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	opts := api.ListOptions{
+		PageToken:  q.Get("pageToken"),
+		UpdatedMin: q.Get("updatedMin"),
+	}
+	var err error
+	if raw := q.Get("maxResults"); raw != "" {
+		if opts.MaxResults, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid", "invalid maxResults")
+			return
+		}
+	}
+	if opts.ShowHidden, err = boolParam(r, "showHidden", false); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid", "invalid showHidden")
+		return
+	}
+	if opts.ShowDeleted, err = boolParam(r, "showDeleted", false); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid", "invalid showDeleted")
+		return
+	}
+	showCompleted, err := boolParam(r, "showCompleted", true)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid", "invalid showCompleted")
+		return
+	}
+
+	tasklistID := r.PathValue("tasklist")
+	res, err := h.b.ListTasks(r.Context(), tasklistID, opts)
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+
+	var items []*tasks.Task
+	var etags []string
+	for _, t := range res.Items {
+		if !showCompleted && t.Status == "completed" {
+			continue
+		}
+		t.SelfLink = selfLink(r, "tasks/v1/lists/"+tasklistID+"/tasks/"+t.Id)
+		items = append(items, t)
+		etags = append(etags, t.Etag)
+	}
+	res.Items = items
+	res.Etag = collectionETag(etags)
+	writeResource(w, r, res.Etag, res)
+}
+
+func (h *handler) getTask(w http.ResponseWriter, r *http.Request) {
+	tasklistID := r.PathValue("tasklist")
+	t, err := h.b.GetTask(r.Context(), tasklistID, r.PathValue("task"))
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	t.SelfLink = selfLink(r, "tasks/v1/lists/"+tasklistID+"/tasks/"+t.Id)
+	writeResource(w, r, t.Etag, t)
+}
+
+func (h *handler) insertTask(w http.ResponseWriter, r *http.Request) {
+	var t tasks.Task
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		writeError(w, http.StatusBadRequest, "parseError", err.Error())
+		return
+	}
+	tasklistID := r.PathValue("tasklist")
+	created, err := h.b.InsertTask(r.Context(), tasklistID, &t, api.MoveOptions{
+		Parent:   r.URL.Query().Get("parent"),
+		Previous: r.URL.Query().Get("previous"),
+	})
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	created.SelfLink = selfLink(r, "tasks/v1/lists/"+tasklistID+"/tasks/"+created.Id)
+	writeResource(w, r, created.Etag, created)
+}
+
+// mutableTaskFields are the JSON fields a client may change on a task.
+var mutableTaskFields = []string{"title", "notes", "due", "status", "completed", "links"}
+
+// patchTask applies a partial update. Fields sent as JSON null are cleared.
+func (h *handler) patchTask(w http.ResponseWriter, r *http.Request) {
+	h.modifyTask(w, r, false)
+}
+
+// updateTask replaces the task. Mutable fields missing from the body are cleared.
+func (h *handler) updateTask(w http.ResponseWriter, r *http.Request) {
+	h.modifyTask(w, r, true)
+}
+
+func (h *handler) modifyTask(w http.ResponseWriter, r *http.Request, replace bool) {
+	tasklistID, taskID := r.PathValue("tasklist"), r.PathValue("task")
+	current, err := h.b.GetTask(r.Context(), tasklistID, taskID)
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	if !checkIfMatch(w, r, current.Etag) {
+		return
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		writeError(w, http.StatusBadRequest, "parseError", err.Error())
+		return
+	}
+	body, _ := json.Marshal(raw)
+	var t tasks.Task
+	if err := json.Unmarshal(body, &t); err != nil {
+		writeError(w, http.StatusBadRequest, "parseError", err.Error())
+		return
+	}
+	if t.Id != "" && t.Id != taskID {
+		writeError(w, http.StatusBadRequest, "invalid", "task ID in body does not match URL")
+		return
+	}
+	t.Id = taskID
+
+	// Translate the wire representation back into the client library's
+	// ForceSendFields/NullFields so the backend sees exactly what was sent.
+	for _, field := range mutableTaskFields {
+		v, ok := raw[field]
+		goName := strings.ToUpper(field[:1]) + field[1:]
+		switch {
+		case ok && string(v) == "null", !ok && replace:
+			t.NullFields = append(t.NullFields, goName)
+		case ok:
+			t.ForceSendFields = append(t.ForceSendFields, goName)
+		}
+	}
+
+	updated, err := h.b.PatchTask(r.Context(), tasklistID, &t)
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	updated.SelfLink = selfLink(r, "tasks/v1/lists/"+tasklistID+"/tasks/"+updated.Id)
+	writeResource(w, r, updated.Etag, updated)
+}
+
+func (h *handler) deleteTask(w http.ResponseWriter, r *http.Request) {
+	tasklistID, taskID := r.PathValue("tasklist"), r.PathValue("task")
+	current, err := h.b.GetTask(r.Context(), tasklistID, taskID)
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	if !checkIfMatch(w, r, current.Etag) {
+		return
+	}
+	if err := h.b.DeleteTask(r.Context(), tasklistID, taskID); err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) clearTasks(w http.ResponseWriter, r *http.Request) {
+	if err := h.b.ClearTasks(r.Context(), r.PathValue("tasklist")); err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) moveTask(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	tasklistID := r.PathValue("tasklist")
+	moved, err := h.b.MoveTask(r.Context(), tasklistID, r.PathValue("task"), api.MoveOptions{
+		Parent:              q.Get("parent"),
+		Previous:            q.Get("previous"),
+		DestinationTasklist: q.Get("destinationTasklist"),
+	})
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	if dest := q.Get("destinationTasklist"); dest != "" {
+		tasklistID = dest
+	}
+	moved.SelfLink = selfLink(r, "tasks/v1/lists/"+tasklistID+"/tasks/"+moved.Id)
+	writeResource(w, r, moved.Etag, moved)
+}
+
+func selfLink(r *http.Request, path string) string {
+	return "http://" + r.Host + "/" + path
+}
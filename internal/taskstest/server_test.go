@@ -0,0 +1,126 @@
+package taskstest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/BRO3886/gtasks/api"
+	"github.com/BRO3886/gtasks/internal/config"
+	"github.com/BRO3886/gtasks/internal/taskstest"
+	"google.golang.org/api/tasks/v1"
+)
+
+// newBackend starts a Server and returns the backend gtasks itself uses,
+// pointed at the server through the api.endpoint setting.
+func newBackend(t *testing.T) (api.Backend, *taskstest.Server) {
+	t.Helper()
+	srv := taskstest.NewServer()
+	t.Cleanup(srv.Close)
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("GTASKS_API_ENDPOINT", srv.URL)
+	t.Setenv("GTASKS_API_MAX_RETRIES", "0")
+	config.LoadAppConfig()
+	t.Cleanup(config.LoadAppConfig)
+
+	b, err := api.NewBackend(context.Background())
+	if err != nil {
+		t.Fatalf("NewBackend: %v", err)
+	}
+	return b, srv
+}
+
+func titles(items []*tasks.Task) []string {
+	var out []string
+	for _, t := range items {
+		out = append(out, t.Title)
+	}
+	return out
+}
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	b, srv := newBackend(t)
+
+	tl, err := api.CreateTaskList(ctx, b, "Work")
+	if err != nil {
+		t.Fatalf("CreateTaskList: %v", err)
+	}
+	lists, err := api.GetTaskLists(ctx, b)
+	if err != nil || len(lists) != 1 || lists[0].Title != "Work" {
+		t.Fatalf("GetTaskLists: got %v, %v", lists, err)
+	}
+
+	first, err := api.CreateTask(ctx, b, &tasks.Task{Title: "Write report", Due: "2025-01-05T00:00:00.000Z"}, tl.Id)
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	second, err := api.CreateTaskAfter(ctx, b, &tasks.Task{Title: "Send report"}, tl.Id, "", first.Id)
+	if err != nil {
+		t.Fatalf("CreateTaskAfter: %v", err)
+	}
+	if _, err := api.CreateSubtask(ctx, b, &tasks.Task{Title: "Draft"}, tl.Id, first.Id); err != nil {
+		t.Fatalf("CreateSubtask: %v", err)
+	}
+
+	got, err := api.GetTasks(ctx, b, tl.Id, false, 0)
+	if err != nil {
+		t.Fatalf("GetTasks: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got tasks %q, want 3", titles(got))
+	}
+	// The server holds the same state the client sees.
+	stored, err := srv.Backend.GetTask(ctx, tl.Id, first.Id)
+	if err != nil || stored.Due != first.Due {
+		t.Fatalf("server has %+v, %v; want due %s", stored, err, first.Due)
+	}
+
+	updated, err := api.UpdateTask(ctx, b, &tasks.Task{Id: second.Id, Etag: second.Etag, Status: "completed"}, tl.Id)
+	if err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if updated.Status != "completed" || updated.Completed == nil || updated.Title != "Send report" {
+		t.Fatalf("UpdateTask: got %+v", updated)
+	}
+	// The ETag of the first fetch is stale now.
+	_, err = api.UpdateTask(ctx, b, &tasks.Task{Id: second.Id, Etag: second.Etag, Title: "Lost"}, tl.Id)
+	if !api.IsConflict(err) {
+		t.Fatalf("stale update: got %v, want a conflict", err)
+	}
+
+	got, err = api.GetTasks(ctx, b, tl.Id, false, 0)
+	if err != nil {
+		t.Fatalf("GetTasks: %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("got pending tasks %q, want 2", titles(got))
+	}
+	got, err = api.GetTasks(ctx, b, tl.Id, true, 0)
+	if err != nil || len(got) != 3 {
+		t.Errorf("got tasks %q, %v; want 3 with completed", titles(got), err)
+	}
+
+	home, err := api.CreateTaskList(ctx, b, "Home")
+	if err != nil {
+		t.Fatalf("CreateTaskList: %v", err)
+	}
+	if _, err := api.MoveTaskToList(ctx, b, tl.Id, first.Id, home.Id); err != nil {
+		t.Fatalf("MoveTaskToList: %v", err)
+	}
+	got, err = api.GetTasks(ctx, b, home.Id, false, 0)
+	if err != nil || len(got) != 2 {
+		t.Fatalf("moved tasks: got %q, %v; want the task and its subtask", titles(got), err)
+	}
+
+	if err := api.DeleteTask(ctx, b, second.Id, tl.Id); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if _, err := api.GetTasks(ctx, b, tl.Id, true, 0); !errors.Is(err, api.ErrNoTasks) {
+		t.Errorf("GetTasks after delete: got %v, want ErrNoTasks", err)
+	}
+	if _, err := b.GetTask(ctx, tl.Id, "missing"); err == nil {
+		t.Errorf("GetTask of a missing task succeeded")
+	}
+}
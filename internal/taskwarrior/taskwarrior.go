@@ -0,0 +1,149 @@
+// Package taskwarrior keeps the fields of Taskwarrior tasks that Google Tasks
+// has no place for in the notes of a task, so that tasks can be moved from
+// Taskwarrior to Google Tasks and back without losing them:
+//
+//	Ask about the late fee
+//	Called on Monday, no answer
+//
+//	Priority: H
+//	Tags: +bank +phone
+//	taskwarrior: uuid=0b0f1c52-3e8b-4c9e-9f1b-2a3c4d5e6f70 entry=20250105T093000Z
+//
+// Each annotation is a line of its own, followed by the priority and tags
+// and a marker line with the UUID and the time the task was created.
+package taskwarrior
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeLayout is the layout of the dates in Taskwarrior's JSON.
+const TimeLayout = "20060102T150405Z"
+
+// FormatTime formats t for Taskwarrior, or returns "" if t is zero.
+func FormatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(TimeLayout)
+}
+
+// ParseTime parses a Taskwarrior date. An empty string is the zero time.
+func ParseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(TimeLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (want e.g. 20250105T093000Z)", s)
+	}
+	return t, nil
+}
+
+// Annotation is a note added to a Taskwarrior task.
+type Annotation struct {
+	Entry       string `json:"entry"`
+	Description string `json:"description"`
+}
+
+// Extra is what a Taskwarrior task holds that Google Tasks cannot.
+type Extra struct {
+	UUID        string
+	Entry       time.Time
+	Priority    string
+	Tags        []string
+	Annotations []string
+}
+
+const markerPrefix = "taskwarrior:"
+
+// Notes returns e written as task notes.
+func (e Extra) Notes() string {
+	var lines []string
+	for _, a := range e.Annotations {
+		if a = strings.TrimSpace(a); a != "" {
+			lines = append(lines, a)
+		}
+	}
+	var meta []string
+	if e.Priority != "" {
+		meta = append(meta, "Priority: "+e.Priority)
+	}
+	if len(e.Tags) > 0 {
+		tags := make([]string, len(e.Tags))
+		for i, tag := range e.Tags {
+			tags[i] = "+" + tag
+		}
+		meta = append(meta, "Tags: "+strings.Join(tags, " "))
+	}
+	var marker []string
+	if e.UUID != "" {
+		marker = append(marker, "uuid="+e.UUID)
+	}
+	if !e.Entry.IsZero() {
+		marker = append(marker, "entry="+FormatTime(e.Entry))
+	}
+	if len(marker) > 0 {
+		meta = append(meta, markerPrefix+" "+strings.Join(marker, " "))
+	}
+	if len(lines) > 0 && len(meta) > 0 {
+		lines = append(lines, "")
+	}
+	return strings.Join(append(lines, meta...), "\n")
+}
+
+// ParseNotes reads the notes of a task back into an Extra. Lines that are
+// not the priority, tags or marker become annotations, so notes written in
+// Google Tasks come out as one annotation per line.
+func ParseNotes(notes string) Extra {
+	var e Extra
+	for _, line := range strings.Split(notes, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, markerPrefix):
+			for _, f := range strings.Fields(strings.TrimPrefix(line, markerPrefix)) {
+				key, value, _ := strings.Cut(f, "=")
+				switch key {
+				case "uuid":
+					e.UUID = value
+				case "entry":
+					e.Entry, _ = ParseTime(value)
+				}
+			}
+		case isPriority(line):
+			e.Priority = strings.TrimSpace(strings.TrimPrefix(line, "Priority:"))
+		case strings.HasPrefix(line, "Tags:"):
+			for _, tag := range strings.Fields(strings.TrimPrefix(line, "Tags:")) {
+				if tag = strings.TrimPrefix(tag, "+"); tag != "" {
+					e.Tags = append(e.Tags, tag)
+				}
+			}
+		default:
+			e.Annotations = append(e.Annotations, line)
+		}
+	}
+	return e
+}
+
+func isPriority(line string) bool {
+	p, ok := strings.CutPrefix(line, "Priority:")
+	switch strings.TrimSpace(p) {
+	case "H", "M", "L":
+		return ok
+	}
+	return false
+}
+
+// UUIDFor returns a UUID derived from a Google task ID, so that a task
+// exported twice gets the same UUID and Taskwarrior updates it instead of
+// adding it again. It is a version 5 style UUID of the SHA-1 of the ID.
+func UUIDFor(id string) string {
+	h := sha1.Sum([]byte("gtasks:" + id))
+	h[6] = h[6]&0x0f | 0x50
+	h[8] = h[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])
+}
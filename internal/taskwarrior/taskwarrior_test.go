@@ -0,0 +1,73 @@
+package taskwarrior
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestNotesRoundTrip(t *testing.T) {
+	entry := time.Date(2025, 1, 5, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		extra Extra
+		notes string
+	}{
+		{"empty", Extra{}, ""},
+		{"annotations only", Extra{Annotations: []string{"Call back", "Ask for Sam"}}, "Call back\nAsk for Sam"},
+		{
+			"everything",
+			Extra{UUID: "0b0f1c52-3e8b-4c9e-9f1b-2a3c4d5e6f70", Entry: entry, Priority: "H", Tags: []string{"bank", "phone"}, Annotations: []string{"Called on Monday"}},
+			"Called on Monday\n\nPriority: H\nTags: +bank +phone\ntaskwarrior: uuid=0b0f1c52-3e8b-4c9e-9f1b-2a3c4d5e6f70 entry=20250105T093000Z",
+		},
+		{"no annotations", Extra{Priority: "L"}, "Priority: L"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.extra.Notes(); got != tt.notes {
+				t.Fatalf("Notes: got %q, want %q", got, tt.notes)
+			}
+			if got := ParseNotes(tt.notes); !reflect.DeepEqual(got, tt.extra) {
+				t.Errorf("ParseNotes: got %+v, want %+v", got, tt.extra)
+			}
+		})
+	}
+}
+
+func TestParseNotesKeepsOtherLines(t *testing.T) {
+	got := ParseNotes("  Buy stamps \n\nPriority: urgent\nTags:\n")
+	want := Extra{Annotations: []string{"Buy stamps", "Priority: urgent"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestUUIDFor(t *testing.T) {
+	uuid := UUIDFor("MTIzNDU2Nzg5")
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(uuid) {
+		t.Errorf("%s is not a version 5 UUID", uuid)
+	}
+	if UUIDFor("MTIzNDU2Nzg5") != uuid {
+		t.Errorf("UUIDFor is not stable")
+	}
+	if UUIDFor("other") == uuid {
+		t.Errorf("different IDs give the same UUID")
+	}
+}
+
+func TestTime(t *testing.T) {
+	if got := FormatTime(time.Time{}); got != "" {
+		t.Errorf("FormatTime of zero: got %q", got)
+	}
+	berlin := time.FixedZone("CET", 60*60)
+	if got := FormatTime(time.Date(2025, 1, 5, 0, 0, 0, 0, berlin)); got != "20250104T230000Z" {
+		t.Errorf("FormatTime: got %q", got)
+	}
+	if _, err := ParseTime("2025-01-05"); err == nil {
+		t.Errorf("ParseTime accepted 2025-01-05")
+	}
+	if got, err := ParseTime(""); err != nil || !got.IsZero() {
+		t.Errorf("ParseTime of empty: got %v, %v", got, err)
+	}
+}
@@ -28,8 +28,12 @@ var (
 	Print = PrintStyle.PrintfFunc()
 )
 
+// Exit ends the program after ErrorP has printed its message. Tests can
+// replace it to keep the test binary running.
+var Exit = os.Exit
+
 // ErrorP prints errors with a format and interface like in printf and exits the program.
 func ErrorP(format string, a ...interface{}) {
 	ErrorStyle.Printf(format, a...)
-	os.Exit(1)
+	Exit(1)
 }
@@ -6,6 +6,9 @@ import (
 	"google.golang.org/api/tasks/v1"
 )
 
+// Sort orders tasks by the given key and then moves every subtask directly
+// beneath its parent, so the result can be rendered as a tree.
+// Subtasks whose parent is not in the slice are treated as top-level tasks.
 func Sort(tasks []*tasks.Task, sortBy string) {
 	switch sortBy {
 	case "due":
@@ -24,10 +27,39 @@ func Sort(tasks []*tasks.Task, sortBy string) {
 		sort.SliceStable(tasks, func(i, j int) bool {
 			return tasks[i].Title < tasks[j].Title
 		})
-	case "position":
 	default:
+		// Positions are only comparable between siblings; grouping below
+		// takes care of placing subtasks under their parent.
 		sort.SliceStable(tasks, func(i, j int) bool {
-			return tasks[i].Position < tasks[j].Position && tasks[i].Parent == ""
+			return tasks[i].Position < tasks[j].Position
 		})
 	}
+
+	groupSubtasks(tasks)
+}
+
+// groupSubtasks reorders list in place so each subtask follows its parent,
+// keeping the existing relative order among roots and among siblings.
+func groupSubtasks(list []*tasks.Task) {
+	present := make(map[string]bool, len(list))
+	for _, t := range list {
+		present[t.Id] = true
+	}
+
+	children := make(map[string][]*tasks.Task)
+	var roots []*tasks.Task
+	for _, t := range list {
+		if t.Parent != "" && present[t.Parent] {
+			children[t.Parent] = append(children[t.Parent], t)
+		} else {
+			roots = append(roots, t)
+		}
+	}
+
+	out := make([]*tasks.Task, 0, len(list))
+	for _, r := range roots {
+		out = append(out, r)
+		out = append(out, children[r.Id]...)
+	}
+	copy(list, out)
 }